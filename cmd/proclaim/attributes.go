@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/dogmatiq/ferrite"
+	"github.com/dogmatiq/imbue"
+	"github.com/dogmatiq/proclaim/reconciler"
+)
+
+var secretAttributeSourcesEnabled = ferrite.
+	Bool("SECRET_ATTRIBUTE_SOURCES_ENABLED", "allow attribute values to be obtained from Secrets that have the proclaim.dogmatiq.io/attribute-source=true label").
+	WithDefault(false).
+	Required()
+
+func init() {
+	imbue.Decorate0(
+		container,
+		func(
+			_ imbue.Context,
+			r *reconciler.Reconciler,
+		) (*reconciler.Reconciler, error) {
+			r.EnableSecretAttributeSources = secretAttributeSourcesEnabled.Value()
+			return r, nil
+		},
+	)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/dogmatiq/ferrite"
+	"github.com/dogmatiq/imbue"
+	"github.com/dogmatiq/proclaim/reconciler"
+)
+
+var discoveryEnabled = ferrite.
+	Bool("DISCOVERY_ENABLED", "verify that advertised instances are discoverable via DNS-SD").
+	WithDefault(true).
+	Required()
+
+var driftDetectionInterval = ferrite.
+	Duration("DRIFT_DETECTION_INTERVAL", "the interval at which advertised instances are checked for drift, defaults to 10 times the instance's TTL").
+	Optional()
+
+func init() {
+	imbue.Decorate0(
+		container,
+		func(
+			_ imbue.Context,
+			r *reconciler.Reconciler,
+		) (*reconciler.Reconciler, error) {
+			r.DisableDiscovery = !discoveryEnabled.Value()
+			r.DriftDetectionInterval, _ = driftDetectionInterval.Value()
+			return r, nil
+		},
+	)
+}
@@ -1,24 +1,106 @@
 package main
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+
 	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/ferrite"
 	"github.com/dogmatiq/imbue"
+	"github.com/dogmatiq/proclaim/resolver"
+	"github.com/dogmatiq/proclaim/resolver/dohresolver"
 	"github.com/miekg/dns"
 )
 
+const (
+	dnsTransportUDP   = "udp"
+	dnsTransportTCP   = "tcp"
+	dnsTransportTLS   = "tls"
+	dnsTransportHTTPS = "https"
+)
+
+var dnsTransport = ferrite.
+	Enum("DNS_TRANSPORT", "the transport used to make DNS-SD discovery queries").
+	WithMember(dnsTransportUDP, "plain DNS over UDP").
+	WithMember(dnsTransportTCP, "plain DNS over TCP").
+	WithMember(dnsTransportTLS, "DNS-over-TLS (RFC 7858)").
+	WithMember(dnsTransportHTTPS, "DNS-over-HTTPS (RFC 8484)").
+	WithDefault(dnsTransportTCP).
+	Required()
+
+var dnsServers = ferrite.
+	String("DNS_SERVERS", "a comma-separated list of DNS servers (or DoH URLs) used for DNS-SD discovery").
+	Optional()
+
+var dnsPort = ferrite.
+	NetworkPort("DNS_PORT", "the port of the DNS servers used for DNS-SD discovery").
+	Optional()
+
+var dnsTimeout = ferrite.
+	Duration("DNS_TIMEOUT", "the timeout for each DNS-SD discovery query").
+	Optional()
+
+var dnsTLSServerName = ferrite.
+	String("DNS_TLS_SERVER_NAME", "the host name used to verify the TLS certificates of the DNS servers").
+	Optional(ferrite.RelevantWhen(dnsTransport, dnsTransportTLS))
+
 func init() {
 	imbue.With1(
 		container,
 		func(
 			_ imbue.Context,
 			cfg *dns.ClientConfig,
-		) (*dnssd.UnicastResolver, error) {
+		) (resolver.Resolver, error) {
+			transport := dnsTransport.Value()
+			timeout, _ := dnsTimeout.Value()
+
+			if transport == dnsTransportHTTPS {
+				// The servers in /etc/resolv.conf are IP addresses, not
+				// DNS-over-HTTPS URLs, so the URLs must be given explicitly.
+				if _, ok := dnsServers.Value(); !ok || len(cfg.Servers) == 0 {
+					return nil, errors.New("DNS_SERVERS must contain at least one DNS-over-HTTPS URL when DNS_TRANSPORT is \"https\"")
+				}
+
+				for _, s := range cfg.Servers {
+					u, err := url.Parse(s)
+					if err != nil || u.Scheme != "https" || u.Host == "" {
+						return nil, fmt.Errorf("DNS-over-HTTPS server must be an https URL: %q", s)
+					}
+				}
+
+				return &dohresolver.Resolver{
+					URLs:    cfg.Servers,
+					Timeout: timeout,
+				}, nil
+			}
+
+			client := &dns.Client{
+				// Use TCP for DNS-SD queries by default to avoid truncation of
+				// large responses.
+				Net:     "tcp",
+				Timeout: timeout,
+			}
+
+			switch transport {
+			case dnsTransportUDP:
+				client.Net = "udp"
+			case dnsTransportTLS:
+				client.Net = "tcp-tls"
+				client.TLSConfig = &tls.Config{
+					MinVersion: tls.VersionTLS12,
+				}
+
+				if n, ok := dnsTLSServerName.Value(); ok {
+					client.TLSConfig.ServerName = n
+				}
+			}
+
 			return &dnssd.UnicastResolver{
-				Client: &dns.Client{
-					// Always use TCP for DNS-SD queries to avoid truncation of
-					// large responses.
-					Net: "tcp",
-				},
+				Client: client,
 				Config: cfg,
 			}, nil
 		},
@@ -29,7 +111,38 @@ func init() {
 		func(
 			imbue.Context,
 		) (*dns.ClientConfig, error) {
-			return dns.ClientConfigFromFile("/etc/resolv.conf")
+			cfg := &dns.ClientConfig{
+				Port:     "53",
+				Ndots:    1,
+				Timeout:  5,
+				Attempts: 2,
+			}
+
+			if servers, ok := dnsServers.Value(); ok {
+				for _, s := range strings.Split(servers, ",") {
+					if s = strings.TrimSpace(s); s != "" {
+						cfg.Servers = append(cfg.Servers, s)
+					}
+				}
+			} else {
+				var err error
+				cfg, err = dns.ClientConfigFromFile("/etc/resolv.conf")
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if p, ok := dnsPort.Value(); ok {
+				cfg.Port = p
+			} else if dnsTransport.Value() == dnsTransportTLS {
+				cfg.Port = "853"
+			}
+
+			if t, ok := dnsTimeout.Value(); ok {
+				cfg.Timeout = int(math.Ceil(t.Seconds()))
+			}
+
+			return cfg, nil
 		},
 	)
 }
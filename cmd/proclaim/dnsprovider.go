@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/dnsimple/dnsimple-go/v4/dnsimple"
+	"github.com/dogmatiq/ferrite"
+	"github.com/dogmatiq/imbue"
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/dogmatiq/proclaim/provider/dnsimpleprovider"
+	"github.com/dogmatiq/proclaim/provider/route53provider"
+	"github.com/dogmatiq/proclaim/reconciler"
+)
+
+var dnsProvidersEnabled = ferrite.
+	Bool("DNS_PROVIDERS_ENABLED", "enable providers configured by DNSProvider resources").
+	WithDefault(false).
+	Required()
+
+func init() {
+	imbue.Decorate0(
+		container,
+		func(
+			_ imbue.Context,
+			r *reconciler.Reconciler,
+		) (*reconciler.Reconciler, error) {
+			if dnsProvidersEnabled.Value() {
+				r.BuildProvider = buildProvider
+			}
+			return r, nil
+		},
+	)
+}
+
+// buildProvider builds a provider from the specification of a DNSProvider
+// resource.
+func buildProvider(
+	ctx context.Context,
+	spec crd.DNSProviderSpec,
+	creds map[string][]byte,
+) (provider.Provider, error) {
+	switch spec.Type {
+	case crd.ProviderTypeRoute53:
+		return buildRoute53Provider(ctx, spec, creds)
+	case crd.ProviderTypeDNSimple:
+		return buildDNSimpleProvider(ctx, spec, creds)
+	default:
+		return nil, fmt.Errorf("unsupported provider type %q", spec.Type)
+	}
+}
+
+// buildRoute53Provider builds a Route 53 provider. If the credentials do not
+// contain an AWS_ACCESS_KEY_ID, the default AWS credential chain is used.
+func buildRoute53Provider(
+	ctx context.Context,
+	spec crd.DNSProviderSpec,
+	creds map[string][]byte,
+) (provider.Provider, error) {
+	var options []func(*config.LoadOptions) error
+
+	if id := string(creds["AWS_ACCESS_KEY_ID"]); id != "" {
+		options = append(
+			options,
+			config.WithCredentialsProvider(
+				credentials.NewStaticCredentialsProvider(
+					id,
+					string(creds["AWS_SECRET_ACCESS_KEY"]),
+					string(creds["AWS_SESSION_TOKEN"]),
+				),
+			),
+		)
+	}
+
+	if region := string(creds["AWS_REGION"]); region != "" {
+		options = append(options, config.WithRegion(region))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS configuration: %w", err)
+	}
+
+	return &route53provider.Provider{
+		Client: route53.NewFromConfig(
+			cfg,
+			func(o *route53.Options) {
+				if spec.APIURL != "" {
+					o.BaseEndpoint = aws.String(spec.APIURL)
+				}
+			},
+		),
+		BatchWindow: route53BatchWindow.Value(),
+		RateLimit:   route53RateLimit.Value(),
+	}, nil
+}
+
+// buildDNSimpleProvider builds a DNSimple provider. The credentials must
+// contain a DNSIMPLE_TOKEN.
+func buildDNSimpleProvider(
+	ctx context.Context,
+	spec crd.DNSProviderSpec,
+	creds map[string][]byte,
+) (provider.Provider, error) {
+	token := string(creds["DNSIMPLE_TOKEN"])
+	if token == "" {
+		return nil, errors.New("the credentials secret must contain a DNSIMPLE_TOKEN key")
+	}
+
+	client := dnsimple.NewClient(
+		dnsimple.StaticTokenHTTPClient(ctx, token),
+	)
+
+	if spec.APIURL != "" {
+		client.BaseURL = spec.APIURL
+	}
+
+	return &dnsimpleprovider.Provider{
+		Client: client,
+	}, nil
+}
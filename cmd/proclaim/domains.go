@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/dogmatiq/proclaim/provider"
+)
+
+// restrict returns p restricted to the domains in the given comma-separated
+// lists, or p itself if both lists are empty.
+func restrict(p provider.Provider, allowed, denied string) provider.Provider {
+	policy := provider.DomainPolicy{
+		Allowed: splitList(allowed),
+		Denied:  splitList(denied),
+	}
+
+	if len(policy.Allowed) == 0 && len(policy.Denied) == 0 {
+		return p
+	}
+
+	return provider.Restrict(p, policy)
+}
+
+// splitList splits a comma-separated list, discarding empty elements.
+func splitList(v string) []string {
+	var list []string
+
+	for _, e := range strings.Split(v, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/dogmatiq/ferrite"
+	"github.com/dogmatiq/imbue"
+	"github.com/dogmatiq/proclaim/reconciler"
+)
+
+var dryRunEnabled = ferrite.
+	Bool("DRY_RUN_ENABLED", "report the changes that would be made to DNS records without making them").
+	WithDefault(false).
+	Required()
+
+func init() {
+	imbue.Decorate0(
+		container,
+		func(
+			_ imbue.Context,
+			r *reconciler.Reconciler,
+		) (*reconciler.Reconciler, error) {
+			r.DryRun = dryRunEnabled.Value()
+			return r, nil
+		},
+	)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/dogmatiq/ferrite"
+	"github.com/dogmatiq/imbue"
+	"github.com/dogmatiq/proclaim/reconciler"
+)
+
+var serviceTypeEnumerationEnabled = ferrite.
+	Bool("SERVICE_TYPE_ENUMERATION_ENABLED", "advertise the service type of each instance in the '_services._dns-sd._udp' domain").
+	WithDefault(false).
+	Required()
+
+var browsingDomainEnumerationEnabled = ferrite.
+	Bool("BROWSING_DOMAIN_ENUMERATION_ENABLED", "advertise the domain of each instance as a browsing domain of its parent domain").
+	WithDefault(false).
+	Required()
+
+func init() {
+	imbue.Decorate0(
+		container,
+		func(
+			_ imbue.Context,
+			r *reconciler.Reconciler,
+		) (*reconciler.Reconciler, error) {
+			r.EnableServiceTypeEnumeration = serviceTypeEnumerationEnabled.Value()
+			r.EnableBrowsingDomainEnumeration = browsingDomainEnumerationEnabled.Value()
+			return r, nil
+		},
+	)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dogmatiq/ferrite"
+	"github.com/dogmatiq/imbue"
+	"github.com/dogmatiq/proclaim/reconciler"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var healthProbeBindAddress = ferrite.
+	String("HEALTH_PROBE_BIND_ADDRESS", "the address on which the /healthz and /readyz probe endpoints are served").
+	WithDefault(":8081").
+	Required()
+
+var providerHealthCheckInterval = ferrite.
+	Duration("PROVIDER_HEALTH_CHECK_INTERVAL", "the interval at which the credentials and reachability of each provider are checked").
+	WithDefault(1 * time.Minute).
+	Required()
+
+var providerStatusConfigMap = ferrite.
+	String("PROVIDER_STATUS_CONFIGMAP", "the ConfigMap to which provider health is written, in namespace/name format").
+	Optional()
+
+func init() {
+	imbue.Decorate0(
+		container,
+		func(
+			_ imbue.Context,
+			o manager.Options,
+		) (manager.Options, error) {
+			o.HealthProbeBindAddress = healthProbeBindAddress.Value()
+			return o, nil
+		},
+	)
+
+	imbue.Decorate0(
+		container,
+		func(
+			_ imbue.Context,
+			r *reconciler.Reconciler,
+		) (*reconciler.Reconciler, error) {
+			r.HealthCheckInterval = providerHealthCheckInterval.Value()
+
+			if v, ok := providerStatusConfigMap.Value(); ok {
+				ns, name, ok := strings.Cut(v, "/")
+				if !ok || ns == "" || name == "" {
+					return nil, fmt.Errorf("PROVIDER_STATUS_CONFIGMAP must be in namespace/name format, got %q", v)
+				}
+
+				r.HealthStatusConfigMap = types.NamespacedName{
+					Namespace: ns,
+					Name:      name,
+				}
+			}
+
+			if err := r.Manager.AddHealthzCheck("ping", healthz.Ping); err != nil {
+				return nil, err
+			}
+
+			if err := r.Manager.AddReadyzCheck("started", r.CheckStarted); err != nil {
+				return nil, err
+			}
+
+			if err := r.Manager.AddReadyzCheck("providers", r.CheckProviders); err != nil {
+				return nil, err
+			}
+
+			return r, nil
+		},
+	)
+}
@@ -1,35 +1,49 @@
 package main
 
 import (
-	"github.com/dogmatiq/dissolve/dnssd"
 	"github.com/dogmatiq/imbue"
 	"github.com/dogmatiq/proclaim/crd"
 	"github.com/dogmatiq/proclaim/reconciler"
+	"github.com/dogmatiq/proclaim/resolver"
 	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	controller "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/scheme"
 )
 
 func init() {
-	imbue.With1(
+	imbue.With3(
 		container,
 		func(
 			_ imbue.Context,
+			s *runtime.Scheme,
+			c cache.Options,
 			l imbue.ByName[systemLogger, logr.Logger],
+		) (manager.Options, error) {
+			return manager.Options{
+				Logger: l.Value(),
+				Scheme: s,
+				Cache:  c,
+			}, nil
+		},
+	)
+
+	imbue.With1(
+		container,
+		func(
+			_ imbue.Context,
+			o manager.Options,
 		) (manager.Manager, error) {
 			cfg, err := controller.GetConfig()
 			if err != nil {
 				return nil, err
 			}
 
-			return controller.NewManager(
-				cfg,
-				controller.Options{
-					Logger: l.Value(),
-				},
-			)
+			return controller.NewManager(cfg, o)
 		},
 	)
 
@@ -38,24 +52,30 @@ func init() {
 		func(
 			_ imbue.Context,
 			m manager.Manager,
-			r *dnssd.UnicastResolver,
+			r resolver.Resolver,
 			l imbue.ByName[verboseLogger, logr.Logger],
 		) (*reconciler.Reconciler, error) {
 			return &reconciler.Reconciler{
-				Manager:  m,
-				Client:   m.GetClient(),
-				Resolver: r,
-				Logger:   l.Value(),
+				Manager:   m,
+				Client:    m.GetClient(),
+				Resolver:  r,
+				Logger:    l.Value(),
+				APIReader: m.GetAPIReader(),
 			}, nil
 		},
 	)
 
-	imbue.Decorate0(
+	imbue.With0(
 		container,
 		func(
-			_ imbue.Context,
-			m manager.Manager,
-		) (manager.Manager, error) {
+			imbue.Context,
+		) (*runtime.Scheme, error) {
+			s := runtime.NewScheme()
+
+			if err := clientgoscheme.AddToScheme(s); err != nil {
+				return nil, err
+			}
+
 			b := &scheme.Builder{
 				GroupVersion: schema.GroupVersion{
 					Group:   crd.GroupName,
@@ -66,13 +86,17 @@ func init() {
 			b.Register(
 				&crd.DNSSDServiceInstance{},
 				&crd.DNSSDServiceInstanceList{},
+				&crd.DNSProvider{},
+				&crd.DNSProviderList{},
+				&crd.DNSSDPolicy{},
+				&crd.DNSSDPolicyList{},
 			)
 
-			if err := b.AddToScheme(m.GetScheme()); err != nil {
+			if err := b.AddToScheme(s); err != nil {
 				return nil, err
 			}
 
-			return m, nil
+			return s, nil
 		},
 	)
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"time"
+
+	"github.com/dogmatiq/ferrite"
+	"github.com/dogmatiq/imbue"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var leaderElectionEnabled = ferrite.
+	Bool("LEADER_ELECTION_ENABLED", "elect a leader so that only one replica reconciles DNS-SD service instances at a time").
+	WithDefault(false).
+	Required()
+
+var leaderElectionID = ferrite.
+	String("LEADER_ELECTION_ID", "the name of the lease used for leader election").
+	WithDefault("proclaim.dogmatiq.io").
+	Required(ferrite.RelevantIf(leaderElectionEnabled))
+
+var leaderElectionNamespace = ferrite.
+	String("LEADER_ELECTION_NAMESPACE", "the namespace of the lease used for leader election, defaults to the pod's namespace").
+	Optional(ferrite.RelevantIf(leaderElectionEnabled))
+
+var leaderElectionLeaseDuration = ferrite.
+	Duration("LEADER_ELECTION_LEASE_DURATION", "the time that non-leader replicas wait before attempting to acquire an unrenewed lease").
+	WithDefault(15 * time.Second).
+	Required(ferrite.RelevantIf(leaderElectionEnabled))
+
+var leaderElectionRenewDeadline = ferrite.
+	Duration("LEADER_ELECTION_RENEW_DEADLINE", "the time that the leader retries renewing its lease before giving up leadership").
+	WithDefault(10 * time.Second).
+	Required(ferrite.RelevantIf(leaderElectionEnabled))
+
+var leaderElectionRetryPeriod = ferrite.
+	Duration("LEADER_ELECTION_RETRY_PERIOD", "the time between attempts to acquire or renew the lease").
+	WithDefault(2 * time.Second).
+	Required(ferrite.RelevantIf(leaderElectionEnabled))
+
+func init() {
+	imbue.Decorate0(
+		container,
+		func(
+			_ imbue.Context,
+			o manager.Options,
+		) (manager.Options, error) {
+			if !leaderElectionEnabled.Value() {
+				return o, nil
+			}
+
+			leaseDuration := leaderElectionLeaseDuration.Value()
+			renewDeadline := leaderElectionRenewDeadline.Value()
+			retryPeriod := leaderElectionRetryPeriod.Value()
+
+			o.LeaderElection = true
+			o.LeaderElectionID = leaderElectionID.Value()
+			o.LeaderElectionNamespace, _ = leaderElectionNamespace.Value()
+			o.LeaderElectionReleaseOnCancel = true
+			o.LeaseDuration = &leaseDuration
+			o.RenewDeadline = &renewDeadline
+			o.RetryPeriod = &retryPeriod
+
+			return o, nil
+		},
+	)
+}
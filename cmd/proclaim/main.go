@@ -6,15 +6,12 @@ import (
 
 	"github.com/dogmatiq/ferrite"
 	"github.com/dogmatiq/imbue"
-	"github.com/dogmatiq/proclaim/crd"
 	"github.com/dogmatiq/proclaim/reconciler"
 	"github.com/go-logr/logr"
 	"github.com/miekg/dns"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	controller "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
-	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 var container = imbue.New()
@@ -36,17 +33,13 @@ func main() {
 		) error {
 			l.Value().Info(
 				"DNS configuration loaded",
+				"transport", dnsTransport.Value(),
 				"servers", c.Servers,
 				"port", c.Port,
 				"timeout", c.Timeout,
 			)
 
-			err := builder.
-				ControllerManagedBy(m).
-				For(&crd.DNSSDServiceInstance{}).
-				WithEventFilter(predicate.GenerationChangedPredicate{}).
-				Complete(r)
-			if err != nil {
+			if err := r.SetupWithManager(ctx); err != nil {
 				return err
 			}
 
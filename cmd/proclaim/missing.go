@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/dogmatiq/ferrite"
+	"github.com/dogmatiq/imbue"
+	"github.com/dogmatiq/proclaim/reconciler"
+)
+
+var missingProviderPolicy = ferrite.
+	Enum("MISSING_PROVIDER_POLICY", "how instances associated with a provider that is no longer configured are handled").
+	WithMember(string(reconciler.OrphanRecords), "leave the DNS records in place and allow the instance to be deleted").
+	WithMember(string(reconciler.BlockDeletion), "block deletion of the instance until the provider is configured again").
+	WithMember(string(reconciler.Readopt), "re-adopt the instance using another provider that can advertise on its domain").
+	WithDefault(string(reconciler.OrphanRecords)).
+	Required()
+
+func init() {
+	imbue.Decorate0(
+		container,
+		func(
+			_ imbue.Context,
+			r *reconciler.Reconciler,
+		) (*reconciler.Reconciler, error) {
+			r.MissingProviderPolicy = reconciler.MissingProviderPolicy(missingProviderPolicy.Value())
+			return r, nil
+		},
+	)
+}
@@ -23,6 +23,14 @@ var dnsimpleURL = ferrite.
 	WithDefault("https://api.dnsimple.com").
 	Required(ferrite.RelevantIf(dnsimpleEnabled))
 
+var dnsimpleAllowedDomains = ferrite.
+	String("DNSIMPLE_ALLOWED_DOMAINS", "a comma-separated list of the domains on which the DNSimple provider may advertise, defaults to all hosted domains").
+	Optional(ferrite.RelevantIf(dnsimpleEnabled))
+
+var dnsimpleDeniedDomains = ferrite.
+	String("DNSIMPLE_DENIED_DOMAINS", "a comma-separated list of the domains on which the DNSimple provider must not advertise").
+	Optional(ferrite.RelevantIf(dnsimpleEnabled))
+
 func init() {
 	imbue.Decorate0(
 		container,
@@ -42,11 +50,18 @@ func init() {
 			)
 			client.BaseURL = dnsimpleURL.Value().String()
 
+			allowed, _ := dnsimpleAllowedDomains.Value()
+			denied, _ := dnsimpleDeniedDomains.Value()
+
 			r.Providers = append(
 				r.Providers,
-				&dnsimpleprovider.Provider{
-					Client: client,
-				},
+				restrict(
+					&dnsimpleprovider.Provider{
+						Client: client,
+					},
+					allowed,
+					denied,
+				),
 			)
 
 			return r, nil
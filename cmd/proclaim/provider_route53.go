@@ -1,6 +1,8 @@
 package main
 
 import (
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
@@ -15,6 +17,26 @@ var route53Enabled = ferrite.
 	WithDefault(false).
 	Required()
 
+var route53AllowedDomains = ferrite.
+	String("ROUTE53_ALLOWED_DOMAINS", "a comma-separated list of the domains on which the Route 53 provider may advertise, defaults to all hosted domains").
+	Optional(ferrite.RelevantIf(route53Enabled))
+
+var route53DeniedDomains = ferrite.
+	String("ROUTE53_DENIED_DOMAINS", "a comma-separated list of the domains on which the Route 53 provider must not advertise").
+	Optional(ferrite.RelevantIf(route53Enabled))
+
+var route53BatchWindow = ferrite.
+	Duration("ROUTE53_BATCH_WINDOW", "the amount of time to wait for other changes to the same hosted zone, so they can be applied in a single request, 0s disables batching").
+	WithDefault(1 * time.Second).
+	WithMinimum(0).
+	Required()
+
+var route53RateLimit = ferrite.
+	Float[float64]("ROUTE53_RATE_LIMIT", "the maximum number of requests per second made to the Route 53 API by each provider, shared by reads and writes").
+	WithDefault(5).
+	WithMinimum(0.1).
+	Required()
+
 func init() {
 	imbue.Decorate1(
 		container,
@@ -32,11 +54,20 @@ func init() {
 				return nil, err
 			}
 
+			allowed, _ := route53AllowedDomains.Value()
+			denied, _ := route53DeniedDomains.Value()
+
 			r.Providers = append(
 				r.Providers,
-				&route53provider.Provider{
-					Client: cli,
-				},
+				restrict(
+					&route53provider.Provider{
+						Client:      cli,
+						BatchWindow: route53BatchWindow.Value(),
+						RateLimit:   route53RateLimit.Value(),
+					},
+					allowed,
+					denied,
+				),
 			)
 
 			return r, nil
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"github.com/dogmatiq/ferrite"
+	"github.com/dogmatiq/imbue"
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/reconciler"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var controllerName = ferrite.
+	String("CONTROLLER_NAME", "a name that distinguishes the controller from any other deployments of Proclaim in the same cluster").
+	WithDefault("proclaim").
+	Required()
+
+var watchNamespaces = ferrite.
+	String("WATCH_NAMESPACES", "a comma-separated list of namespaces to watch, defaults to all namespaces").
+	Optional()
+
+var instanceSelector = ferrite.
+	String("INSTANCE_SELECTOR", "a label selector that restricts the DNS-SD service instances managed by the controller").
+	Optional()
+
+var instanceClass = ferrite.
+	String("INSTANCE_CLASS", "the instance class managed by the controller, defaults to instances without a class").
+	Optional()
+
+func init() {
+	imbue.With0(
+		container,
+		func(
+			imbue.Context,
+		) (cache.Options, error) {
+			var opts cache.Options
+
+			if v, ok := watchNamespaces.Value(); ok {
+				opts.DefaultNamespaces = map[string]cache.Config{}
+
+				for _, ns := range splitList(v) {
+					opts.DefaultNamespaces[ns] = cache.Config{}
+				}
+			}
+
+			if v, ok := instanceSelector.Value(); ok {
+				sel, err := labels.Parse(v)
+				if err != nil {
+					return cache.Options{}, err
+				}
+
+				opts.ByObject = map[client.Object]cache.ByObject{
+					&crd.DNSSDServiceInstance{}: {
+						Label: sel,
+					},
+				}
+			}
+
+			return opts, nil
+		},
+	)
+
+	imbue.Decorate0(
+		container,
+		func(
+			_ imbue.Context,
+			r *reconciler.Reconciler,
+		) (*reconciler.Reconciler, error) {
+			r.ControllerName = controllerName.Value()
+			r.InstanceClass, _ = instanceClass.Value()
+
+			_, namespaced := watchNamespaces.Value()
+			_, selected := instanceSelector.Value()
+			r.Scoped = namespaced || selected
+
+			return r, nil
+		},
+	)
+}
@@ -1,6 +1,9 @@
 package crd
 
 import (
+	"fmt"
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
@@ -59,3 +62,128 @@ func InstanceIgnoredCondition() metav1.Condition {
 		Message: "no running Proclaim controllers have providers that can advertise on this domain",
 	}
 }
+
+// ReasonDomainDenied is the reason of the Adopted condition of an instance that
+// is not advertised because the domain policies of the providers do not permit
+// its domain.
+const ReasonDomainDenied = "DomainDenied"
+
+// InstanceDenied records an event indicating that the service instance was
+// not adopted because the domain policies of the providers that might
+// otherwise advertise it do not permit its domain.
+func InstanceDenied(m manager.Manager, res *DNSSDServiceInstance, reasons []string) {
+	m.
+		GetEventRecorderFor("proclaim").
+		Eventf(
+			res,
+			"Warning",
+			ReasonDomainDenied,
+			"no providers are permitted to advertise on %q: %s",
+			res.Spec.Instance.Domain,
+			strings.Join(reasons, "; "),
+		)
+}
+
+// InstanceDeniedCondition returns a condition indicating that the instance
+// was not adopted because the domain policies of the providers do not permit
+// its domain.
+func InstanceDeniedCondition(reasons []string) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeAdopted,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonDomainDenied,
+		Message: "the domain policies of the providers do not permit this domain: " + strings.Join(reasons, "; "),
+	}
+}
+
+// ReasonForbidden is the reason of the Adopted condition of an instance that is
+// not advertised because no DNSSDPolicy permits it.
+const ReasonForbidden = "Forbidden"
+
+// InstanceForbidden records an event indicating that the service instance was
+// not adopted because no DNSSDPolicy permits it.
+func InstanceForbidden(m manager.Manager, res *DNSSDServiceInstance, reason string) {
+	m.
+		GetEventRecorderFor("proclaim").
+		Eventf(
+			res,
+			"Warning",
+			ReasonForbidden,
+			"%s",
+			reason,
+		)
+}
+
+// InstanceForbiddenCondition returns a condition indicating that the instance
+// was not adopted because no DNSSDPolicy permits it.
+func InstanceForbiddenCondition(reason string) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeAdopted,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonForbidden,
+		Message: reason,
+	}
+}
+
+// ReasonConflict is the reason of the Adopted condition of an instance that is
+// not advertised because another instance with the same fully-qualified name
+// takes precedence.
+const ReasonConflict = "Conflict"
+
+// InstanceConflict records an event indicating that the service instance is
+// not advertised because another instance with the same fully-qualified name
+// takes precedence.
+func InstanceConflict(m manager.Manager, res, winner *DNSSDServiceInstance) {
+	m.
+		GetEventRecorderFor("proclaim").
+		Eventf(
+			res,
+			"Warning",
+			"Conflict",
+			"the %s/%s instance has the same name, service type and domain, and takes precedence",
+			winner.Namespace,
+			winner.Name,
+		)
+}
+
+// InstanceConflictCondition returns a condition indicating that the instance
+// is not advertised because another instance with the same fully-qualified
+// name takes precedence.
+func InstanceConflictCondition(winner *DNSSDServiceInstance) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeAdopted,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonConflict,
+		Message: fmt.Sprintf("the older %s/%s instance has the same name, service type and domain", winner.Namespace, winner.Name),
+	}
+}
+
+// ReasonProviderUnavailable is the reason of the Adopted condition of an
+// instance that is associated with a provider that is not configured.
+const ReasonProviderUnavailable = "ProviderUnavailable"
+
+// ProviderUnavailable records an event indicating that the service instance
+// is associated with a provider that is not configured.
+func ProviderUnavailable(m manager.Manager, res *DNSSDServiceInstance, action string) {
+	m.
+		GetEventRecorderFor("proclaim").
+		Eventf(
+			res,
+			"Warning",
+			ReasonProviderUnavailable,
+			"the %q provider is not configured, %s",
+			res.Status.Provider,
+			action,
+		)
+}
+
+// ProviderUnavailableCondition returns a condition indicating that the
+// instance is associated with a provider that is not configured.
+func ProviderUnavailableCondition(provider, action string) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeAdopted,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonProviderUnavailable,
+		Message: fmt.Sprintf("the %q provider is not configured, %s", provider, action),
+	}
+}
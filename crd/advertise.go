@@ -1,6 +1,8 @@
 package crd
 
 import (
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
@@ -57,6 +59,60 @@ func DNSRecordsObservedCondition() metav1.Condition {
 	}
 }
 
+// ReasonPropagating is the reason used for the Advertised condition when
+// changes to the instance's DNS records have been made, but have not yet
+// propagated to all of the provider's name servers.
+const ReasonPropagating = "Propagating"
+
+// DNSRecordsPropagatingCondition returns a condition indicating that the
+// instance's DNS records have been created or updated, but the changes have
+// not yet propagated to all of the provider's name servers.
+func DNSRecordsPropagatingCondition() metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeAdvertised,
+		Status:  metav1.ConditionTrue,
+		Reason:  ReasonPropagating,
+		Message: "updated DNS records, waiting for changes to propagate",
+	}
+}
+
+// DNSRecordsPropagated records an event indicating that changes to DNS records
+// have propagated to all of the provider's name servers.
+func DNSRecordsPropagated(m manager.Manager, res *DNSSDServiceInstance) {
+	m.
+		GetEventRecorderFor("proclaim-"+res.Status.Provider).
+		Event(
+			res,
+			"Normal",
+			"RecordsPropagated",
+			"changes to DNS records have propagated",
+		)
+}
+
+// DNSRecordsPropagatedCondition returns a condition indicating that changes to
+// the instance's DNS records have propagated to all of the provider's name
+// servers.
+func DNSRecordsPropagatedCondition() metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeAdvertised,
+		Status:  metav1.ConditionTrue,
+		Reason:  "RecordsPropagated",
+		Message: "changes to DNS records have propagated",
+	}
+}
+
+// DNSRecordsPropagationUnknownCondition returns a condition indicating that
+// the instance's DNS records have been created or updated, but the provider
+// was unable to report whether the changes have propagated.
+func DNSRecordsPropagationUnknownCondition(err error) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeAdvertised,
+		Status:  metav1.ConditionTrue,
+		Reason:  "PropagationUnknown",
+		Message: "unable to determine whether changes to DNS records have propagated: " + err.Error(),
+	}
+}
+
 // DNSRecordsDeleted records an event indicating that DNS records were deleted.
 func DNSRecordsDeleted(m manager.Manager, res *DNSSDServiceInstance) {
 	m.
@@ -133,3 +189,61 @@ func UnadvertiseErrorCondition(err error) metav1.Condition {
 		Message: err.Error(),
 	}
 }
+
+// AttributeSourceError records an event indicating that the value of an
+// attribute could not be obtained from a ConfigMap or Secret.
+func AttributeSourceError(
+	m manager.Manager,
+	res *DNSSDServiceInstance,
+	err error,
+) {
+	m.
+		GetEventRecorderFor("proclaim-"+res.Status.Provider).
+		Event(
+			res,
+			"Warning",
+			"AttributeSourceError",
+			err.Error(),
+		)
+}
+
+// AttributeSourceErrorCondition returns a condition indicating that the
+// instance can not be advertised because the value of an attribute could not
+// be obtained from a ConfigMap or Secret.
+func AttributeSourceErrorCondition(err error) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeAdvertised,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "AttributeSourceError",
+		Message: err.Error(),
+	}
+}
+
+// InvalidAddresses records an event indicating that the instance can not be
+// advertised because some of its target's addresses are invalid.
+func InvalidAddresses(
+	m manager.Manager,
+	res *DNSSDServiceInstance,
+	addresses []string,
+) {
+	m.
+		GetEventRecorderFor("proclaim-"+res.Status.Provider).
+		Eventf(
+			res,
+			"Warning",
+			"InvalidAddress",
+			"invalid target addresses: %s",
+			strings.Join(addresses, ", "),
+		)
+}
+
+// InvalidAddressesCondition returns a condition indicating that the instance
+// can not be advertised because some of its target's addresses are invalid.
+func InvalidAddressesCondition(addresses []string) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeAdvertised,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "InvalidAddress",
+		Message: "invalid target addresses: " + strings.Join(addresses, ", "),
+	}
+}
@@ -2,6 +2,7 @@ package crd
 
 import (
 	"fmt"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -86,32 +87,76 @@ func NegativeLookupResultCondition() metav1.Condition {
 // LookupResultOutOfSync records an event indicating that the service instance
 // was discovered via DNS-SD, but the result did not match the advertised DNS
 // records.
-func LookupResultOutOfSync(m manager.Manager, res *DNSSDServiceInstance, diff string) {
+func LookupResultOutOfSync(m manager.Manager, res *DNSSDServiceInstance, diff []Difference) {
 	m.
 		GetEventRecorderFor("proclaim-dnssd").
 		Event(
 			res,
 			"Warning",
 			"LookupResultOutOfSync",
-			"instance discovered with incorrect (potentially cached) values: "+diff,
+			"instance discovered with incorrect (potentially cached) values: "+formatDifferences(diff),
 		)
 }
 
 // LookupResultOutOfSyncCondition returns a condition indicating that the
 // instance was found by a DNS-SD lookup operation, but the result did not match
 // the advertised DNS records.
-func LookupResultOutOfSyncCondition(diff string) metav1.Condition {
+func LookupResultOutOfSyncCondition(diff []Difference) metav1.Condition {
 	return metav1.Condition{
 		Type:   ConditionTypeDiscoverable,
 		Status: metav1.ConditionFalse,
 		Reason: "LookupResultOutOfSync",
 		Message: fmt.Sprintf(
 			"DNS-SD lookup result does not match the advertised DNS records: %s",
-			diff,
+			formatDifferences(diff),
 		),
 	}
 }
 
+// ReasonLookupResultStale is the reason used for the "Discoverable" condition
+// when the discovered DNS records only differ from the advertised records in a
+// way that can be explained by DNS caching.
+const ReasonLookupResultStale = "LookupResultStale"
+
+// LookupResultStale records an event indicating that the service instance was
+// discovered via DNS-SD, but the result contained values that are likely
+// cached from before the instance was last advertised.
+func LookupResultStale(m manager.Manager, res *DNSSDServiceInstance, diff []Difference) {
+	m.
+		GetEventRecorderFor("proclaim-dnssd").
+		Event(
+			res,
+			"Normal",
+			ReasonLookupResultStale,
+			"instance discovered with potentially cached values: "+formatDifferences(diff),
+		)
+}
+
+// LookupResultStaleCondition returns a condition indicating that the instance
+// was found by a DNS-SD lookup operation, but the result contained values that
+// are likely cached from before the instance was last advertised.
+func LookupResultStaleCondition(diff []Difference) metav1.Condition {
+	return metav1.Condition{
+		Type:   ConditionTypeDiscoverable,
+		Status: metav1.ConditionFalse,
+		Reason: ReasonLookupResultStale,
+		Message: fmt.Sprintf(
+			"DNS-SD lookup result contains potentially cached values: %s",
+			formatDifferences(diff),
+		),
+	}
+}
+
+// formatDifferences returns a human-readable description of a set of
+// differences.
+func formatDifferences(diff []Difference) string {
+	var parts []string
+	for _, d := range diff {
+		parts = append(parts, d.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
 // DiscoveryError records an event indicating that an error occurred while
 // performing DNS-SD discovery.
 func DiscoveryError(
@@ -140,3 +185,14 @@ func DiscoveryErrorCondition(err error) metav1.Condition {
 		Message: err.Error(),
 	}
 }
+
+// DiscoveryDisabledCondition returns a condition indicating that DNS-SD
+// discovery is not performed for the instance.
+func DiscoveryDisabledCondition() metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeDiscoverable,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "DiscoveryDisabled",
+		Message: "DNS-SD discovery is disabled for this instance",
+	}
+}
@@ -0,0 +1,80 @@
+package crd
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// MigrateToAnnotation is the annotation that requests that a service instance
+// be migrated to the provider with the given ID.
+const MigrateToAnnotation = GroupName + "/migrate-to"
+
+// ConditionTypeMigrated is a condition that indicates whether or not the
+// service instance has been migrated to the provider requested by the
+// MigrateToAnnotation.
+const ConditionTypeMigrated = "Migrated"
+
+// MigrationPendingCondition returns a condition indicating that the instance
+// has been advertised by the new provider, but is not yet discoverable.
+func MigrationPendingCondition(m *Migration) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeMigrated,
+		Status:  metav1.ConditionFalse,
+		Reason:  "MigrationPending",
+		Message: fmt.Sprintf("waiting for the instance to be discoverable after advertising via %s", m.ProviderDescription),
+	}
+}
+
+// MigrationFailed records an event indicating that the instance could not be
+// migrated to the requested provider.
+func MigrationFailed(m manager.Manager, res *DNSSDServiceInstance, err error) {
+	m.
+		GetEventRecorderFor("proclaim").
+		Eventf(
+			res,
+			"Warning",
+			"MigrationFailed",
+			"unable to migrate to the %q provider: %s",
+			res.Annotations[MigrateToAnnotation],
+			err.Error(),
+		)
+}
+
+// MigrationFailedCondition returns a condition indicating that the instance
+// could not be migrated to the requested provider.
+func MigrationFailedCondition(err error) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeMigrated,
+		Status:  metav1.ConditionFalse,
+		Reason:  "MigrationFailed",
+		Message: err.Error(),
+	}
+}
+
+// Migrated records an event indicating that the instance was migrated to a
+// different provider or advertiser.
+func Migrated(m manager.Manager, res *DNSSDServiceInstance, from string) {
+	m.
+		GetEventRecorderFor("proclaim-"+res.Status.Provider).
+		Eventf(
+			res,
+			"Normal",
+			"Migrated",
+			"migrated from %s to %s",
+			from,
+			res.Status.ProviderDescription,
+		)
+}
+
+// MigratedCondition returns a condition indicating that the instance was
+// migrated to the requested provider.
+func MigratedCondition() metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeMigrated,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Migrated",
+		Message: "the instance is advertised by the requested provider",
+	}
+}
@@ -0,0 +1,67 @@
+package crd
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// PausedAnnotation is the annotation that pauses reconciliation of a service
+// instance when it is set to "true".
+const PausedAnnotation = GroupName + "/paused"
+
+// ConditionTypePaused is a condition that indicates whether or not
+// reconciliation of the service instance is paused.
+const ConditionTypePaused = "Paused"
+
+// IsPaused returns true if reconciliation of the service instance is paused.
+func (res *DNSSDServiceInstance) IsPaused() bool {
+	return res.Annotations[PausedAnnotation] == "true"
+}
+
+// ReconciliationPaused records an event indicating that reconciliation of the
+// service instance has been paused.
+func ReconciliationPaused(m manager.Manager, res *DNSSDServiceInstance) {
+	m.
+		GetEventRecorderFor("proclaim").
+		Event(
+			res,
+			"Normal",
+			"ReconciliationPaused",
+			"the DNS records and status are not modified until the "+PausedAnnotation+" annotation is removed",
+		)
+}
+
+// ReconciliationPausedCondition returns a condition indicating that
+// reconciliation of the service instance is paused.
+func ReconciliationPausedCondition() metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypePaused,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ReconciliationPaused",
+		Message: "the " + PausedAnnotation + " annotation is set",
+	}
+}
+
+// ReconciliationResumed records an event indicating that reconciliation of
+// the service instance has been resumed.
+func ReconciliationResumed(m manager.Manager, res *DNSSDServiceInstance) {
+	m.
+		GetEventRecorderFor("proclaim").
+		Event(
+			res,
+			"Normal",
+			"ReconciliationResumed",
+			"the "+PausedAnnotation+" annotation has been removed",
+		)
+}
+
+// ReconciliationResumedCondition returns a condition indicating that
+// reconciliation of the service instance has been resumed.
+func ReconciliationResumedCondition() metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypePaused,
+		Status:  metav1.ConditionFalse,
+		Reason:  "ReconciliationResumed",
+		Message: "the " + PausedAnnotation + " annotation is not set",
+	}
+}
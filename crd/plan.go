@@ -0,0 +1,64 @@
+package crd
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// RecordChangeCreate is a RecordChangeAction that creates or updates a DNS
+	// record.
+	RecordChangeCreate RecordChangeAction = "Create"
+
+	// RecordChangeDelete is a RecordChangeAction that deletes a DNS record.
+	RecordChangeDelete RecordChangeAction = "Delete"
+)
+
+// RecordChangeAction is an enumeration of the actions that can be performed on
+// a DNS record.
+type RecordChangeAction string
+
+// RecordChange describes a change to a DNS record that the controller would
+// make if it were not running in dry-run mode.
+type RecordChange struct {
+	Action RecordChangeAction `json:"action"`
+	Record `json:",inline"`
+}
+
+// String returns a brief human-readable description of the change.
+func (c RecordChange) String() string {
+	return fmt.Sprintf("%s %s %s %s", c.Action, c.Type, c.Name, c.Value)
+}
+
+// RecordChangesPlanned records an event indicating that DNS records would be
+// changed if the controller were not running in dry-run mode.
+func RecordChangesPlanned(m manager.Manager, res *DNSSDServiceInstance, changes []RecordChange) {
+	var w strings.Builder
+	w.WriteString("dry-run: ")
+
+	for i, c := range changes {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.WriteString(c.String())
+	}
+
+	m.
+		GetEventRecorderFor("proclaim-"+res.Status.Provider).
+		Event(
+			res,
+			"Normal",
+			"RecordChangesPlanned",
+			w.String(),
+		)
+}
+
+// UpdatePlannedChanges is an StatusUpdate that sets the PlannedChanges field
+// of the resource's status.
+func UpdatePlannedChanges(changes []RecordChange) StatusUpdate {
+	return func(res *DNSSDServiceInstance) {
+		res.Status.PlannedChanges = changes
+	}
+}
@@ -0,0 +1,56 @@
+package crd
+
+import (
+	"github.com/dogmatiq/dyad"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DNSSDPolicy is a cluster-scoped resource that permits the DNS-SD service
+// instances within specific namespaces to be advertised on specific domains
+// and service types.
+type DNSSDPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DNSSDPolicySpec `json:"spec,omitempty"`
+}
+
+// DeepCopyObject returns a deep clone of p.
+func (p *DNSSDPolicy) DeepCopyObject() runtime.Object {
+	return dyad.Clone(p)
+}
+
+// DNSSDPolicyList is a list of DNS-SD policies.
+type DNSSDPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []DNSSDPolicy `json:"items"`
+}
+
+// DeepCopyObject returns a deep clone of l.
+func (l *DNSSDPolicyList) DeepCopyObject() runtime.Object {
+	return dyad.Clone(l)
+}
+
+// DNSSDPolicySpec is the specification of a DNS-SD policy.
+type DNSSDPolicySpec struct {
+	// Namespaces is a list of the names of the namespaces to which the policy
+	// applies.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects additional namespaces to which the policy
+	// applies by their labels. An empty selector matches all namespaces.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Domains is a list of the domains on which instances within the selected
+	// namespaces may be advertised. Subdomains of each domain are also
+	// permitted. If it is empty, any domain is permitted.
+	Domains []string `json:"domains,omitempty"`
+
+	// ServiceTypes is a list of the service types that instances within the
+	// selected namespaces may advertise. If it is empty, any service type is
+	// permitted.
+	ServiceTypes []string `json:"serviceTypes,omitempty"`
+}
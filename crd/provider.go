@@ -0,0 +1,215 @@
+package crd
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/dogmatiq/dyad"
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// ProviderTypeRoute53 is the DNSProvider type for Amazon Route 53.
+	ProviderTypeRoute53 = "route53"
+
+	// ProviderTypeDNSimple is the DNSProvider type for DNSimple.
+	ProviderTypeDNSimple = "dnsimple"
+)
+
+// DNSProvider is a cluster-scoped resource that configures a DNS provider on
+// which DNS-SD service instances are advertised.
+type DNSProvider struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DNSProviderSpec   `json:"spec,omitempty"`
+	Status DNSProviderStatus `json:"status,omitempty"`
+}
+
+// DeepCopyObject returns a deep clone of p.
+func (p *DNSProvider) DeepCopyObject() runtime.Object {
+	return dyad.Clone(p)
+}
+
+// DNSProviderList is a list of DNS providers.
+type DNSProviderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []DNSProvider `json:"items"`
+}
+
+// DeepCopyObject returns a deep clone of l.
+func (l *DNSProviderList) DeepCopyObject() runtime.Object {
+	return dyad.Clone(l)
+}
+
+// DNSProviderSpec is the specification of a DNS provider.
+type DNSProviderSpec struct {
+	// Type is the type of the provider, one of the ProviderType constants.
+	Type string `json:"type"`
+
+	// CredentialsSecretRef refers to a Secret containing the provider's
+	// credentials. The keys within the Secret are the same as the environment
+	// variables used to configure the equivalent provider, such as
+	// DNSIMPLE_TOKEN or AWS_ACCESS_KEY_ID.
+	CredentialsSecretRef *corev1.SecretReference `json:"credentialsSecretRef,omitempty"`
+
+	// APIURL overrides the URL of the provider's API.
+	APIURL string `json:"apiURL,omitempty"`
+
+	// Domains is a list of the domains on which the provider may advertise.
+	// Subdomains of each domain are also permitted. If it is empty, the
+	// provider may advertise on any domain that it hosts.
+	Domains []string `json:"domains,omitempty"`
+
+	// DeniedDomains is a list of the domains on which the provider must not
+	// advertise, even if they are within one of the permitted domains.
+	// Subdomains of each domain are also denied.
+	DeniedDomains []string `json:"deniedDomains,omitempty"`
+
+	// InstanceClassName is the name of the instance class of the controller
+	// that uses this provider. If it is empty, the provider is used by
+	// controllers without a class.
+	InstanceClassName string `json:"instanceClassName,omitempty"`
+}
+
+// DNSProviderStatus contains the status of a DNS provider.
+type DNSProviderStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Provider is the ID of the provider, as stored in the status of each
+	// service instance that it advertises.
+	Provider            string `json:"provider,omitempty"`
+	ProviderDescription string `json:"providerDescription,omitempty"`
+}
+
+// Condition returns the condition with the given type.
+func (p *DNSProvider) Condition(t string) metav1.Condition {
+	for _, c := range p.Status.Conditions {
+		if c.Type == t {
+			return c
+		}
+	}
+
+	return metav1.Condition{
+		Type:   t,
+		Status: metav1.ConditionUnknown,
+	}
+}
+
+// UpdateDNSProviderStatus sets the status of the given DNS provider, merging
+// the given condition into any existing conditions.
+func UpdateDNSProviderStatus(
+	ctx context.Context,
+	cli client.Client,
+	p *DNSProvider,
+	id, desc string,
+	c metav1.Condition,
+) error {
+	clone := dyad.Clone(p)
+	clone.Status.Provider = id
+	clone.Status.ProviderDescription = desc
+
+	c.ObservedGeneration = clone.Generation
+	c.LastTransitionTime = metav1.Now()
+
+	index := slices.IndexFunc(
+		clone.Status.Conditions,
+		func(x metav1.Condition) bool {
+			return x.Type == c.Type
+		},
+	)
+
+	if index == -1 {
+		clone.Status.Conditions = append(clone.Status.Conditions, c)
+	} else {
+		if x := clone.Status.Conditions[index]; x.Status == c.Status {
+			c.LastTransitionTime = x.LastTransitionTime
+		}
+		clone.Status.Conditions[index] = c
+	}
+
+	if reflect.DeepEqual(clone.Status, p.Status) {
+		return nil
+	}
+
+	if err := cli.Status().Update(ctx, clone); err != nil {
+		return err
+	}
+
+	*p = *clone
+
+	return nil
+}
+
+// ConditionTypeReady is a condition that indicates whether or not a DNS
+// provider is able to advertise service instances.
+const ConditionTypeReady = "Ready"
+
+// ProviderReadyCondition returns a condition indicating that the DNS provider
+// is configured and healthy.
+func ProviderReadyCondition() metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ProviderReady",
+		Message: "the provider is configured and healthy",
+	}
+}
+
+// ProviderConfigurationError records an event indicating that the DNS
+// provider could not be built from its specification.
+func ProviderConfigurationError(m manager.Manager, p *DNSProvider, err error) {
+	m.
+		GetEventRecorderFor("proclaim").
+		Eventf(
+			p,
+			"Warning",
+			"ProviderConfigurationError",
+			"unable to configure provider: %s",
+			err.Error(),
+		)
+}
+
+// ProviderConfigurationErrorCondition returns a condition indicating that
+// the DNS provider could not be built from its specification.
+func ProviderConfigurationErrorCondition(err error) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  "ProviderConfigurationError",
+		Message: err.Error(),
+	}
+}
+
+// ProviderUnhealthy records an event indicating that the DNS provider's
+// health check failed.
+func ProviderUnhealthy(m manager.Manager, p *DNSProvider, err error) {
+	m.
+		GetEventRecorderFor("proclaim-"+p.Status.Provider).
+		Eventf(
+			p,
+			"Warning",
+			"ProviderUnhealthy",
+			"%s health check failed: %s",
+			p.Status.ProviderDescription,
+			err.Error(),
+		)
+}
+
+// ProviderUnhealthyCondition returns a condition indicating that the DNS
+// provider's health check failed.
+func ProviderUnhealthyCondition(err error) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionTypeReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  "ProviderUnhealthy",
+		Message: err.Error(),
+	}
+}
@@ -1,11 +1,18 @@
 package crd
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dogmatiq/dissolve/dnssd"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -17,19 +24,144 @@ type Instance struct {
 	TTL         metav1.Duration  `json:"ttl,omitempty"`
 	Targets     [1]Target        `json:"targets"`
 	Attributes  []map[string]any `json:"attributes,omitempty"`
+	Subtypes    []string         `json:"subtypes,omitempty"`
 }
 
 // Target describes a single target address for a DNS service instance.
 type Target struct {
-	Host     string `json:"host"`
+	Host     string `json:"host,omitempty"`
 	Port     uint16 `json:"port"`
 	Priority uint16 `json:"priority,omitempty"`
 	Weight   uint16 `json:"weight,omitempty"`
+
+	// Addresses is a list of IPv4 and/or IPv6 addresses of the target host.
+	// If it is non-empty, the controller manages the A and AAAA records of
+	// the target host in addition to the instance's DNS-SD records.
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// AttributeValue is the explicit form of an attribute value, used in place of
+// a scalar value within an attribute set.
+//
+// Exactly one of its fields should be set.
+type AttributeValue struct {
+	// Value is used exactly as given. Unlike scalar values, numbers are not
+	// reformatted, and an empty string produces a key with an empty value
+	// ("key="), which is distinct from a flag ("key").
+	Value *string `json:"value,omitempty"`
+
+	// BinaryValue is a base64-encoded value that may contain arbitrary bytes.
+	BinaryValue []byte `json:"binaryValue,omitempty"`
+
+	// ValueFrom obtains the value from a ConfigMap or Secret.
+	ValueFrom *AttributeValueSource `json:"valueFrom,omitempty"`
+}
+
+// AttributeSourceLabel is the label that must be set to "true" on a Secret
+// before its values may be used as attribute values.
+//
+// Attribute values are published in DNS records, and copied into the status
+// and events of the instance, so Secrets are not used unless they explicitly
+// opt in.
+const AttributeSourceLabel = GroupName + "/attribute-source"
+
+// AttributeValueSource describes a source for the value of an attribute, as an
+// alternative to specifying the value directly.
+//
+// Values obtained from a Secret are only used if the Secret has the
+// AttributeSourceLabel label.
+type AttributeValueSource struct {
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	SecretKeyRef    *corev1.SecretKeySelector    `json:"secretKeyRef,omitempty"`
+}
+
+// Discovery configures how the controller verifies that a service instance is
+// discoverable via DNS-SD.
+type Discovery struct {
+	// Enabled overrides the controller's default setting for whether DNS-SD
+	// discovery is performed for this instance.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Interval is the interval at which the instance is checked for drift
+	// once it has been advertised. If it is zero, the controller's default
+	// interval is used.
+	Interval metav1.Duration `json:"interval,omitempty"`
+}
+
+// RoutingPolicy determines how the DNS provider chooses between several sets
+// of DNS records with the same name, such as those of an instance that is
+// advertised by several clusters.
+//
+// Exactly one of Weight, Region, Geolocation or Failover must be set.
+type RoutingPolicy struct {
+	// SetIdentifier distinguishes the instance's records from other records
+	// with the same name.
+	SetIdentifier string `json:"setIdentifier"`
+
+	// Weight selects records in proportion to their weight relative to the
+	// total weight of all records with the same name.
+	Weight *int64 `json:"weight,omitempty"`
+
+	// Region selects the records associated with the region that has the
+	// lowest latency to the client.
+	Region string `json:"region,omitempty"`
+
+	// Geolocation selects records based on the location of the client.
+	Geolocation *Geolocation `json:"geolocation,omitempty"`
+
+	// Failover is either "PRIMARY" or "SECONDARY". Secondary records are only
+	// used when the primary records are unhealthy.
+	Failover string `json:"failover,omitempty"`
+
+	// HealthCheck is the health check that determines whether the instance's
+	// records are returned. If it is nil, the records are always considered
+	// healthy.
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+}
+
+// HealthCheck describes a health check that is associated with a service
+// instance's records.
+//
+// If ID is empty, a health check of the instance's target host and port is
+// created when the instance is advertised, and deleted when it is
+// unadvertised.
+type HealthCheck struct {
+	// ID is the ID of an existing health check.
+	ID string `json:"id,omitempty"`
+
+	// Protocol is the protocol used to check the target, either "TCP",
+	// "HTTP" or "HTTPS". If it is empty, "TCP" is used.
+	Protocol string `json:"protocol,omitempty"`
+
+	// Path is the path requested when the protocol is "HTTP" or "HTTPS".
+	Path string `json:"path,omitempty"`
+
+	// FailureThreshold is the number of consecutive checks that must fail
+	// before the target is considered unhealthy. If it is zero, 3 is used.
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// Geolocation describes the location of the clients to which records are
+// returned by a geolocation routing policy.
+type Geolocation struct {
+	Continent   string `json:"continent,omitempty"`
+	Country     string `json:"country,omitempty"`
+	Subdivision string `json:"subdivision,omitempty"`
 }
 
 // DNSSDServiceInstanceSpec is the specification for a service instance.
 type DNSSDServiceInstanceSpec struct {
-	Instance Instance `json:"instance"`
+	Instance  Instance  `json:"instance"`
+	Discovery Discovery `json:"discovery,omitempty"`
+
+	// Routing is the routing policy used to advertise the instance. If it is
+	// nil, the instance's records replace any others with the same name.
+	Routing *RoutingPolicy `json:"routing,omitempty"`
+
+	// InstanceClassName is the name of the instance class, which determines
+	// which of several Proclaim controllers manages the instance. If it is
+	// empty, the instance is managed by controllers without a class.
+	InstanceClassName string `json:"instanceClassName,omitempty"`
 }
 
 // ToDissolve returns a Dissolve dnssd.Instance from a CRD service instance
@@ -52,6 +184,12 @@ func (s DNSSDServiceInstanceSpec) ToDissolve() dnssd.ServiceInstance {
 		inst.TTL = 60 * time.Second
 	}
 
+	// If the target has no host name of its own, its address records are
+	// published under a host name derived from the service instance's name.
+	if inst.TargetHost == "" {
+		inst.TargetHost = defaultTargetHost(inst.ServiceInstanceName)
+	}
+
 	for _, src := range s.Instance.Attributes {
 		var dst dnssd.Attributes
 
@@ -71,6 +209,20 @@ func (s DNSSDServiceInstanceSpec) ToDissolve() dnssd.ServiceInstance {
 				dst = dst.WithPair(k, []byte(s))
 			case nil:
 				// ignore
+			case map[string]any:
+				// Invalid values are rejected by ResolveAttributes(), so they
+				// are never advertised.
+				av, err := parseAttributeValue(v)
+				if err != nil {
+					continue
+				}
+				if av.Value != nil {
+					dst = dst.WithPair(k, []byte(*av.Value))
+				} else if av.BinaryValue != nil {
+					dst = dst.WithPair(k, av.BinaryValue)
+				}
+				// Values that refer to a ConfigMap or Secret are ignored
+				// unless they have been replaced using ResolveAttributes().
 			default:
 				// TODO: A validating web-hook will make it so this branch
 				// cannot be reached.
@@ -85,3 +237,192 @@ func (s DNSSDServiceInstanceSpec) ToDissolve() dnssd.ServiceInstance {
 
 	return inst
 }
+
+// AttributeValueSources returns the sources of all attribute values that
+// refer to a ConfigMap or Secret.
+func (s DNSSDServiceInstanceSpec) AttributeValueSources() []AttributeValueSource {
+	var sources []AttributeValueSource
+
+	for _, attrs := range s.Instance.Attributes {
+		for _, v := range attrs {
+			if av, err := parseAttributeValue(v); err == nil && av.ValueFrom != nil {
+				sources = append(sources, *av.ValueFrom)
+			}
+		}
+	}
+
+	return sources
+}
+
+// ResolveAttributes returns a copy of s in which each attribute value that
+// refers to a ConfigMap or Secret is replaced with the value returned by
+// resolve.
+//
+// If resolve returns ok == false the attribute is omitted. It returns an
+// AttributeValueError if any attribute value given in the explicit form is
+// invalid.
+func (s DNSSDServiceInstanceSpec) ResolveAttributes(
+	resolve func(AttributeValueSource) (value string, ok bool, err error),
+) (DNSSDServiceInstanceSpec, error) {
+	attributes := make([]map[string]any, 0, len(s.Instance.Attributes))
+
+	for _, src := range s.Instance.Attributes {
+		dst := make(map[string]any, len(src))
+
+		for k, v := range src {
+			av, err := parseAttributeValue(v)
+			if err != nil {
+				return DNSSDServiceInstanceSpec{}, AttributeValueError{k, err}
+			}
+
+			if av.ValueFrom != nil {
+				value, ok, err := resolve(*av.ValueFrom)
+				if err != nil {
+					return DNSSDServiceInstanceSpec{}, fmt.Errorf("unable to resolve %q attribute: %w", k, err)
+				}
+				if !ok {
+					continue
+				}
+				v = value
+			}
+
+			dst[k] = v
+		}
+
+		attributes = append(attributes, dst)
+	}
+
+	s.Instance.Attributes = attributes
+	return s, nil
+}
+
+// AttributeValueError indicates that an attribute value given in the explicit
+// form is invalid.
+type AttributeValueError struct {
+	Key string
+	Err error
+}
+
+func (e AttributeValueError) Error() string {
+	return fmt.Sprintf("invalid value for %q attribute: %s", e.Key, e.Err)
+}
+
+func (e AttributeValueError) Unwrap() error {
+	return e.Err
+}
+
+// parseAttributeValue returns the explicit attribute value described by v.
+//
+// If v is not a map it is a scalar value, and the returned value is empty.
+func parseAttributeValue(v any) (AttributeValue, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return AttributeValue{}, nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return AttributeValue{}, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var av AttributeValue
+	if err := dec.Decode(&av); err != nil {
+		return AttributeValue{}, err
+	}
+
+	n := 0
+	for _, set := range []bool{av.Value != nil, av.BinaryValue != nil, av.ValueFrom != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return AttributeValue{}, errors.New("exactly one of value, binaryValue or valueFrom must be specified")
+	}
+
+	if src := av.ValueFrom; src != nil {
+		switch {
+		case src.ConfigMapKeyRef != nil && src.SecretKeyRef != nil,
+			src.ConfigMapKeyRef == nil && src.SecretKeyRef == nil:
+			return AttributeValue{}, errors.New("exactly one of configMapKeyRef or secretKeyRef must be specified")
+		case src.ConfigMapKeyRef != nil && (src.ConfigMapKeyRef.Name == "" || src.ConfigMapKeyRef.Key == ""),
+			src.SecretKeyRef != nil && (src.SecretKeyRef.Name == "" || src.SecretKeyRef.Key == ""):
+			return AttributeValue{}, errors.New("both name and key must be specified")
+		}
+	}
+
+	return av, nil
+}
+
+// AdvertiseOptions returns the Dissolve advertise options that describe the
+// records to publish in addition to those of the service instance itself.
+func (s DNSSDServiceInstanceSpec) AdvertiseOptions() []dnssd.AdvertiseOption {
+	var options []dnssd.AdvertiseOption
+
+	for _, st := range s.Instance.Subtypes {
+		options = append(options, dnssd.WithServiceSubType(st))
+	}
+
+	for _, addr := range s.Instance.Targets[0].Addresses {
+		if ip := net.ParseIP(addr); ip != nil {
+			options = append(options, dnssd.WithIPAddress(ip))
+		}
+	}
+
+	return options
+}
+
+// InvalidAddresses returns the addresses of the target that are not valid IPv4
+// or IPv6 addresses.
+//
+// Such addresses are omitted from the options returned by AdvertiseOptions(),
+// so the instance should not be advertised unless this list is empty.
+func (s DNSSDServiceInstanceSpec) InvalidAddresses() []string {
+	var invalid []string
+
+	for _, addr := range s.Instance.Targets[0].Addresses {
+		if net.ParseIP(addr) == nil {
+			invalid = append(invalid, addr)
+		}
+	}
+
+	return invalid
+}
+
+// defaultTargetHost returns the host name of the target of a service instance
+// that does not specify a host name of its own.
+//
+// The instance name may contain spaces and arbitrary UTF-8 characters, so it
+// can not be used as a host name directly. Instead, it is reduced to a label
+// containing only lowercase letters, digits and hyphens. A hash of the
+// instance's fully-qualified name is appended to the label so that instances
+// with similar names do not share the same host.
+func defaultTargetHost(n dnssd.ServiceInstanceName) string {
+	// The hash uses 9 characters of the 63 permitted within a single label,
+	// including the hyphen that separates it from the prefix.
+	const maxPrefixLen = 63 - 9
+
+	var prefix []byte
+	for _, r := range strings.ToLower(n.Name) {
+		switch {
+		case len(prefix) == maxPrefixLen:
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			prefix = append(prefix, byte(r))
+		case len(prefix) != 0 && prefix[len(prefix)-1] != '-':
+			prefix = append(prefix, '-')
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(n.Absolute())))
+
+	label := fmt.Sprintf("%08x", h.Sum32())
+	if p := strings.TrimSuffix(string(prefix), "-"); p != "" {
+		label = p + "-" + label
+	}
+
+	return label + "." + strings.TrimSuffix(n.Domain, ".")
+}
@@ -0,0 +1,138 @@
+package crd
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestDNSSDServiceInstanceSpec_ResolveAttributes(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Value any
+		Valid bool
+	}{
+		{"scalar", "value", true},
+		{"explicit value", map[string]any{"value": "v"}, true},
+		{"binary value", map[string]any{"binaryValue": "dg=="}, true},
+		{"config map reference", map[string]any{"valueFrom": map[string]any{"configMapKeyRef": map[string]any{"name": "n", "key": "k"}}}, true},
+		{"secret reference", map[string]any{"valueFrom": map[string]any{"secretKeyRef": map[string]any{"name": "n", "key": "k"}}}, true},
+		{"empty map", map[string]any{}, false},
+		{"unknown field", map[string]any{"valu": "v"}, false},
+		{"multiple forms", map[string]any{"value": "v", "binaryValue": "dg=="}, false},
+		{"invalid base64", map[string]any{"binaryValue": "!"}, false},
+		{"empty source", map[string]any{"valueFrom": map[string]any{}}, false},
+		{"missing key", map[string]any{"valueFrom": map[string]any{"secretKeyRef": map[string]any{"name": "n"}}}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			spec := DNSSDServiceInstanceSpec{
+				Instance: Instance{
+					Attributes: []map[string]any{
+						{"attr": c.Value},
+					},
+				},
+			}
+
+			_, err := spec.ResolveAttributes(
+				func(AttributeValueSource) (string, bool, error) {
+					return "resolved", true, nil
+				},
+			)
+
+			var e AttributeValueError
+			if c.Valid && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			} else if !c.Valid && !errors.As(err, &e) {
+				t.Fatalf("expected an AttributeValueError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDNSSDServiceInstanceSpec_ToDissolve(t *testing.T) {
+	spec := func(name, host string) DNSSDServiceInstanceSpec {
+		return DNSSDServiceInstanceSpec{
+			Instance: Instance{
+				Name:        name,
+				ServiceType: "_http._tcp",
+				Domain:      "example.org",
+				Targets: [1]Target{
+					{Host: host, Port: 80},
+				},
+			},
+		}
+	}
+
+	t.Run("it uses the target's host name", func(t *testing.T) {
+		if got := spec("Instance", "host.example.org").ToDissolve().TargetHost; got != "host.example.org" {
+			t.Fatalf("unexpected host: got %q, want %q", got, "host.example.org")
+		}
+	})
+
+	t.Run("it derives a valid host name from the instance name", func(t *testing.T) {
+		cases := []struct {
+			Name   string
+			Prefix string
+		}{
+			{"Instance", "instance-"},
+			{"My Web Server (primary)", "my-web-server-primary-"},
+			{"Café à la carte", "caf-la-carte-"},
+			{"日本語", ""},
+			{strings.Repeat("x", 100), strings.Repeat("x", 54) + "-"},
+		}
+
+		pattern := regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)\.example\.org$`)
+
+		for _, c := range cases {
+			t.Run(c.Name, func(t *testing.T) {
+				host := spec(c.Name, "").ToDissolve().TargetHost
+
+				if !pattern.MatchString(host) {
+					t.Fatalf("invalid host name: %q", host)
+				}
+
+				if !strings.HasPrefix(host, c.Prefix) {
+					t.Fatalf("unexpected host name: got %q, want prefix %q", host, c.Prefix)
+				}
+			})
+		}
+	})
+
+	t.Run("it derives different host names for similar instance names", func(t *testing.T) {
+		a := spec("Web Server", "").ToDissolve().TargetHost
+		b := spec("web-server", "").ToDissolve().TargetHost
+
+		if a == b {
+			t.Fatalf("expected different host names, got %q for both", a)
+		}
+	})
+}
+
+func TestDNSSDServiceInstanceSpec_InvalidAddresses(t *testing.T) {
+	spec := DNSSDServiceInstanceSpec{
+		Instance: Instance{
+			Targets: [1]Target{
+				{
+					Addresses: []string{
+						"192.0.2.10",
+						"2001:db8::10",
+						"192.0.2.300",
+						"host.example.org",
+					},
+				},
+			},
+		},
+	}
+
+	got := spec.InvalidAddresses()
+	want := []string{"192.0.2.300", "host.example.org"}
+
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected invalid addresses: got %q, want %q", got, want)
+	}
+}
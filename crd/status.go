@@ -2,9 +2,13 @@ package crd
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/dogmatiq/dyad"
+	"github.com/miekg/dns"
 	"golang.org/x/exp/slices"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -17,6 +21,90 @@ type DNSSDServiceInstanceStatus struct {
 	ProviderDescription string         `json:"providerDescription,omitempty"`
 	Provider            string         `json:"provider,omitempty"`
 	Advertiser          map[string]any `json:"advertiser,omitempty"`
+
+	// Controller is the name of the Proclaim controller that associated the
+	// instance with its provider.
+	Controller string `json:"controller,omitempty"`
+
+	Records        []Record     `json:"records,omitempty"`
+	LastAdvertised *metav1.Time `json:"lastAdvertised,omitempty"`
+
+	// PreviousRecords are the records that were replaced when the instance
+	// was last advertised with different records. DNS caches may continue to
+	// hold them until PreviousRecordsExpire.
+	PreviousRecords       []Record     `json:"previousRecords,omitempty"`
+	PreviousRecordsExpire *metav1.Time `json:"previousRecordsExpire,omitempty"`
+
+	PropagatingChanges []string `json:"propagatingChanges,omitempty"`
+
+	Drift []Difference `json:"drift,omitempty"`
+
+	Migration *Migration `json:"migration,omitempty"`
+
+	PlannedChanges []RecordChange `json:"plannedChanges,omitempty"`
+}
+
+// Migration describes an in-progress migration of a service instance to a
+// different provider or advertiser.
+type Migration struct {
+	ProviderDescription string         `json:"providerDescription,omitempty"`
+	Provider            string         `json:"provider"`
+	Advertiser          map[string]any `json:"advertiser,omitempty"`
+}
+
+// Record describes a DNS record that the controller manages on behalf of the
+// service instance.
+type Record struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	TTL   uint32 `json:"ttl"`
+}
+
+// NewRecord returns a Record that describes the given DNS resource record.
+func NewRecord(rr dns.RR) Record {
+	h := rr.Header()
+
+	return Record{
+		Type:  dns.TypeToString[h.Rrtype],
+		Name:  h.Name,
+		Value: strings.TrimPrefix(rr.String(), h.String()),
+		TTL:   h.Ttl,
+	}
+}
+
+// HasRecords returns true if the records in the resource's status are
+// equivalent to the given DNS records, regardless of their order.
+func (res *DNSSDServiceInstance) HasRecords(records []dns.RR) bool {
+	if len(records) != len(res.Status.Records) {
+		return false
+	}
+
+	for _, rr := range records {
+		if !slices.Contains(res.Status.Records, NewRecord(rr)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Difference describes a single value within a service instance's DNS
+// records that was discovered to differ from the desired value.
+//
+// Field is a path to the value within the instance specification, such as
+// "targets[0].port" or "attributes[0].txtvers". Observed and Desired are
+// string representations of the discovered and desired values. An empty string
+// indicates that the value is absent.
+type Difference struct {
+	Field    string `json:"field"`
+	Observed string `json:"observed,omitempty"`
+	Desired  string `json:"desired,omitempty"`
+}
+
+// String returns a brief human-readable description of the difference.
+func (d Difference) String() string {
+	return fmt.Sprintf("%s %q != %q", d.Field, d.Observed, d.Desired)
 }
 
 // Condition returns the condition with the given type.
@@ -113,6 +201,89 @@ func AssociateProvider(provider string, advertiser map[string]any) StatusUpdate
 	}
 }
 
+// AssociateController is an StatusUpdate that sets the Controller field of the
+// resource's status.
+func AssociateController(name string) StatusUpdate {
+	return func(res *DNSSDServiceInstance) {
+		res.Status.Controller = name
+	}
+}
+
+// RecordsAdvertised is an StatusUpdate that sets the Records field of the
+// resource's status to the given DNS records, and the LastAdvertised field to
+// the current time.
+//
+// If the records differ from those already in the status, the existing
+// records are retained as the PreviousRecords until the longest of their TTLs
+// has elapsed.
+func RecordsAdvertised(records []dns.RR) StatusUpdate {
+	return func(res *DNSSDServiceInstance) {
+		now := metav1.Now()
+
+		if len(res.Status.Records) != 0 && !res.HasRecords(records) {
+			var ttl uint32
+			for _, r := range res.Status.Records {
+				ttl = max(ttl, r.TTL)
+			}
+
+			expire := metav1.NewTime(now.Add(time.Duration(ttl) * time.Second))
+			res.Status.PreviousRecords = res.Status.Records
+			res.Status.PreviousRecordsExpire = &expire
+		}
+
+		res.Status.Records = nil
+		for _, rr := range records {
+			res.Status.Records = append(res.Status.Records, NewRecord(rr))
+		}
+
+		res.Status.LastAdvertised = &now
+	}
+}
+
+// RecordsUnadvertised is an StatusUpdate that clears the Records and
+// PreviousRecords fields of the resource's status.
+func RecordsUnadvertised() StatusUpdate {
+	return func(res *DNSSDServiceInstance) {
+		res.Status.Records = nil
+		res.Status.PreviousRecords = nil
+		res.Status.PreviousRecordsExpire = nil
+	}
+}
+
+// CachedRecords returns the records that DNS caches may still hold from
+// before the instance was last advertised with different records, as of the
+// given time.
+func (res *DNSSDServiceInstance) CachedRecords(now time.Time) []Record {
+	if e := res.Status.PreviousRecordsExpire; e == nil || !now.Before(e.Time) {
+		return nil
+	}
+	return res.Status.PreviousRecords
+}
+
+// UpdatePropagatingChanges is an StatusUpdate that sets the PropagatingChanges
+// field of the resource's status.
+func UpdatePropagatingChanges(ids []string) StatusUpdate {
+	return func(res *DNSSDServiceInstance) {
+		res.Status.PropagatingChanges = ids
+	}
+}
+
+// UpdateMigration is an StatusUpdate that sets the Migration field of the
+// resource's status.
+func UpdateMigration(m *Migration) StatusUpdate {
+	return func(res *DNSSDServiceInstance) {
+		res.Status.Migration = m
+	}
+}
+
+// UpdateDrift is an StatusUpdate that sets the Drift field of the resource's
+// status.
+func UpdateDrift(diff []Difference) StatusUpdate {
+	return func(res *DNSSDServiceInstance) {
+		res.Status.Drift = diff
+	}
+}
+
 // If is an StatusUpdate that conditionally applies other StatusUpdates.
 func If(test bool, updates ...StatusUpdate) StatusUpdate {
 	return func(res *DNSSDServiceInstance) {
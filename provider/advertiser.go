@@ -1,6 +1,9 @@
 package provider
 
 import (
+	"context"
+	"time"
+
 	"github.com/dogmatiq/dissolve/dnssd"
 )
 
@@ -13,3 +16,127 @@ type Advertiser interface {
 	// the provider that created it.
 	ID() map[string]any
 }
+
+// Planner is an optional interface that may be implemented by an Advertiser
+// to report the changes it would make without making them.
+type Planner interface {
+	// Plan returns the changes to DNS records that Advertise would make in
+	// order to advertise the given service instance, without making them.
+	//
+	// The changes include the instance's sub-type PTR records and, if
+	// the instance is advertised with any dnssd.WithIPAddress() options, the
+	// address records of its target host.
+	Plan(
+		ctx context.Context,
+		inst dnssd.ServiceInstance,
+		options ...dnssd.AdvertiseOption,
+	) ([]Change, error)
+
+	// PlanUnadvertise returns the changes to DNS records that Unadvertise
+	// would make in order to unadvertise the given service instance, without
+	// making them.
+	PlanUnadvertise(
+		ctx context.Context,
+		inst dnssd.ServiceInstance,
+	) ([]Change, error)
+}
+
+// AsPlanner returns a as a Planner, if it supports planning.
+//
+// It must be used instead of a type assertion, as an advertiser returned by
+// RestrictAdvertiser() only implements the optional interfaces of the
+// advertiser it wraps via these functions.
+func AsPlanner(a Advertiser) (Planner, bool) {
+	if r, ok := a.(*restrictedAdvertiser); ok {
+		return r.planner()
+	}
+	p, ok := a.(Planner)
+	return p, ok
+}
+
+// AddressAdvertiser is an optional interface that may be implemented by an
+// Advertiser that manages the address records of an instance's target host.
+type AddressAdvertiser interface {
+	// UnadvertiseAddresses removes the A and AAAA records of the given host.
+	//
+	// Advertise manages these records when it is called with one or more
+	// dnssd.WithIPAddress() options. Because the target host of an instance
+	// may change, or be shared by several instances, the caller is
+	// responsible for removing them once they are no longer required.
+	//
+	// It returns true if any changes to DNS records were made.
+	UnadvertiseAddresses(
+		ctx context.Context,
+		host string,
+	) (bool, error)
+}
+
+// AsAddressAdvertiser returns a as an AddressAdvertiser, if it manages the
+// address records of an instance's target host.
+func AsAddressAdvertiser(a Advertiser) (AddressAdvertiser, bool) {
+	if r, ok := a.(*restrictedAdvertiser); ok {
+		a = r.Advertiser
+	}
+	aa, ok := a.(AddressAdvertiser)
+	return aa, ok
+}
+
+// EnumerationAdvertiser is an optional interface that may be implemented by an
+// Advertiser to advertise the records used to enumerate service types and
+// browsing domains.
+type EnumerationAdvertiser interface {
+	// AdvertiseServiceType creates and/or updates DNS records to advertise
+	// the given service type within the service type enumeration domain of
+	// the given domain, "_services._dns-sd._udp.<domain>".
+	//
+	// It returns true if any changes to DNS records were made.
+	//
+	// See https://www.rfc-editor.org/rfc/rfc6763#section-9.
+	AdvertiseServiceType(
+		ctx context.Context,
+		serviceType, domain string,
+		ttl time.Duration,
+	) (bool, error)
+
+	// UnadvertiseServiceType removes and/or updates DNS records to stop
+	// advertising the given service type within the service type enumeration
+	// domain of the given domain.
+	//
+	// It returns true if any changes to DNS records were made.
+	UnadvertiseServiceType(
+		ctx context.Context,
+		serviceType, domain string,
+	) (bool, error)
+
+	// AdvertiseBrowsingDomain creates and/or updates DNS records to advertise
+	// browsingDomain as a (legacy) browsing domain of the given domain, using
+	// the "b._dns-sd._udp.<domain>" and "lb._dns-sd._udp.<domain>" records.
+	//
+	// It returns true if any changes to DNS records were made.
+	//
+	// See https://www.rfc-editor.org/rfc/rfc6763#section-11.
+	AdvertiseBrowsingDomain(
+		ctx context.Context,
+		browsingDomain, domain string,
+		ttl time.Duration,
+	) (bool, error)
+
+	// UnadvertiseBrowsingDomain removes and/or updates DNS records to stop
+	// advertising browsingDomain as a browsing domain of the given domain.
+	//
+	// It returns true if any changes to DNS records were made.
+	UnadvertiseBrowsingDomain(
+		ctx context.Context,
+		browsingDomain, domain string,
+	) (bool, error)
+}
+
+// AsEnumerationAdvertiser returns a as an EnumerationAdvertiser, if it
+// advertises service type and browsing domain enumeration records.
+func AsEnumerationAdvertiser(a Advertiser) (EnumerationAdvertiser, bool) {
+	if r, ok := a.(*restrictedAdvertiser); ok {
+		return r.enumeration()
+	}
+	ea, ok := a.(EnumerationAdvertiser)
+	return ea, ok
+}
@@ -1,8 +1,14 @@
 package dnsimpleprovider
 
 import (
+	"context"
+	"strconv"
+
 	"github.com/dnsimple/dnsimple-go/v4/dnsimple"
+	"github.com/dogmatiq/dissolve/dnssd"
 	dnsimpleadvertiser "github.com/dogmatiq/dissolve/dnssd/advertiser/dnsimple"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/dogmatiq/proclaim/provider/dnsimpleprovider/internal/dnsimplex"
 )
 
 type advertiser struct {
@@ -13,3 +19,105 @@ type advertiser struct {
 func (a *advertiser) ID() map[string]any {
 	return marshalAdvertiserID(a.Zone)
 }
+
+// Advertise creates and/or updates DNS records to advertise the given service
+// instance.
+//
+// It returns true if any changes to DNS records were made, or false if the
+// service was already advertised as-is.
+func (a *advertiser) Advertise(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+	options ...dnssd.AdvertiseOption,
+) (bool, error) {
+	changed, err := a.Advertiser.Advertise(ctx, inst)
+	if err != nil {
+		return false, err
+	}
+
+	cs := &changeSet{}
+
+	if err := a.syncSubTypePTRs(
+		ctx,
+		inst,
+		provider.ServiceSubTypeRecords(inst, options...),
+		cs,
+	); err != nil {
+		return false, err
+	}
+
+	// Only manage the target host's address records if the instance has been
+	// advertised with explicit IP addresses. Otherwise, the target host is
+	// managed independently of Proclaim.
+	if addresses := provider.AddressRecords(inst, options...); len(addresses) != 0 {
+		if err := a.syncAddresses(
+			ctx,
+			inst.TargetHost,
+			inst.TTL,
+			addresses,
+			cs,
+		); err != nil {
+			return false, err
+		}
+	}
+
+	ok, err := a.apply(ctx, cs)
+	return changed || ok, err
+}
+
+// Unadvertise removes and/or updates DNS records to stop advertising the given
+// service instance.
+//
+// It returns true if any changes to DNS records were made, or false if the
+// service was not advertised.
+func (a *advertiser) Unadvertise(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+) (bool, error) {
+	cs := &changeSet{}
+
+	if err := a.syncSubTypePTRs(ctx, inst, nil, cs); err != nil {
+		return false, err
+	}
+
+	ok, err := a.apply(ctx, cs)
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := a.Advertiser.Unadvertise(ctx, inst)
+	return changed || ok, err
+}
+
+// apply makes the changes in cs to the advertiser's zone. It returns false if
+// there are no changes to make.
+func (a *advertiser) apply(
+	ctx context.Context,
+	cs *changeSet,
+) (bool, error) {
+	if cs.IsEmpty() {
+		return false, nil
+	}
+
+	accountID := strconv.FormatInt(a.Zone.AccountID, 10)
+
+	for _, rec := range cs.deletes {
+		if _, err := a.Client.Zones.DeleteRecord(ctx, accountID, a.Zone.Name, rec.ID); err != nil {
+			return false, dnsimplex.Errorf("unable to delete %s record: %w", rec.Type, err)
+		}
+	}
+
+	for _, up := range cs.updates {
+		if _, err := a.Client.Zones.UpdateRecord(ctx, accountID, a.Zone.Name, up.Before.ID, up.After); err != nil {
+			return false, dnsimplex.Errorf("unable to update %s record: %w", up.Before.Type, err)
+		}
+	}
+
+	for _, attr := range cs.creates {
+		if _, err := a.Client.Zones.CreateRecord(ctx, accountID, a.Zone.Name, attr); err != nil {
+			return false, dnsimplex.Errorf("unable to create %s record: %w", attr.Type, err)
+		}
+	}
+
+	return true, nil
+}
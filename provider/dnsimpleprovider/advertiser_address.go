@@ -0,0 +1,127 @@
+package dnsimpleprovider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnsimple/dnsimple-go/v4/dnsimple"
+	"github.com/dogmatiq/proclaim/provider/dnsimpleprovider/internal/dnsimplex"
+	"github.com/miekg/dns"
+)
+
+// UnadvertiseAddresses removes the A and AAAA records of the given host.
+func (a *advertiser) UnadvertiseAddresses(
+	ctx context.Context,
+	host string,
+) (bool, error) {
+	cs := &changeSet{}
+
+	if err := a.syncAddresses(ctx, host, 0, nil, cs); err != nil {
+		return false, err
+	}
+
+	return a.apply(ctx, cs)
+}
+
+// syncAddresses adds changes to cs that make the A and AAAA records of the
+// given host contain exactly the addresses in desired.
+func (a *advertiser) syncAddresses(
+	ctx context.Context,
+	host string,
+	ttl time.Duration,
+	desired []dns.RR,
+	cs *changeSet,
+) error {
+	name, err := a.relativeHostName(host)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range []string{"A", "AAAA"} {
+		current := map[string]dnsimple.ZoneRecord{}
+
+		if err := dnsimplex.Each(
+			ctx,
+			func(opts dnsimple.ListOptions) (*dnsimple.Pagination, []dnsimple.ZoneRecord, error) {
+				res, err := a.Client.Zones.ListRecords(
+					ctx,
+					strconv.FormatInt(a.Zone.AccountID, 10),
+					a.Zone.Name,
+					&dnsimple.ZoneRecordListOptions{
+						ListOptions: opts,
+						Name:        dnsimple.String(name),
+						Type:        dnsimple.String(t),
+					},
+				)
+				if err != nil {
+					return nil, nil, dnsimplex.Errorf("unable to list %s records: %w", t, err)
+				}
+
+				return res.Pagination, res.Data, nil
+			},
+			func(rec dnsimple.ZoneRecord) (bool, error) {
+				current[rec.Content] = rec
+				return true, nil
+			},
+		); err != nil {
+			return err
+		}
+
+		for _, rr := range desired {
+			if dns.TypeToString[rr.Header().Rrtype] != t {
+				continue
+			}
+
+			var content string
+			switch rr := rr.(type) {
+			case *dns.A:
+				content = rr.A.String()
+			case *dns.AAAA:
+				content = rr.AAAA.String()
+			}
+
+			attr := dnsimple.ZoneRecordAttributes{
+				ZoneID:  a.Zone.Name,
+				Type:    t,
+				Name:    dnsimple.String(name),
+				Content: content,
+				TTL:     int(ttl.Seconds()),
+			}
+
+			if rec, ok := current[content]; ok {
+				delete(current, content)
+				cs.Update(rec, attr)
+			} else {
+				cs.Create(attr)
+			}
+		}
+
+		for _, rec := range current {
+			cs.Delete(rec)
+		}
+	}
+
+	return nil
+}
+
+// relativeHostName returns the name of the given host relative to the
+// advertiser's zone.
+//
+// The zone apex is represented by an empty string.
+func (a *advertiser) relativeHostName(host string) (string, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	zone := strings.ToLower(a.Zone.Name)
+
+	if host == zone {
+		return "", nil
+	}
+
+	if name, ok := strings.CutSuffix(host, "."+zone); ok {
+		return name, nil
+	}
+
+	return "", fmt.Errorf("target host %q is not within the %q zone", host, a.Zone.Name)
+}
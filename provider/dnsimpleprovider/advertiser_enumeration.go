@@ -0,0 +1,172 @@
+package dnsimpleprovider
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnsimple/dnsimple-go/v4/dnsimple"
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/dogmatiq/proclaim/provider/dnsimpleprovider/internal/dnsimplex"
+)
+
+// AdvertiseServiceType creates and/or updates DNS records to advertise the
+// given service type within the service type enumeration domain of the given
+// domain.
+func (a *advertiser) AdvertiseServiceType(
+	ctx context.Context,
+	serviceType, domain string,
+	ttl time.Duration,
+) (bool, error) {
+	cs := &changeSet{}
+
+	if err := a.syncPTR(
+		ctx,
+		dnssd.AbsoluteTypeEnumerationDomain(domain),
+		dnssd.AbsoluteInstanceEnumerationDomain(serviceType, domain),
+		ttl,
+		cs,
+	); err != nil {
+		return false, err
+	}
+
+	return a.apply(ctx, cs)
+}
+
+// UnadvertiseServiceType removes and/or updates DNS records to stop
+// advertising the given service type within the service type enumeration
+// domain of the given domain.
+func (a *advertiser) UnadvertiseServiceType(
+	ctx context.Context,
+	serviceType, domain string,
+) (bool, error) {
+	cs := &changeSet{}
+
+	if err := a.deletePTR(
+		ctx,
+		dnssd.AbsoluteTypeEnumerationDomain(domain),
+		dnssd.AbsoluteInstanceEnumerationDomain(serviceType, domain),
+		cs,
+	); err != nil {
+		return false, err
+	}
+
+	return a.apply(ctx, cs)
+}
+
+// AdvertiseBrowsingDomain creates and/or updates DNS records to advertise
+// browsingDomain as a browsing domain of the given domain.
+func (a *advertiser) AdvertiseBrowsingDomain(
+	ctx context.Context,
+	browsingDomain, domain string,
+	ttl time.Duration,
+) (bool, error) {
+	cs := &changeSet{}
+
+	for _, name := range provider.BrowsingDomainEnumerationDomains(domain) {
+		if err := a.syncPTR(ctx, name, browsingDomain, ttl, cs); err != nil {
+			return false, err
+		}
+	}
+
+	return a.apply(ctx, cs)
+}
+
+// UnadvertiseBrowsingDomain removes and/or updates DNS records to stop
+// advertising browsingDomain as a browsing domain of the given domain.
+func (a *advertiser) UnadvertiseBrowsingDomain(
+	ctx context.Context,
+	browsingDomain, domain string,
+) (bool, error) {
+	cs := &changeSet{}
+
+	for _, name := range provider.BrowsingDomainEnumerationDomains(domain) {
+		if err := a.deletePTR(ctx, name, browsingDomain, cs); err != nil {
+			return false, err
+		}
+	}
+
+	return a.apply(ctx, cs)
+}
+
+// findPTR returns the PTR record with the given absolute name that refers to
+// target.
+func (a *advertiser) findPTR(
+	ctx context.Context,
+	name, target string,
+) (dnsimple.ZoneRecord, bool, error) {
+	content := strings.TrimRight(target, ".")
+
+	return dnsimplex.Find(
+		ctx,
+		func(opts dnsimple.ListOptions) (*dnsimple.Pagination, []dnsimple.ZoneRecord, error) {
+			res, err := a.Client.Zones.ListRecords(
+				ctx,
+				strconv.FormatInt(a.Zone.AccountID, 10),
+				a.Zone.Name,
+				&dnsimple.ZoneRecordListOptions{
+					ListOptions: opts,
+					Name:        dnsimple.String(a.relativeName(name)),
+					Type:        dnsimple.String("PTR"),
+				},
+			)
+			if err != nil {
+				return nil, nil, dnsimplex.Errorf("unable to list PTR records: %w", err)
+			}
+
+			return res.Pagination, res.Data, nil
+		},
+		func(rec dnsimple.ZoneRecord) (dnsimple.ZoneRecord, bool, error) {
+			return rec, rec.Content == content, nil
+		},
+	)
+}
+
+// syncPTR adds changes to cs that create and/or update a PTR record with the
+// given absolute name that refers to target.
+func (a *advertiser) syncPTR(
+	ctx context.Context,
+	name, target string,
+	ttl time.Duration,
+	cs *changeSet,
+) error {
+	current, ok, err := a.findPTR(ctx, name, target)
+	if err != nil {
+		return err
+	}
+
+	desired := dnsimple.ZoneRecordAttributes{
+		ZoneID:  a.Zone.Name,
+		Type:    "PTR",
+		Name:    dnsimple.String(a.relativeName(name)),
+		Content: strings.TrimRight(target, "."),
+		TTL:     int(ttl.Seconds()),
+	}
+
+	if ok {
+		cs.Update(current, desired)
+	} else {
+		cs.Create(desired)
+	}
+
+	return nil
+}
+
+// deletePTR adds a change to cs that deletes the PTR record with the given
+// absolute name that refers to target, if it exists.
+func (a *advertiser) deletePTR(
+	ctx context.Context,
+	name, target string,
+	cs *changeSet,
+) error {
+	current, ok, err := a.findPTR(ctx, name, target)
+	if !ok || err != nil {
+		return err
+	}
+
+	cs.Delete(current)
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package dnsimpleprovider
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/dnsimple/dnsimple-go/v4/dnsimple"
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/provider/dnsimpleprovider/internal/dnsimplex"
+	"github.com/miekg/dns"
+)
+
+// syncSubTypePTRs adds changes to cs that advertise inst as providing the
+// service sub-types described by the PTR records in desired, and only those
+// sub-types.
+func (a *advertiser) syncSubTypePTRs(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+	desired []*dns.PTR,
+	cs *changeSet,
+) error {
+	current, err := a.findSubTypePTRs(ctx, inst)
+	if err != nil {
+		return err
+	}
+
+	for _, rr := range desired {
+		attr := dnsimple.ZoneRecordAttributes{
+			ZoneID:  a.Zone.Name,
+			Type:    "PTR",
+			Name:    dnsimple.String(a.relativeName(rr.Hdr.Name)),
+			Content: strings.TrimRight(rr.Ptr, "."),
+			TTL:     int(rr.Hdr.Ttl),
+		}
+
+		if rec, ok := current[*attr.Name]; ok {
+			delete(current, *attr.Name)
+			cs.Update(rec, attr)
+		} else {
+			cs.Create(attr)
+		}
+	}
+
+	for _, rec := range current {
+		cs.Delete(rec)
+	}
+
+	return nil
+}
+
+// findSubTypePTRs returns the sub-type PTR records that refer to inst, keyed
+// by their (zone-relative) name.
+func (a *advertiser) findSubTypePTRs(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+) (map[string]dnsimple.ZoneRecord, error) {
+	suffix := "._sub." + inst.ServiceType
+	content := strings.TrimRight(inst.Absolute(), ".")
+	records := map[string]dnsimple.ZoneRecord{}
+
+	return records, dnsimplex.Each(
+		ctx,
+		func(opts dnsimple.ListOptions) (*dnsimple.Pagination, []dnsimple.ZoneRecord, error) {
+			res, err := a.Client.Zones.ListRecords(
+				ctx,
+				strconv.FormatInt(a.Zone.AccountID, 10),
+				a.Zone.Name,
+				&dnsimple.ZoneRecordListOptions{
+					ListOptions: opts,
+					NameLike:    dnsimple.String(suffix),
+					Type:        dnsimple.String("PTR"),
+				},
+			)
+			if err != nil {
+				return nil, nil, dnsimplex.Errorf("unable to list PTR records: %w", err)
+			}
+
+			return res.Pagination, res.Data, nil
+		},
+		func(rec dnsimple.ZoneRecord) (bool, error) {
+			if strings.HasSuffix(rec.Name, suffix) && rec.Content == content {
+				records[rec.Name] = rec
+			}
+			return true, nil
+		},
+	)
+}
+
+// relativeName returns the given absolute DNS name relative to the
+// advertiser's zone.
+func (a *advertiser) relativeName(name string) string {
+	return strings.TrimSuffix(name, "."+a.Zone.Name+".")
+}
@@ -0,0 +1,22 @@
+package dnsimpleprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// HealthCheck returns an error if the provider's API token is invalid or the
+// DNSimple API can not be reached.
+func (p *Provider) HealthCheck(ctx context.Context) error {
+	res, err := p.Client.Identity.Whoami(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to identify API token: %w", err)
+	}
+
+	if res.Data == nil || (res.Data.Account == nil && res.Data.User == nil) {
+		return errors.New("API token is not associated with an account or user")
+	}
+
+	return nil
+}
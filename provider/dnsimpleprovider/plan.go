@@ -0,0 +1,183 @@
+package dnsimpleprovider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/dnsimple/dnsimple-go/v4/dnsimple"
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/dogmatiq/proclaim/provider/dnsimpleprovider/internal/dnsimplex"
+	"github.com/miekg/dns"
+)
+
+// Plan returns the changes to DNS records that Advertise would make in order
+// to advertise the given service instance, without making them.
+func (a *advertiser) Plan(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+	options ...dnssd.AdvertiseOption,
+) ([]provider.Change, error) {
+	// Address records are only managed if the instance is advertised with
+	// explicit IP addresses, as per Advertise().
+	addresses := len(provider.AddressRecords(inst, options...)) != 0
+
+	current, err := a.instanceRecords(ctx, inst, addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.PlanChanges(
+		current,
+		dnssd.NewRecords(inst, options...),
+	), nil
+}
+
+// PlanUnadvertise returns the changes to DNS records that Unadvertise would
+// make in order to unadvertise the given service instance, without making
+// them.
+func (a *advertiser) PlanUnadvertise(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+) ([]provider.Change, error) {
+	current, err := a.instanceRecords(ctx, inst, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.PlanChanges(current, nil), nil
+}
+
+// instanceRecords returns the records that currently advertise the given
+// service instance within the advertiser's zone.
+//
+// The address records of the instance's target host are only included if
+// addresses is true.
+func (a *advertiser) instanceRecords(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+	addresses bool,
+) ([]dns.RR, error) {
+	var current []dnsimple.ZoneRecord
+
+	ptr, ok, err := a.findPTR(
+		ctx,
+		dnssd.AbsoluteInstanceEnumerationDomain(inst.ServiceType, inst.Domain),
+		inst.Absolute(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		current = append(current, ptr)
+	}
+
+	subTypes, err := a.findSubTypePTRs(ctx, inst)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range subTypes {
+		current = append(current, rec)
+	}
+
+	name := dnssd.EscapeInstance(inst.Name) + "." + inst.ServiceType
+
+	for _, t := range []string{"SRV", "TXT"} {
+		records, err := a.findRecords(ctx, name, t)
+		if err != nil {
+			return nil, err
+		}
+		current = append(current, records...)
+	}
+
+	if addresses {
+		host, err := a.relativeHostName(inst.TargetHost)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range []string{"A", "AAAA"} {
+			records, err := a.findRecords(ctx, host, t)
+			if err != nil {
+				return nil, err
+			}
+			current = append(current, records...)
+		}
+	}
+
+	var records []dns.RR
+
+	for _, rec := range current {
+		rr, err := a.parseRecord(rec)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rr)
+	}
+
+	return records, nil
+}
+
+// findRecords returns the records with the given (zone-relative) name and
+// type.
+func (a *advertiser) findRecords(
+	ctx context.Context,
+	name, recordType string,
+) ([]dnsimple.ZoneRecord, error) {
+	var records []dnsimple.ZoneRecord
+
+	return records, dnsimplex.Each(
+		ctx,
+		func(opts dnsimple.ListOptions) (*dnsimple.Pagination, []dnsimple.ZoneRecord, error) {
+			res, err := a.Client.Zones.ListRecords(
+				ctx,
+				strconv.FormatInt(a.Zone.AccountID, 10),
+				a.Zone.Name,
+				&dnsimple.ZoneRecordListOptions{
+					ListOptions: opts,
+					Name:        dnsimple.String(name),
+					Type:        dnsimple.String(recordType),
+				},
+			)
+			if err != nil {
+				return nil, nil, dnsimplex.Errorf("unable to list %s records: %w", recordType, err)
+			}
+
+			return res.Pagination, res.Data, nil
+		},
+		func(rec dnsimple.ZoneRecord) (bool, error) {
+			records = append(records, rec)
+			return true, nil
+		},
+	)
+}
+
+// parseRecord returns the DNS record described by a DNSimple zone record.
+func (a *advertiser) parseRecord(rec dnsimple.ZoneRecord) (dns.RR, error) {
+	name := a.Zone.Name + "."
+	if rec.Name != "" {
+		name = rec.Name + "." + name
+	}
+
+	content := rec.Content
+
+	switch rec.Type {
+	case "SRV":
+		// DNSimple stores the priority of SRV records separately.
+		content = fmt.Sprintf("%d %s", rec.Priority, content)
+	case "TXT":
+		// Empty TXT records are advertised as "=", as per the dissolve
+		// advertiser.
+		if content == `"="` {
+			content = `""`
+		}
+	}
+
+	rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, rec.TTL, rec.Type, content))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s record: %w", rec.Type, err)
+	}
+
+	return rr, nil
+}
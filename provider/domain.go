@@ -0,0 +1,241 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+)
+
+// DomainPolicy restricts the domains on which a provider may advertise.
+//
+// Each entry in Allowed and Denied matches the domain itself and all of its
+// subdomains.
+type DomainPolicy struct {
+	// Allowed is the list of domains on which the provider may advertise. If
+	// it is empty, all domains are allowed unless they are denied.
+	Allowed []string
+
+	// Denied is the list of domains on which the provider must not advertise,
+	// even if they are within one of the allowed domains.
+	Denied []string
+}
+
+// Check returns an error describing why the policy does not permit
+// advertising on the given domain, or nil if it is permitted.
+func (p DomainPolicy) Check(domain string) error {
+	for _, d := range p.Denied {
+		if IsWithinDomain(domain, d) {
+			return fmt.Errorf("%q is within the denied domain %q", domain, d)
+		}
+	}
+
+	if len(p.Allowed) == 0 {
+		return nil
+	}
+
+	for _, d := range p.Allowed {
+		if IsWithinDomain(domain, d) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%q is not within any of the allowed domains", domain)
+}
+
+// IsWithinDomain returns true if domain is equal to parent, or is one of its
+// subdomains. The comparison is case-insensitive.
+func IsWithinDomain(domain, parent string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	parent = strings.ToLower(strings.TrimSuffix(parent, "."))
+
+	return domain == parent || strings.HasSuffix(domain, "."+parent)
+}
+
+// Restricted is an optional interface that may be implemented by a Provider
+// whose domains are restricted by a DomainPolicy.
+type Restricted interface {
+	// DomainPolicy returns the policy that restricts the provider's domains.
+	DomainPolicy() DomainPolicy
+}
+
+// Restrict returns a provider that only advertises on the domains permitted by
+// the given policy.
+func Restrict(p Provider, policy DomainPolicy) Provider {
+	return &restricted{p, policy}
+}
+
+// restricted is a Provider that only advertises on the domains permitted by a
+// DomainPolicy.
+type restricted struct {
+	Provider
+
+	policy DomainPolicy
+}
+
+func (p *restricted) DomainPolicy() DomainPolicy {
+	return p.policy
+}
+
+func (p *restricted) AdvertiserByID(
+	ctx context.Context,
+	id map[string]any,
+) (Advertiser, error) {
+	a, err := p.Provider.AdvertiserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return RestrictAdvertiser(a, p.policy), nil
+}
+
+func (p *restricted) AdvertiserByDomain(
+	ctx context.Context,
+	domain string,
+) (Advertiser, bool, error) {
+	if p.policy.Check(domain) != nil {
+		return nil, false, nil
+	}
+	return p.Provider.AdvertiserByDomain(ctx, domain)
+}
+
+func (p *restricted) HealthCheck(ctx context.Context) error {
+	if c, ok := p.Provider.(HealthChecker); ok {
+		return c.HealthCheck(ctx)
+	}
+	return nil
+}
+
+// RestrictAdvertiser returns an advertiser that only advertises on the domains
+// permitted by the given policy.
+//
+// It is used to restrict advertisers that are obtained by their ID, which
+// unlike a domain does not indicate whether the policy permits the
+// advertiser's use. Records may be removed from any domain, so that instances
+// advertised before the policy changed can still be unadvertised.
+//
+// The returned advertiser only implements Advertiser. The optional interfaces
+// that are implemented by a are obtained using AsPlanner(),
+// AsAddressAdvertiser(), AsEnumerationAdvertiser(), AsRoutingPolicyAdvertiser()
+// and AsPropagationChecker(), which apply the same policy.
+func RestrictAdvertiser(a Advertiser, policy DomainPolicy) Advertiser {
+	if len(policy.Allowed) == 0 && len(policy.Denied) == 0 {
+		return a
+	}
+	return &restrictedAdvertiser{a, policy}
+}
+
+// restrictedAdvertiser is an Advertiser that only advertises on the domains
+// permitted by a DomainPolicy.
+type restrictedAdvertiser struct {
+	Advertiser
+
+	policy DomainPolicy
+}
+
+func (a *restrictedAdvertiser) Advertise(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+	options ...dnssd.AdvertiseOption,
+) (bool, error) {
+	if err := a.policy.Check(inst.Domain); err != nil {
+		return false, err
+	}
+	return a.Advertiser.Advertise(ctx, inst, options...)
+}
+
+// planner returns the wrapped advertiser as a Planner that only plans changes
+// on the permitted domains.
+func (a *restrictedAdvertiser) planner() (Planner, bool) {
+	p, ok := a.Advertiser.(Planner)
+	if !ok {
+		return nil, false
+	}
+	return &restrictedPlanner{p, a.policy}, true
+}
+
+// enumeration returns the wrapped advertiser as an EnumerationAdvertiser that
+// only advertises enumeration records on the permitted domains.
+func (a *restrictedAdvertiser) enumeration() (EnumerationAdvertiser, bool) {
+	ea, ok := a.Advertiser.(EnumerationAdvertiser)
+	if !ok {
+		return nil, false
+	}
+	return &restrictedEnumerationAdvertiser{ea, a.policy}, true
+}
+
+// routing returns the wrapped advertiser as a RoutingPolicyAdvertiser whose
+// advertisers only advertise on the permitted domains.
+func (a *restrictedAdvertiser) routing() (RoutingPolicyAdvertiser, bool) {
+	ra, ok := a.Advertiser.(RoutingPolicyAdvertiser)
+	if !ok {
+		return nil, false
+	}
+	return &restrictedRoutingPolicyAdvertiser{ra, a.policy}, true
+}
+
+// restrictedPlanner is a Planner that only plans changes on the domains
+// permitted by a DomainPolicy.
+type restrictedPlanner struct {
+	Planner
+
+	policy DomainPolicy
+}
+
+func (p *restrictedPlanner) Plan(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+	options ...dnssd.AdvertiseOption,
+) ([]Change, error) {
+	if err := p.policy.Check(inst.Domain); err != nil {
+		return nil, err
+	}
+	return p.Planner.Plan(ctx, inst, options...)
+}
+
+// restrictedEnumerationAdvertiser is an EnumerationAdvertiser that only
+// advertises on the domains permitted by a DomainPolicy.
+type restrictedEnumerationAdvertiser struct {
+	EnumerationAdvertiser
+
+	policy DomainPolicy
+}
+
+func (a *restrictedEnumerationAdvertiser) AdvertiseServiceType(
+	ctx context.Context,
+	serviceType, domain string,
+	ttl time.Duration,
+) (bool, error) {
+	if err := a.policy.Check(domain); err != nil {
+		return false, err
+	}
+	return a.EnumerationAdvertiser.AdvertiseServiceType(ctx, serviceType, domain, ttl)
+}
+
+func (a *restrictedEnumerationAdvertiser) AdvertiseBrowsingDomain(
+	ctx context.Context,
+	browsingDomain, domain string,
+	ttl time.Duration,
+) (bool, error) {
+	if err := a.policy.Check(domain); err != nil {
+		return false, err
+	}
+	return a.EnumerationAdvertiser.AdvertiseBrowsingDomain(ctx, browsingDomain, domain, ttl)
+}
+
+// restrictedRoutingPolicyAdvertiser is a RoutingPolicyAdvertiser whose
+// advertisers only advertise on the domains permitted by a DomainPolicy.
+type restrictedRoutingPolicyAdvertiser struct {
+	RoutingPolicyAdvertiser
+
+	policy DomainPolicy
+}
+
+func (a *restrictedRoutingPolicyAdvertiser) WithRoutingPolicy(p RoutingPolicy) (Advertiser, error) {
+	x, err := a.RoutingPolicyAdvertiser.WithRoutingPolicy(p)
+	if err != nil {
+		return nil, err
+	}
+	return RestrictAdvertiser(x, a.policy), nil
+}
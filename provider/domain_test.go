@@ -0,0 +1,257 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+)
+
+func TestDomainPolicy_Check(t *testing.T) {
+	policy := DomainPolicy{
+		Allowed: []string{"example.org", "example.com."},
+		Denied:  []string{"internal.example.org"},
+	}
+
+	cases := []struct {
+		Domain  string
+		Allowed bool
+	}{
+		{"example.org", true},
+		{"EXAMPLE.ORG.", true},
+		{"sub.example.org", true},
+		{"example.com", true},
+		{"internal.example.org", false},
+		{"sub.internal.example.org", false},
+		{"notexample.org", false},
+		{"example.net", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Domain, func(t *testing.T) {
+			err := policy.Check(c.Domain)
+			if c.Allowed && err != nil {
+				t.Fatalf("expected domain to be allowed, got %q", err)
+			} else if !c.Allowed && err == nil {
+				t.Fatal("expected domain to be denied")
+			}
+		})
+	}
+
+	t.Run("it allows all domains that are not denied if there are no allowed domains", func(t *testing.T) {
+		p := DomainPolicy{Denied: []string{"example.org"}}
+
+		if err := p.Check("example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Check("example.org"); err == nil {
+			t.Fatal("expected domain to be denied")
+		}
+	})
+}
+
+func TestRestrict(t *testing.T) {
+	policy := DomainPolicy{
+		Allowed: []string{"example.org"},
+		Denied:  []string{"internal.example.org"},
+	}
+
+	p := Restrict(&testProvider{}, policy)
+
+	t.Run("it exposes the policy", func(t *testing.T) {
+		rp, ok := p.(Restricted)
+		if !ok {
+			t.Fatal("expected provider to implement Restricted")
+		}
+
+		if got := rp.DomainPolicy(); got.Check("internal.example.org") == nil {
+			t.Fatal("expected the policy to deny the domain")
+		}
+	})
+
+	t.Run("AdvertiserByDomain", func(t *testing.T) {
+		cases := []struct {
+			Domain string
+			Want   bool
+		}{
+			{"example.org", true},
+			{"sub.example.org", true},
+			{"internal.example.org", false},
+			{"example.com", false},
+		}
+
+		for _, c := range cases {
+			t.Run(c.Domain, func(t *testing.T) {
+				_, ok, err := p.AdvertiserByDomain(context.Background(), c.Domain)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok != c.Want {
+					t.Fatalf("unexpected result: got %t, want %t", ok, c.Want)
+				}
+			})
+		}
+	})
+
+	t.Run("AdvertiserByID", func(t *testing.T) {
+		a, err := p.AdvertiserByID(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cases := []struct {
+			Domain string
+			Want   bool
+		}{
+			{"example.org", true},
+			{"internal.example.org", false},
+			{"example.com", false},
+		}
+
+		for _, c := range cases {
+			t.Run(c.Domain, func(t *testing.T) {
+				inst := dnssd.ServiceInstance{
+					ServiceInstanceName: dnssd.ServiceInstanceName{
+						Name:        "Instance",
+						ServiceType: "_test._tcp",
+						Domain:      c.Domain,
+					},
+				}
+
+				_, err := a.Advertise(context.Background(), inst)
+				if c.Want && err != nil {
+					t.Fatalf("expected advertise to succeed, got %q", err)
+				} else if !c.Want && err == nil {
+					t.Fatal("expected advertise to fail")
+				}
+
+				if _, err := a.Unadvertise(context.Background(), inst); err != nil {
+					t.Fatalf("expected unadvertise to succeed, got %q", err)
+				}
+			})
+		}
+
+		t.Run("it returns the advertiser as-is if the policy is empty", func(t *testing.T) {
+			a, err := Restrict(&testProvider{}, DomainPolicy{}).AdvertiserByID(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := a.(*testAdvertiser); !ok {
+				t.Fatalf("unexpected advertiser type: %T", a)
+			}
+		})
+	})
+}
+
+func TestRestrictAdvertiser(t *testing.T) {
+	policy := DomainPolicy{
+		Allowed: []string{"example.org"},
+	}
+
+	t.Run("it only exposes the optional interfaces of the wrapped advertiser", func(t *testing.T) {
+		a := RestrictAdvertiser(&testAdvertiser{}, policy)
+
+		if _, ok := AsPlanner(a); ok {
+			t.Fatal("did not expect a planner")
+		}
+		if _, ok := AsAddressAdvertiser(a); ok {
+			t.Fatal("did not expect an address advertiser")
+		}
+		if _, ok := AsEnumerationAdvertiser(a); ok {
+			t.Fatal("did not expect an enumeration advertiser")
+		}
+		if _, ok := AsRoutingPolicyAdvertiser(a); ok {
+			t.Fatal("did not expect a routing policy advertiser")
+		}
+		if _, ok := AsPropagationChecker(a); ok {
+			t.Fatal("did not expect a propagation checker")
+		}
+	})
+
+	t.Run("it exposes the propagation checker of the wrapped advertiser", func(t *testing.T) {
+		a := RestrictAdvertiser(&checkingAdvertiser{}, policy)
+
+		c, ok := AsPropagationChecker(a)
+		if !ok {
+			t.Fatal("expected a propagation checker")
+		}
+
+		done, err := c.IsPropagated(context.Background(), "<id>")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !done {
+			t.Fatal("expected the change to be propagated")
+		}
+	})
+
+	t.Run("it restricts the planner of the wrapped advertiser", func(t *testing.T) {
+		a := RestrictAdvertiser(&checkingAdvertiser{}, policy)
+
+		p, ok := AsPlanner(a)
+		if !ok {
+			t.Fatal("expected a planner")
+		}
+
+		inst := dnssd.ServiceInstance{
+			ServiceInstanceName: dnssd.ServiceInstanceName{
+				Name:        "Instance",
+				ServiceType: "_test._tcp",
+				Domain:      "example.com",
+			},
+		}
+
+		if _, err := p.Plan(context.Background(), inst); err == nil {
+			t.Fatal("expected planning on a denied domain to fail")
+		}
+
+		if _, err := p.PlanUnadvertise(context.Background(), inst); err != nil {
+			t.Fatalf("expected planning the removal of records to succeed, got %q", err)
+		}
+	})
+}
+
+// testProvider is a Provider that manages every domain.
+type testProvider struct{}
+
+func (*testProvider) ID() string       { return "test" }
+func (*testProvider) Describe() string { return "test provider" }
+
+func (*testProvider) AdvertiserByID(context.Context, map[string]any) (Advertiser, error) {
+	return &testAdvertiser{}, nil
+}
+
+func (*testProvider) AdvertiserByDomain(context.Context, string) (Advertiser, bool, error) {
+	return &testAdvertiser{}, true, nil
+}
+
+// testAdvertiser is an Advertiser that does nothing.
+type testAdvertiser struct{}
+
+func (*testAdvertiser) ID() map[string]any { return nil }
+
+func (*testAdvertiser) Advertise(context.Context, dnssd.ServiceInstance, ...dnssd.AdvertiseOption) (bool, error) {
+	return true, nil
+}
+
+func (*testAdvertiser) Unadvertise(context.Context, dnssd.ServiceInstance) (bool, error) {
+	return true, nil
+}
+
+// checkingAdvertiser is an Advertiser that implements Planner and
+// PropagationChecker.
+type checkingAdvertiser struct {
+	testAdvertiser
+}
+
+func (*checkingAdvertiser) Plan(context.Context, dnssd.ServiceInstance, ...dnssd.AdvertiseOption) ([]Change, error) {
+	return nil, nil
+}
+
+func (*checkingAdvertiser) PlanUnadvertise(context.Context, dnssd.ServiceInstance) ([]Change, error) {
+	return nil, nil
+}
+
+func (*checkingAdvertiser) IsPropagated(context.Context, string) (bool, error) {
+	return true, nil
+}
@@ -2,9 +2,11 @@ package providertest
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/dogmatiq/dissolve/dnssd"
 	"github.com/dogmatiq/proclaim/provider"
 )
 
@@ -25,6 +27,19 @@ func Run(
 	t.Cleanup(cancel)
 
 	t.Run("provider", func(t *testing.T) {
+		t.Run("HealthCheck()", func(t *testing.T) {
+			t.Run("it returns nil when the provider is healthy", func(t *testing.T) {
+				c, ok := tctx.Provider.(provider.HealthChecker)
+				if !ok {
+					t.Skip("provider does not implement provider.HealthChecker")
+				}
+
+				if err := c.HealthCheck(ctx); err != nil {
+					t.Fatal(err)
+				}
+			})
+		})
+
 		t.Run("AdvertiserByDomain()", func(t *testing.T) {
 			t.Run("when the provider can advertise on the domain", func(t *testing.T) {
 				t.Run("it returns an advertiser", func(t *testing.T) {
@@ -84,4 +99,157 @@ func Run(
 			})
 		})
 	})
+
+	t.Run("advertiser", func(t *testing.T) {
+		t.Run("Plan()", func(t *testing.T) {
+			t.Run("it plans the creation of every record of an instance that is not advertised", func(t *testing.T) {
+				advertiser, ok, err := tctx.Provider.AdvertiserByDomain(ctx, tctx.Domain)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !ok {
+					t.Fatal("could not find advertiser by domain")
+				}
+
+				inst := dnssd.ServiceInstance{
+					ServiceInstanceName: dnssd.ServiceInstanceName{
+						Name:        fmt.Sprintf("Proclaim Plan Test %d", time.Now().UnixNano()),
+						ServiceType: "_proclaim-test._tcp",
+						Domain:      tctx.Domain,
+					},
+					TargetHost: "host." + tctx.Domain,
+					TargetPort: 12345,
+					TTL:        60 * time.Second,
+				}
+
+				planner, ok := provider.AsPlanner(advertiser)
+				if !ok {
+					t.Skip("advertiser does not support planning")
+				}
+
+				changes, err := planner.Plan(ctx, inst)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				records := dnssd.NewRecords(inst)
+
+				if len(changes) != len(records) {
+					t.Fatalf("expected %d changes, got %d", len(records), len(changes))
+				}
+
+				for _, c := range changes {
+					if c.Action != provider.Create {
+						t.Fatalf("expected only %s actions, got %s %s", provider.Create, c.Action, c.Record)
+					}
+				}
+			})
+		})
+
+		t.Run("PlanUnadvertise()", func(t *testing.T) {
+			t.Run("it plans no changes for an instance that is not advertised", func(t *testing.T) {
+				advertiser, ok, err := tctx.Provider.AdvertiserByDomain(ctx, tctx.Domain)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !ok {
+					t.Fatal("could not find advertiser by domain")
+				}
+
+				inst := dnssd.ServiceInstance{
+					ServiceInstanceName: dnssd.ServiceInstanceName{
+						Name:        fmt.Sprintf("Proclaim Plan Test %d", time.Now().UnixNano()),
+						ServiceType: "_proclaim-test._tcp",
+						Domain:      tctx.Domain,
+					},
+					TargetHost: "host." + tctx.Domain,
+					TargetPort: 12345,
+					TTL:        60 * time.Second,
+				}
+
+				planner, ok := provider.AsPlanner(advertiser)
+				if !ok {
+					t.Skip("advertiser does not support planning")
+				}
+
+				changes, err := planner.PlanUnadvertise(ctx, inst)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if len(changes) != 0 {
+					t.Fatalf("expected no changes, got %d", len(changes))
+				}
+			})
+		})
+
+		t.Run("WithRoutingPolicy()", func(t *testing.T) {
+			t.Run("it can add and remove the routing policy of an advertised instance", func(t *testing.T) {
+				simple, ok, err := tctx.Provider.AdvertiserByDomain(ctx, tctx.Domain)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !ok {
+					t.Fatal("could not find advertiser by domain")
+				}
+
+				ra, ok := provider.AsRoutingPolicyAdvertiser(simple)
+				if !ok {
+					t.Skip("advertiser does not support routing policies")
+				}
+
+				weight := int64(10)
+				routed, err := ra.WithRoutingPolicy(
+					provider.RoutingPolicy{
+						SetIdentifier: "proclaim-test",
+						Weight:        &weight,
+					},
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				inst := dnssd.ServiceInstance{
+					ServiceInstanceName: dnssd.ServiceInstanceName{
+						Name:        fmt.Sprintf("Proclaim Routing Test %d", time.Now().UnixNano()),
+						ServiceType: "_proclaim-test._tcp",
+						Domain:      tctx.Domain,
+					},
+					TargetHost: "host." + tctx.Domain,
+					TargetPort: 12345,
+					TTL:        60 * time.Second,
+				}
+
+				t.Cleanup(func() {
+					ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+					defer cancel()
+
+					if _, err := simple.Unadvertise(ctx, inst); err != nil {
+						t.Error(err)
+					}
+					if _, err := routed.Unadvertise(ctx, inst); err != nil {
+						t.Error(err)
+					}
+				})
+
+				for i, a := range []provider.Advertiser{simple, routed, simple} {
+					changed, err := a.Advertise(ctx, inst)
+					if err != nil {
+						t.Fatalf("unable to advertise instance (step %d): %s", i+1, err)
+					}
+					if !changed {
+						t.Fatalf("expected advertising the instance to change its records (step %d)", i+1)
+					}
+
+					changed, err = a.Advertise(ctx, inst)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if changed {
+						t.Fatalf("did not expect advertising the instance again to change its records (step %d)", i+1)
+					}
+				}
+			})
+		})
+	})
 }
@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// Create is a ChangeAction that creates a DNS record.
+	Create ChangeAction = "Create"
+
+	// Delete is a ChangeAction that deletes a DNS record.
+	Delete ChangeAction = "Delete"
+)
+
+// ChangeAction is an enumeration of the actions that can be performed on a DNS
+// record.
+type ChangeAction string
+
+// Change describes a change to a single DNS record.
+//
+// A record that is modified is represented as the deletion of the current
+// record and the creation of the desired record.
+type Change struct {
+	Action ChangeAction
+	Record dns.RR
+}
+
+// PlanChanges returns the changes required to replace the current records with
+// the desired records.
+//
+// Records are compared without regard to the case or escaping of any DNS
+// names they contain.
+func PlanChanges(current, desired []dns.RR) []Change {
+	var changes []Change
+
+	for _, rr := range desired {
+		if !containsRecord(current, rr) {
+			changes = append(changes, Change{Create, rr})
+		}
+	}
+
+	for _, rr := range current {
+		if !containsRecord(desired, rr) {
+			changes = append(changes, Change{Delete, rr})
+		}
+	}
+
+	return changes
+}
+
+// containsRecord returns true if records contains a record that is equivalent
+// to rr.
+func containsRecord(records []dns.RR, rr dns.RR) bool {
+	rr = canonicalRecord(rr)
+
+	for _, x := range records {
+		x = canonicalRecord(x)
+
+		if x.Header().Ttl == rr.Header().Ttl && dns.IsDuplicate(x, rr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canonicalRecord returns a copy of rr with the DNS names it contains in
+// canonical form.
+func canonicalRecord(rr dns.RR) dns.RR {
+	rr = dns.Copy(rr)
+	rr.Header().Name = canonicalName(rr.Header().Name)
+
+	switch rr := rr.(type) {
+	case *dns.PTR:
+		rr.Ptr = canonicalName(rr.Ptr)
+	case *dns.SRV:
+		rr.Target = canonicalName(rr.Target)
+	}
+
+	return rr
+}
+
+// canonicalName returns the given DNS name in lowercase, with any special
+// characters escaped in the same way regardless of how they are escaped in
+// name.
+func canonicalName(name string) string {
+	var buf [256]byte
+
+	n, err := dns.PackDomainName(dns.Fqdn(name), buf[:], 0, nil, false)
+	if err != nil {
+		return strings.ToLower(name)
+	}
+
+	canonical, _, err := dns.UnpackDomainName(buf[:n], 0)
+	if err != nil {
+		return strings.ToLower(name)
+	}
+
+	return strings.ToLower(canonical)
+}
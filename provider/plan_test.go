@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestPlanChanges(t *testing.T) {
+	rr := func(s string) dns.RR {
+		t.Helper()
+
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rr
+	}
+
+	cases := []struct {
+		Name             string
+		Current, Desired []dns.RR
+		Want             []Change
+	}{
+		{
+			Name: "no records",
+		},
+		{
+			Name: "records that do not exist are created",
+			Desired: []dns.RR{
+				rr(`instance._http._tcp.example.org. 60 IN SRV 10 20 80 host.example.org.`),
+			},
+			Want: []Change{
+				{Create, rr(`instance._http._tcp.example.org. 60 IN SRV 10 20 80 host.example.org.`)},
+			},
+		},
+		{
+			Name: "records that are not desired are deleted",
+			Current: []dns.RR{
+				rr(`instance._http._tcp.example.org. 60 IN TXT "a=1"`),
+			},
+			Want: []Change{
+				{Delete, rr(`instance._http._tcp.example.org. 60 IN TXT "a=1"`)},
+			},
+		},
+		{
+			Name: "identical records are unchanged",
+			Current: []dns.RR{
+				rr(`_http._tcp.example.org. 60 IN PTR instance._http._tcp.example.org.`),
+			},
+			Desired: []dns.RR{
+				rr(`_http._tcp.example.org. 60 IN PTR instance._http._tcp.example.org.`),
+			},
+		},
+		{
+			Name: "names are compared without regard to case",
+			Current: []dns.RR{
+				rr(`_HTTP._tcp.Example.org. 60 IN PTR Instance._http._tcp.example.ORG.`),
+				rr(`instance._http._tcp.example.org. 60 IN SRV 10 20 80 HOST.example.org.`),
+			},
+			Desired: []dns.RR{
+				rr(`_http._tcp.example.org. 60 IN PTR instance._http._tcp.example.org.`),
+				rr(`instance._http._tcp.example.org. 60 IN SRV 10 20 80 host.example.org.`),
+			},
+		},
+		{
+			Name: "names are compared without regard to escaping",
+			Current: []dns.RR{
+				rr(`_http._tcp.example.org. 60 IN PTR My\032Instance._http._tcp.example.org.`),
+			},
+			Desired: []dns.RR{
+				rr(`_http._tcp.example.org. 60 IN PTR My\ Instance._http._tcp.example.org.`),
+			},
+		},
+		{
+			Name: "records with a different TTL are replaced",
+			Current: []dns.RR{
+				rr(`instance._http._tcp.example.org. 60 IN TXT "a=1"`),
+			},
+			Desired: []dns.RR{
+				rr(`instance._http._tcp.example.org. 120 IN TXT "a=1"`),
+			},
+			Want: []Change{
+				{Create, rr(`instance._http._tcp.example.org. 120 IN TXT "a=1"`)},
+				{Delete, rr(`instance._http._tcp.example.org. 60 IN TXT "a=1"`)},
+			},
+		},
+		{
+			Name: "records with different data are replaced",
+			Current: []dns.RR{
+				rr(`instance._http._tcp.example.org. 60 IN SRV 10 20 80 host.example.org.`),
+			},
+			Desired: []dns.RR{
+				rr(`instance._http._tcp.example.org. 60 IN SRV 10 20 8080 host.example.org.`),
+			},
+			Want: []Change{
+				{Create, rr(`instance._http._tcp.example.org. 60 IN SRV 10 20 8080 host.example.org.`)},
+				{Delete, rr(`instance._http._tcp.example.org. 60 IN SRV 10 20 80 host.example.org.`)},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			got := PlanChanges(c.Current, c.Desired)
+
+			if len(got) != len(c.Want) {
+				t.Fatalf("unexpected number of changes: got %d, want %d: %v", len(got), len(c.Want), got)
+			}
+
+			for i, want := range c.Want {
+				if got[i].Action != want.Action || got[i].Record.String() != want.Record.String() {
+					t.Fatalf("unexpected change at index %d: got %s %s, want %s %s", i, got[i].Action, got[i].Record, want.Action, want.Record)
+				}
+			}
+		})
+	}
+}
+
+func TestCanonicalRecord(t *testing.T) {
+	cases := []struct {
+		Record, Want string
+	}{
+		{
+			`Instance._HTTP._tcp.Example.org. 60 IN TXT "Value"`,
+			`instance._http._tcp.example.org.	60	IN	TXT	"Value"`,
+		},
+		{
+			`_http._tcp.example.org. 60 IN PTR My\032Instance._HTTP._tcp.example.org.`,
+			`_http._tcp.example.org.	60	IN	PTR	my\ instance._http._tcp.example.org.`,
+		},
+		{
+			`instance._http._tcp.example.org. 60 IN SRV 10 20 80 HOST.Example.org.`,
+			`instance._http._tcp.example.org.	60	IN	SRV	10 20 80 host.example.org.`,
+		},
+		{
+			`host.example.org. 60 IN A 192.0.2.1`,
+			`host.example.org.	60	IN	A	192.0.2.1`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Record, func(t *testing.T) {
+			rr, err := dns.NewRR(c.Record)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			before := rr.String()
+			got := canonicalRecord(rr)
+
+			if got.String() != c.Want {
+				t.Fatalf("unexpected record: got %q, want %q", got, c.Want)
+			}
+
+			if rr.String() != before {
+				t.Fatal("expected the original record to be unchanged")
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"sync"
+)
+
+// PropagationChecker is an optional interface for advertisers that make
+// changes to DNS records that are not immediately visible on all of the
+// provider's name servers.
+type PropagationChecker interface {
+	// IsPropagated returns true if the change with the given ID has propagated
+	// to all of the provider's name servers.
+	//
+	// Change IDs are recorded by the advertiser using RecordChangeID().
+	IsPropagated(ctx context.Context, id string) (bool, error)
+}
+
+// AsPropagationChecker returns a as a PropagationChecker, if the changes it
+// makes are not immediately visible on all of the provider's name servers.
+func AsPropagationChecker(a Advertiser) (PropagationChecker, bool) {
+	if r, ok := a.(*restrictedAdvertiser); ok {
+		a = r.Advertiser
+	}
+	c, ok := a.(PropagationChecker)
+	return c, ok
+}
+
+// ChangeIDs is a set of provider-specific IDs of changes to DNS records.
+type ChangeIDs struct {
+	m   sync.Mutex
+	ids []string
+}
+
+// IDs returns the IDs of the changes, in the order they were recorded.
+func (c *ChangeIDs) IDs() []string {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return append([]string(nil), c.ids...)
+}
+
+type changeIDsKey struct{}
+
+// TrackChanges returns a context that records the IDs of the changes made by
+// any advertiser that implements PropagationChecker when it is called with
+// that context.
+func TrackChanges(ctx context.Context) (context.Context, *ChangeIDs) {
+	c := &ChangeIDs{}
+	return context.WithValue(ctx, changeIDsKey{}, c), c
+}
+
+// RecordChangeID records the ID of a change made by an advertiser within a
+// context returned by TrackChanges().
+//
+// It does nothing if ctx does not track changes, or if the ID has already been
+// recorded.
+func RecordChangeID(ctx context.Context, id string) {
+	c, ok := ctx.Value(changeIDsKey{}).(*ChangeIDs)
+	if !ok {
+		return
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	for _, x := range c.ids {
+		if x == id {
+			return
+		}
+	}
+
+	c.ids = append(c.ids, id)
+}
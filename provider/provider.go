@@ -26,3 +26,11 @@ type Provider interface {
 	// ok is false if this provider does not manage the given domain.
 	AdvertiserByDomain(ctx context.Context, domain string) (_ Advertiser, ok bool, _ error)
 }
+
+// HealthChecker is an optional interface that may be implemented by a Provider
+// to verify that it is able to communicate with the hosting provider.
+type HealthChecker interface {
+	// HealthCheck returns an error if the provider's credentials are invalid
+	// or the hosting provider's API can not be reached.
+	HealthCheck(ctx context.Context) error
+}
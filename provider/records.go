@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"strings"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/miekg/dns"
+)
+
+// ServiceSubTypeRecords returns the PTR records that advertise inst as
+// providing each of the service sub-types specified by the given options.
+//
+// See https://www.rfc-editor.org/rfc/rfc6763#section-7.1.
+func ServiceSubTypeRecords(
+	inst dnssd.ServiceInstance,
+	options ...dnssd.AdvertiseOption,
+) []*dns.PTR {
+	name := dnssd.AbsoluteInstanceEnumerationDomain(inst.ServiceType, inst.Domain)
+
+	var records []*dns.PTR
+	for _, rr := range dnssd.NewRecords(inst, options...) {
+		if ptr, ok := rr.(*dns.PTR); ok && !strings.EqualFold(ptr.Hdr.Name, name) {
+			records = append(records, ptr)
+		}
+	}
+
+	return records
+}
+
+// AddressRecords returns the A and AAAA records that advertise the IP
+// addresses of inst's target host, as specified by the given options.
+func AddressRecords(
+	inst dnssd.ServiceInstance,
+	options ...dnssd.AdvertiseOption,
+) []dns.RR {
+	var records []dns.RR
+	for _, rr := range dnssd.NewRecords(inst, options...) {
+		switch rr.(type) {
+		case *dns.A, *dns.AAAA:
+			records = append(records, rr)
+		}
+	}
+
+	return records
+}
+
+// ServiceSubTypeDomain returns the absolute DNS name under which the PTR
+// records for all sub-types of inst's service type are advertised.
+//
+// For example, "_sub._http._tcp.example.org.".
+func ServiceSubTypeDomain(inst dnssd.ServiceInstance) string {
+	return "_sub." + dnssd.AbsoluteInstanceEnumerationDomain(inst.ServiceType, inst.Domain)
+}
+
+// BrowsingDomainEnumerationDomains returns the absolute DNS names of the PTR
+// records that advertise browsing domains within the given domain.
+//
+// See https://www.rfc-editor.org/rfc/rfc6763#section-11.
+func BrowsingDomainEnumerationDomains(domain string) []string {
+	return []string{
+		"b._dns-sd._udp." + domain + ".",
+		"lb._dns-sd._udp." + domain + ".",
+	}
+}
@@ -1,12 +1,282 @@
 package route53provider
 
-import route53advertiser "github.com/dogmatiq/dissolve/dnssd/advertiser/route53"
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/miekg/dns"
+	"golang.org/x/exp/slices"
+)
 
 type advertiser struct {
-	*route53advertiser.Advertiser
+	Client advertiserAPI
 	ZoneID string
+
+	// Policy is the routing policy used to advertise instances. If it is nil,
+	// instances are advertised using simple record sets.
+	Policy *provider.RoutingPolicy
+}
+
+// advertiserAPI is the subset of the Route 53 client used by advertisers.
+type advertiserAPI interface {
+	recordSetChanger
+
+	ListResourceRecordSets(
+		ctx context.Context,
+		in *route53.ListResourceRecordSetsInput,
+		options ...func(*route53.Options),
+	) (*route53.ListResourceRecordSetsOutput, error)
+
+	GetChange(
+		ctx context.Context,
+		in *route53.GetChangeInput,
+		options ...func(*route53.Options),
+	) (*route53.GetChangeOutput, error)
+
+	GetHealthCheck(
+		ctx context.Context,
+		in *route53.GetHealthCheckInput,
+		options ...func(*route53.Options),
+	) (*route53.GetHealthCheckOutput, error)
+
+	CreateHealthCheck(
+		ctx context.Context,
+		in *route53.CreateHealthCheckInput,
+		options ...func(*route53.Options),
+	) (*route53.CreateHealthCheckOutput, error)
+
+	DeleteHealthCheck(
+		ctx context.Context,
+		in *route53.DeleteHealthCheckInput,
+		options ...func(*route53.Options),
+	) (*route53.DeleteHealthCheckOutput, error)
 }
 
 func (a *advertiser) ID() map[string]any {
 	return marshalAdvertiserID(a.ZoneID)
 }
+
+// Advertise creates and/or updates DNS records to advertise the given service
+// instance.
+//
+// All of the instance's records are changed using a single request, so the
+// instance is never partially advertised. If the advertiser has a routing
+// policy with a health check, it is associated with the SRV and TXT records.
+// Any record sets that must be replaced in order to add, remove or change the
+// type of the routing policy are replaced within the same request, and the
+// health checks that they used are deleted once they are no longer used.
+// The PTR records are shared by every instance of the service type, so they
+// can not be associated with any one instance's health check.
+//
+// It returns true if any changes to DNS records were made, or false if the
+// service was already advertised as-is.
+func (a *advertiser) Advertise(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+	options ...dnssd.AdvertiseOption,
+) (bool, error) {
+	cs := &types.ChangeBatch{
+		Comment: aws.String(fmt.Sprintf(
+			"advertising DNS-SD %s instance: %s",
+			inst.ServiceType,
+			inst.Name,
+		)),
+	}
+
+	if err := a.syncPTRValue(
+		ctx,
+		dnssd.AbsoluteInstanceEnumerationDomain(inst.ServiceType, inst.Domain),
+		inst.Absolute(),
+		true,
+		cs,
+	); err != nil {
+		return false, err
+	}
+
+	current, _, err := a.findOwnRecordSet(ctx, inst.Absolute(), types.RRTypeSrv)
+	if err != nil {
+		return false, err
+	}
+
+	prevHealthCheckID := aws.ToString(current.HealthCheckId)
+	healthCheckID, created, err := a.syncHealthCheck(ctx, inst, prevHealthCheckID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := a.syncRecordSet(
+		ctx,
+		inst.Absolute(),
+		types.RRTypeSrv,
+		inst.TTL,
+		healthCheckID,
+		[]dns.RR{dnssd.NewSRVRecord(inst)},
+		cs,
+	); err != nil {
+		return false, err
+	}
+
+	var txt []dns.RR
+	for _, rr := range dnssd.NewTXTRecords(inst) {
+		txt = append(txt, rr)
+	}
+
+	if err := a.syncRecordSet(
+		ctx,
+		inst.Absolute(),
+		types.RRTypeTxt,
+		inst.TTL,
+		healthCheckID,
+		txt,
+		cs,
+	); err != nil {
+		return false, err
+	}
+
+	if err := a.syncSubTypePTRs(
+		ctx,
+		inst,
+		provider.ServiceSubTypeRecords(inst, options...),
+		cs,
+	); err != nil {
+		return false, err
+	}
+
+	// Only manage the target host's address records if the instance has been
+	// advertised with explicit IP addresses. Otherwise, the target host is
+	// managed independently of Proclaim.
+	if addresses := provider.AddressRecords(inst, options...); len(addresses) != 0 {
+		if err := a.syncAddresses(
+			ctx,
+			inst.TargetHost,
+			inst.TTL,
+			addresses,
+			cs,
+		); err != nil {
+			return false, err
+		}
+	}
+
+	changed, err := a.apply(ctx, cs)
+	if err != nil {
+		if created {
+			// The health check is not associated with any records, so it
+			// would otherwise never be deleted. A new one is created when the
+			// instance is advertised again.
+			_ = a.deleteHealthCheck(ctx, healthCheckID)
+		}
+		return false, err
+	}
+
+	orphaned := orphanedHealthChecks(cs)
+	if healthCheckID != prevHealthCheckID && !slices.Contains(orphaned, prevHealthCheckID) {
+		orphaned = append(orphaned, prevHealthCheckID)
+	}
+
+	for _, id := range orphaned {
+		if err := a.deleteHealthCheck(ctx, id); err != nil {
+			return false, err
+		}
+	}
+
+	return changed, nil
+}
+
+// Unadvertise removes and/or updates DNS records to stop advertising the given
+// service instance.
+//
+// The instance's PTR records are only removed if no other SRV record sets with
+// the same name remain, as they may be shared with the records that other
+// clusters advertise using a routing policy. The health check associated with
+// the instance's records is deleted if it was created by Proclaim.
+//
+// It returns true if any changes to DNS records were made, or false if the
+// service was not advertised.
+func (a *advertiser) Unadvertise(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+) (bool, error) {
+	cs := &types.ChangeBatch{
+		Comment: aws.String(fmt.Sprintf(
+			"unadvertising DNS-SD %s instance: %s",
+			inst.ServiceType,
+			inst.Name,
+		)),
+	}
+
+	for _, t := range []types.RRType{types.RRTypeSrv, types.RRTypeTxt} {
+		if err := a.syncRecordSet(ctx, inst.Absolute(), t, 0, "", nil, cs); err != nil {
+			return false, err
+		}
+	}
+
+	sets, err := a.findRecordSets(ctx, inst.Absolute(), types.RRTypeSrv)
+	if err != nil {
+		return false, err
+	}
+
+	shared := false
+	for _, set := range sets {
+		if aws.ToString(set.SetIdentifier) != a.setIdentifier() {
+			shared = true
+			break
+		}
+	}
+
+	if !shared {
+		if err := a.syncPTRValue(
+			ctx,
+			dnssd.AbsoluteInstanceEnumerationDomain(inst.ServiceType, inst.Domain),
+			inst.Absolute(),
+			false,
+			cs,
+		); err != nil {
+			return false, err
+		}
+
+		if err := a.syncSubTypePTRs(ctx, inst, nil, cs); err != nil {
+			return false, err
+		}
+	}
+
+	changed, err := a.apply(ctx, cs)
+	if err != nil {
+		return false, err
+	}
+
+	for _, id := range orphanedHealthChecks(cs) {
+		if err := a.deleteHealthCheck(ctx, id); err != nil {
+			return false, err
+		}
+	}
+
+	return changed, nil
+}
+
+// apply submits the changes in cs to Route 53. It returns false if there are
+// no changes to make.
+func (a *advertiser) apply(
+	ctx context.Context,
+	cs *types.ChangeBatch,
+) (bool, error) {
+	if len(cs.Changes) == 0 {
+		return false, nil
+	}
+
+	if _, err := a.Client.ChangeResourceRecordSets(
+		ctx,
+		&route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: aws.String(a.ZoneID),
+			ChangeBatch:  cs,
+		},
+	); err != nil {
+		return false, fmt.Errorf("unable to change resource record sets: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,75 @@
+package route53provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/miekg/dns"
+	"golang.org/x/exp/slices"
+)
+
+// UnadvertiseAddresses removes the A and AAAA records of the given host.
+func (a *advertiser) UnadvertiseAddresses(
+	ctx context.Context,
+	host string,
+) (bool, error) {
+	cs := &types.ChangeBatch{
+		Comment: aws.String(fmt.Sprintf(
+			"unadvertising addresses of DNS-SD target host: %s",
+			host,
+		)),
+	}
+
+	if err := a.syncAddresses(ctx, host, 0, nil, cs); err != nil {
+		return false, err
+	}
+
+	return a.apply(ctx, cs)
+}
+
+// syncAddresses adds changes to cs that make the A and AAAA record sets of the
+// given host contain exactly the addresses in desired.
+func (a *advertiser) syncAddresses(
+	ctx context.Context,
+	host string,
+	ttl time.Duration,
+	desired []dns.RR,
+	cs *types.ChangeBatch,
+) error {
+	var v4, v6 []dns.RR
+
+	for _, rr := range desired {
+		switch rr.(type) {
+		case *dns.A:
+			v4 = append(v4, rr)
+		case *dns.AAAA:
+			v6 = append(v6, rr)
+		}
+	}
+
+	name := dns.Fqdn(host)
+
+	if err := a.syncRecordSet(ctx, name, types.RRTypeA, ttl, "", v4, cs); err != nil {
+		return err
+	}
+
+	return a.syncRecordSet(ctx, name, types.RRTypeAaaa, ttl, "", v6, cs)
+}
+
+// sameValues returns true if a and b contain the same record values,
+// regardless of order.
+func sameValues(a, b []types.ResourceRecord) bool {
+	values := func(records []types.ResourceRecord) []string {
+		var v []string
+		for _, rec := range records {
+			v = append(v, *rec.Value)
+		}
+		slices.Sort(v)
+		return v
+	}
+
+	return slices.Equal(values(a), values(b))
+}
@@ -0,0 +1,119 @@
+package route53provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/provider"
+)
+
+// AdvertiseServiceType creates and/or updates DNS records to advertise the
+// given service type within the service type enumeration domain of the given
+// domain.
+//
+// The TTL is ignored, all PTR records managed by this provider use ptrTTL.
+func (a *advertiser) AdvertiseServiceType(
+	ctx context.Context,
+	serviceType, domain string,
+	_ time.Duration,
+) (bool, error) {
+	return a.syncServiceType(ctx, serviceType, domain, true)
+}
+
+// UnadvertiseServiceType removes and/or updates DNS records to stop
+// advertising the given service type within the service type enumeration
+// domain of the given domain.
+func (a *advertiser) UnadvertiseServiceType(
+	ctx context.Context,
+	serviceType, domain string,
+) (bool, error) {
+	return a.syncServiceType(ctx, serviceType, domain, false)
+}
+
+// AdvertiseBrowsingDomain creates and/or updates DNS records to advertise
+// browsingDomain as a browsing domain of the given domain.
+//
+// The TTL is ignored, all PTR records managed by this provider use ptrTTL.
+func (a *advertiser) AdvertiseBrowsingDomain(
+	ctx context.Context,
+	browsingDomain, domain string,
+	_ time.Duration,
+) (bool, error) {
+	return a.syncBrowsingDomain(ctx, browsingDomain, domain, true)
+}
+
+// UnadvertiseBrowsingDomain removes and/or updates DNS records to stop
+// advertising browsingDomain as a browsing domain of the given domain.
+func (a *advertiser) UnadvertiseBrowsingDomain(
+	ctx context.Context,
+	browsingDomain, domain string,
+) (bool, error) {
+	return a.syncBrowsingDomain(ctx, browsingDomain, domain, false)
+}
+
+func (a *advertiser) syncServiceType(
+	ctx context.Context,
+	serviceType, domain string,
+	present bool,
+) (bool, error) {
+	cs := &types.ChangeBatch{
+		Comment: aws.String(fmt.Sprintf(
+			"%s DNS-SD service type: %s",
+			verb(present),
+			serviceType,
+		)),
+	}
+
+	if err := a.syncPTRValue(
+		ctx,
+		dnssd.AbsoluteTypeEnumerationDomain(domain),
+		dnssd.AbsoluteInstanceEnumerationDomain(serviceType, domain),
+		present,
+		cs,
+	); err != nil {
+		return false, err
+	}
+
+	return a.apply(ctx, cs)
+}
+
+func (a *advertiser) syncBrowsingDomain(
+	ctx context.Context,
+	browsingDomain, domain string,
+	present bool,
+) (bool, error) {
+	cs := &types.ChangeBatch{
+		Comment: aws.String(fmt.Sprintf(
+			"%s DNS-SD browsing domain: %s",
+			verb(present),
+			browsingDomain,
+		)),
+	}
+
+	for _, name := range provider.BrowsingDomainEnumerationDomains(domain) {
+		if err := a.syncPTRValue(
+			ctx,
+			name,
+			browsingDomain+".",
+			present,
+			cs,
+		); err != nil {
+			return false, err
+		}
+	}
+
+	return a.apply(ctx, cs)
+}
+
+// verb returns the verb used to describe a change batch that makes a record
+// present or absent.
+func verb(present bool) string {
+	if present {
+		return "advertising"
+	}
+	return "unadvertising"
+}
@@ -0,0 +1,118 @@
+package route53provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/miekg/dns"
+	"golang.org/x/exp/slices"
+)
+
+// syncSubTypePTRs adds changes to cs that make inst a member of the sub-type
+// PTR record sets in desired, and only those record sets.
+func (a *advertiser) syncSubTypePTRs(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+	desired []*dns.PTR,
+	cs *types.ChangeBatch,
+) error {
+	current, err := a.findSubTypePTRs(ctx, inst)
+	if err != nil {
+		return err
+	}
+
+	pending := slices.Clone(desired)
+
+	for _, set := range current {
+		index := indexOf(set, inst.Absolute())
+		wanted := slices.IndexFunc(
+			pending,
+			func(rr *dns.PTR) bool {
+				return strings.EqualFold(rr.Hdr.Name, *set.Name)
+			},
+		)
+
+		if wanted != -1 {
+			pending = slices.Delete(pending, wanted, wanted+1)
+
+			if index == -1 {
+				records := append(
+					slices.Clone(set.ResourceRecords),
+					types.ResourceRecord{
+						Value: aws.String(inst.Absolute()),
+					},
+				)
+
+				if err := replacePTRSet(set, records, cs); err != nil {
+					return err
+				}
+			}
+		} else if index != -1 {
+			records := slices.Delete(
+				slices.Clone(set.ResourceRecords),
+				index,
+				index+1,
+			)
+
+			if err := replacePTRSet(set, records, cs); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, rr := range pending {
+		createPTRSet(rr.Hdr.Name, rr.Ptr, cs)
+	}
+
+	return nil
+}
+
+// findSubTypePTRs returns the PTR record sets for every sub-type of inst's
+// service type, regardless of whether inst is a member of those sets.
+func (a *advertiser) findSubTypePTRs(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+) ([]types.ResourceRecordSet, error) {
+	domain := provider.ServiceSubTypeDomain(inst)
+	suffix := strings.ToLower("." + domain)
+
+	in := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(a.ZoneID),
+		StartRecordName: aws.String(domain),
+	}
+
+	var sets []types.ResourceRecordSet
+
+	for {
+		out, err := a.Client.ListResourceRecordSets(ctx, in)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list resource record sets: %w", err)
+		}
+
+		// Route 53 sorts record sets by name with the labels reversed, so all
+		// of the sub-types of a service type are listed contiguously.
+		for _, set := range out.ResourceRecordSets {
+			if !strings.HasSuffix(strings.ToLower(*set.Name), suffix) {
+				return sets, nil
+			}
+
+			if set.Type == types.RRTypePtr {
+				sets = append(sets, set)
+			}
+		}
+
+		if !out.IsTruncated {
+			return sets, nil
+		}
+
+		in.StartRecordName = out.NextRecordName
+		in.StartRecordType = out.NextRecordType
+		in.StartRecordIdentifier = out.NextRecordIdentifier
+	}
+}
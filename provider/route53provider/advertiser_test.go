@@ -0,0 +1,282 @@
+package route53provider
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/dogmatiq/dissolve/dnssd"
+	"golang.org/x/exp/slices"
+)
+
+func TestAdvertiser(t *testing.T) {
+	const enumDomain = "_http._tcp.example.org."
+
+	instance := func(name string) dnssd.ServiceInstance {
+		return dnssd.ServiceInstance{
+			ServiceInstanceName: dnssd.ServiceInstanceName{
+				Name:        name,
+				ServiceType: "_http._tcp",
+				Domain:      "example.org",
+			},
+			TargetHost: "host.example.org",
+			TargetPort: 8080,
+			TTL:        60 * time.Second,
+		}
+	}
+
+	setup := func() (*advertiser, *fakeRoute53) {
+		client := &fakeRoute53{}
+		return &advertiser{Client: client, ZoneID: "Z123"}, client
+	}
+
+	advertise := func(
+		t *testing.T,
+		a *advertiser,
+		inst dnssd.ServiceInstance,
+		options ...dnssd.AdvertiseOption,
+	) bool {
+		t.Helper()
+
+		changed, err := a.Advertise(context.Background(), inst, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return changed
+	}
+
+	unadvertise := func(t *testing.T, a *advertiser, inst dnssd.ServiceInstance) bool {
+		t.Helper()
+
+		changed, err := a.Unadvertise(context.Background(), inst)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return changed
+	}
+
+	// expectPTR verifies that the PTR record set with the given name is of
+	// the given generation, and contains exactly the given values.
+	expectPTR := func(
+		t *testing.T,
+		client *fakeRoute53,
+		name string,
+		gen uint64,
+		values ...string,
+	) {
+		t.Helper()
+
+		set, ok := client.recordSet(name, types.RRTypePtr)
+		if !ok {
+			t.Fatalf("expected a PTR record set named %s", name)
+		}
+
+		if got, want := aws.ToString(set.SetIdentifier), aws.ToString(marshalGeneration(gen)); got != want {
+			t.Fatalf("unexpected generation: got %q, want %q", got, want)
+		}
+
+		got := ptrValues(&set)
+		slices.Sort(got)
+
+		if !slices.Equal(got, values) {
+			t.Fatalf("unexpected values: got %v, want %v", got, values)
+		}
+	}
+
+	t.Run("it advertises the instance using a single request", func(t *testing.T) {
+		a, client := setup()
+		inst := instance("Instance A")
+
+		if !advertise(t, a, inst) {
+			t.Fatal("expected a change to be reported")
+		}
+
+		if n := client.requests(); n != 1 {
+			t.Fatalf("unexpected number of requests: got %d, want 1", n)
+		}
+
+		srv, ok := client.recordSet(inst.Absolute(), types.RRTypeSrv)
+		if !ok {
+			t.Fatal("expected an SRV record set")
+		}
+
+		if got, want := ptrValues(&srv), []string{"0 0 8080 host.example.org."}; !slices.Equal(got, want) {
+			t.Fatalf("unexpected SRV values: got %v, want %v", got, want)
+		}
+
+		if got, want := aws.ToInt64(srv.TTL), int64(60); got != want {
+			t.Fatalf("unexpected TTL: got %d, want %d", got, want)
+		}
+
+		if _, ok := client.recordSet(inst.Absolute(), types.RRTypeTxt); !ok {
+			t.Fatal("expected a TXT record set")
+		}
+
+		expectPTR(t, client, enumDomain, 0, inst.Absolute())
+	})
+
+	t.Run("it does not make any changes if the instance is already advertised", func(t *testing.T) {
+		a, client := setup()
+		inst := instance("Instance A")
+
+		advertise(t, a, inst)
+
+		if advertise(t, a, inst) {
+			t.Fatal("did not expect a change to be reported")
+		}
+
+		if n := client.requests(); n != 1 {
+			t.Fatalf("unexpected number of requests: got %d, want 1", n)
+		}
+	})
+
+	t.Run("it updates the records of an instance that has changed", func(t *testing.T) {
+		a, client := setup()
+		inst := instance("Instance A")
+
+		advertise(t, a, inst)
+
+		inst.TargetPort = 8443
+		inst.TTL = 120 * time.Second
+
+		if !advertise(t, a, inst) {
+			t.Fatal("expected a change to be reported")
+		}
+
+		srv, _ := client.recordSet(inst.Absolute(), types.RRTypeSrv)
+
+		if got, want := ptrValues(&srv), []string{"0 0 8443 host.example.org."}; !slices.Equal(got, want) {
+			t.Fatalf("unexpected SRV values: got %v, want %v", got, want)
+		}
+
+		if got, want := aws.ToInt64(srv.TTL), int64(120); got != want {
+			t.Fatalf("unexpected TTL: got %d, want %d", got, want)
+		}
+
+		// The PTR record already refers to the instance, so it is unchanged.
+		expectPTR(t, client, enumDomain, 0, inst.Absolute())
+	})
+
+	t.Run("it replaces the PTR record set with a new generation when an instance is added or removed", func(t *testing.T) {
+		a, client := setup()
+		instA := instance("Instance A")
+		instB := instance("Instance B")
+
+		advertise(t, a, instA)
+		advertise(t, a, instB)
+		expectPTR(t, client, enumDomain, 1, instA.Absolute(), instB.Absolute())
+
+		unadvertise(t, a, instA)
+		expectPTR(t, client, enumDomain, 2, instB.Absolute())
+	})
+
+	t.Run("it deletes the PTR record set when the last instance is unadvertised", func(t *testing.T) {
+		a, client := setup()
+		inst := instance("Instance A")
+
+		advertise(t, a, inst)
+
+		if !unadvertise(t, a, inst) {
+			t.Fatal("expected a change to be reported")
+		}
+
+		for _, rt := range []types.RRType{types.RRTypeSrv, types.RRTypeTxt} {
+			if _, ok := client.recordSet(inst.Absolute(), rt); ok {
+				t.Fatalf("did not expect a %s record set", rt)
+			}
+		}
+
+		if _, ok := client.recordSet(enumDomain, types.RRTypePtr); ok {
+			t.Fatal("did not expect a PTR record set")
+		}
+	})
+
+	t.Run("it does not make any changes when unadvertising an instance that is not advertised", func(t *testing.T) {
+		a, client := setup()
+
+		if unadvertise(t, a, instance("Instance A")) {
+			t.Fatal("did not expect a change to be reported")
+		}
+
+		if n := client.requests(); n != 0 {
+			t.Fatalf("unexpected number of requests: got %d, want 0", n)
+		}
+	})
+
+	t.Run("it manages the instance's membership of sub-type PTR record sets", func(t *testing.T) {
+		a, client := setup()
+		inst := instance("Instance A")
+
+		advertise(
+			t, a, inst,
+			dnssd.WithServiceSubType("_printer"),
+			dnssd.WithServiceSubType("_scanner"),
+		)
+		expectPTR(t, client, "_printer._sub."+enumDomain, 0, inst.Absolute())
+		expectPTR(t, client, "_scanner._sub."+enumDomain, 0, inst.Absolute())
+
+		advertise(
+			t, a, inst,
+			dnssd.WithServiceSubType("_printer"),
+		)
+		expectPTR(t, client, "_printer._sub."+enumDomain, 0, inst.Absolute())
+
+		if _, ok := client.recordSet("_scanner._sub."+enumDomain, types.RRTypePtr); ok {
+			t.Fatal("did not expect the _scanner sub-type PTR record set")
+		}
+
+		unadvertise(t, a, inst)
+
+		if _, ok := client.recordSet("_printer._sub."+enumDomain, types.RRTypePtr); ok {
+			t.Fatal("did not expect the _printer sub-type PTR record set")
+		}
+	})
+
+	t.Run("it manages the target host's address records when addresses are given", func(t *testing.T) {
+		a, client := setup()
+		inst := instance("Instance A")
+
+		advertise(
+			t, a, inst,
+			dnssd.WithIPAddress(net.ParseIP("192.0.2.1")),
+			dnssd.WithIPAddress(net.ParseIP("2001:db8::1")),
+		)
+
+		v4, ok := client.recordSet("host.example.org.", types.RRTypeA)
+		if !ok {
+			t.Fatal("expected an A record set")
+		}
+
+		if got, want := ptrValues(&v4), []string{"192.0.2.1"}; !slices.Equal(got, want) {
+			t.Fatalf("unexpected A values: got %v, want %v", got, want)
+		}
+
+		if _, ok := client.recordSet("host.example.org.", types.RRTypeAaaa); !ok {
+			t.Fatal("expected an AAAA record set")
+		}
+
+		advertise(
+			t, a, inst,
+			dnssd.WithIPAddress(net.ParseIP("192.0.2.1")),
+		)
+
+		if _, ok := client.recordSet("host.example.org.", types.RRTypeAaaa); ok {
+			t.Fatal("did not expect an AAAA record set")
+		}
+
+		if changed, err := a.UnadvertiseAddresses(context.Background(), inst.TargetHost); err != nil {
+			t.Fatal(err)
+		} else if !changed {
+			t.Fatal("expected a change to be reported")
+		}
+
+		if _, ok := client.recordSet("host.example.org.", types.RRTypeA); ok {
+			t.Fatal("did not expect an A record set")
+		}
+	})
+}
@@ -0,0 +1,569 @@
+package route53provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"golang.org/x/exp/slices"
+)
+
+// maxBatchSize is the maximum number of resource record elements in a batch of
+// coalesced changes.
+//
+// Route 53 permits up to 1,000 elements in a single request, and counts the
+// records of an UPSERT change twice.
+const maxBatchSize = 1000
+
+// batchTimeout is the maximum amount of time to wait for Route 53 to accept a
+// batch of coalesced changes.
+const batchTimeout = 30 * time.Second
+
+// batchBackoff is the initial amount of time to wait between each of the
+// individual requests that are applied after a batch of coalesced changes
+// fails. It is doubled after each request, up to maxBatchBackoff.
+//
+// The failure may be caused by Route 53's API rate limits, in which case
+// applying each request immediately would only make matters worse. The delay
+// never extends beyond the deadline of the reconciliation that is waiting for
+// the request, which instead fails so that it is retried when the instance is
+// next reconciled.
+const (
+	batchBackoff    = 500 * time.Millisecond
+	maxBatchBackoff = 5 * time.Second
+)
+
+// recordSetChanger is the subset of the Route 53 client used by the batcher.
+type recordSetChanger interface {
+	ChangeResourceRecordSets(
+		ctx context.Context,
+		in *route53.ChangeResourceRecordSetsInput,
+		options ...func(*route53.Options),
+	) (*route53.ChangeResourceRecordSetsOutput, error)
+}
+
+// batcher coalesces the ChangeResourceRecordSets requests made for the same
+// hosted zone within a short window into a single request.
+type batcher struct {
+	Client recordSetChanger
+	Window time.Duration
+
+	m       sync.Mutex
+	pending map[string]*batch
+}
+
+// batch is a set of requests that are applied to a hosted zone together.
+type batch struct {
+	ZoneID   string
+	Requests []*batchRequest
+
+	changes mergedChanges
+	flush   sync.Once
+}
+
+// batchRequest is a single ChangeResourceRecordSets request within a batch.
+type batchRequest struct {
+	Input *route53.ChangeResourceRecordSetsInput
+
+	ctx    context.Context
+	done   chan struct{}
+	output *route53.ChangeResourceRecordSetsOutput
+	err    error
+}
+
+// Install adds middleware to a Route 53 client's stack that routes each
+// ChangeResourceRecordSets request through the batcher.
+func (b *batcher) Install(s *middleware.Stack) error {
+	return s.Initialize.Add(
+		middleware.InitializeMiddlewareFunc(
+			"proclaim:batch",
+			func(
+				ctx context.Context,
+				in middleware.InitializeInput,
+				next middleware.InitializeHandler,
+			) (middleware.InitializeOutput, middleware.Metadata, error) {
+				input, ok := in.Parameters.(*route53.ChangeResourceRecordSetsInput)
+				if !ok {
+					return next.HandleInitialize(ctx, in)
+				}
+
+				out, err := b.Apply(ctx, input)
+				return middleware.InitializeOutput{Result: out}, middleware.Metadata{}, err
+			},
+		),
+		middleware.Before,
+	)
+}
+
+// Apply adds the given request to the pending batch for its hosted zone and
+// waits for the batch to be applied.
+//
+// If ctx is canceled while waiting, the changes may still be applied.
+func (b *batcher) Apply(
+	ctx context.Context,
+	input *route53.ChangeResourceRecordSetsInput,
+) (*route53.ChangeResourceRecordSetsOutput, error) {
+	req := &batchRequest{
+		Input: input,
+		ctx:   ctx,
+		done:  make(chan struct{}),
+	}
+
+	b.enqueue(req)
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-req.done:
+		return req.output, req.err
+	}
+}
+
+// enqueue adds req to the pending batch for its hosted zone, starting a new
+// batch if necessary.
+func (b *batcher) enqueue(req *batchRequest) {
+	zoneID := normalizeZoneID(aws.ToString(req.Input.HostedZoneId))
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.pending == nil {
+		b.pending = map[string]*batch{}
+	}
+
+	if bt, ok := b.pending[zoneID]; ok {
+		if bt.add(req) {
+			return
+		}
+
+		// The request can not be merged with the pending batch, or the batch
+		// is full. Apply the pending batch immediately so that the request
+		// can start a new one.
+		delete(b.pending, zoneID)
+		go b.flush(bt)
+	}
+
+	bt := &batch{ZoneID: zoneID}
+	bt.add(req)
+	b.pending[zoneID] = bt
+
+	time.AfterFunc(
+		b.Window,
+		func() {
+			b.m.Lock()
+			if b.pending[zoneID] == bt {
+				delete(b.pending, zoneID)
+			}
+			b.m.Unlock()
+
+			b.flush(bt)
+		},
+	)
+}
+
+// flush applies the changes in bt, unless it has already been applied.
+func (b *batcher) flush(bt *batch) {
+	bt.flush.Do(func() {
+		backoff := time.Duration(0)
+
+		if len(bt.Requests) > 1 {
+			out, err := b.change(context.Background(), bt.merge())
+			if err == nil {
+				for _, req := range bt.Requests {
+					req.resolve(out, nil)
+				}
+				return
+			}
+
+			// Route 53 applies each request atomically, so a single invalid
+			// change causes the entire batch to fail. Fall back to applying
+			// each of the original requests individually, so that the
+			// failure is only reported to the request that caused it.
+			backoff = batchBackoff
+		}
+
+		for i, req := range bt.Requests {
+			if i > 0 && backoff > 0 {
+				if err := sleep(req.ctx, backoff); err != nil {
+					req.resolve(nil, err)
+					continue
+				}
+				backoff = min(backoff*2, maxBatchBackoff)
+			}
+
+			// There is no point applying a request that nobody is waiting
+			// for. It is made again when the instance is next reconciled.
+			if err := req.ctx.Err(); err != nil {
+				req.resolve(nil, err)
+				continue
+			}
+
+			req.resolve(b.change(req.ctx, req.Input))
+		}
+	})
+}
+
+// sleep waits for d to elapse. It returns an error without waiting if ctx's
+// deadline would be reached first, or if ctx is canceled while waiting.
+func sleep(ctx context.Context, d time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return context.DeadlineExceeded
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// change makes a single ChangeResourceRecordSets request.
+func (b *batcher) change(
+	ctx context.Context,
+	in *route53.ChangeResourceRecordSetsInput,
+) (*route53.ChangeResourceRecordSetsOutput, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), batchTimeout)
+	defer cancel()
+
+	return b.Client.ChangeResourceRecordSets(ctx, in)
+}
+
+// add adds req to the batch. It returns false if req can not be merged with
+// the changes already in the batch, or if the batch would exceed the maximum
+// size.
+func (bt *batch) add(req *batchRequest) bool {
+	changes := bt.changes.clone()
+	if !changes.merge(req.Input.ChangeBatch.Changes) {
+		return false
+	}
+
+	if len(bt.Requests) != 0 && batchSize(changes.build()) > maxBatchSize {
+		return false
+	}
+
+	bt.changes = changes
+	bt.Requests = append(bt.Requests, req)
+
+	return true
+}
+
+// merge returns a single request that contains the merged changes of every
+// request in the batch.
+func (bt *batch) merge() *route53.ChangeResourceRecordSetsInput {
+	return &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(bt.ZoneID),
+		ChangeBatch: &types.ChangeBatch{
+			Comment: aws.String(fmt.Sprintf(
+				"applying %d coalesced DNS-SD change batches",
+				len(bt.Requests),
+			)),
+			Changes: bt.changes.build(),
+		},
+	}
+}
+
+// mergedChanges is the result of merging the changes of several requests that
+// were made against the same state of a hosted zone.
+//
+// Because none of the requests observe each other's changes, a later change to
+// a record set supersedes any earlier change to the same record set. The
+// exception is the PTR record sets that are shared by many instances, which
+// are replaced with a new generation by each change. The values added and
+// removed by each request are combined into a single new generation.
+type mergedChanges struct {
+	keys    []string
+	changes map[string]types.Change
+	ptrs    map[string]*mergedPTRSet
+}
+
+// mergedPTRSet is the result of merging the changes made to a PTR record set
+// by several requests.
+type mergedPTRSet struct {
+	// Current is the record set that is replaced, or nil if the record set
+	// does not exist.
+	Current *types.ResourceRecordSet
+
+	// Next is the record set that replaces Current, or nil if none of the
+	// requests create a new generation.
+	Next *types.ResourceRecordSet
+
+	// Values is the values of the new generation.
+	Values []string
+}
+
+// clone returns a copy of c that can be modified independently.
+func (c mergedChanges) clone() mergedChanges {
+	x := mergedChanges{
+		keys:    slices.Clone(c.keys),
+		changes: make(map[string]types.Change, len(c.changes)),
+		ptrs:    make(map[string]*mergedPTRSet, len(c.ptrs)),
+	}
+
+	for k, v := range c.changes {
+		x.changes[k] = v
+	}
+
+	for k, v := range c.ptrs {
+		p := *v
+		p.Values = slices.Clone(v.Values)
+		x.ptrs[k] = &p
+	}
+
+	return x
+}
+
+// merge merges the changes made by a single request. It returns false if the
+// changes can not be merged because they replace a different generation of a
+// PTR record set than the changes already merged.
+func (c *mergedChanges) merge(changes []types.Change) bool {
+	ptrs := map[string]*mergedPTRSet{}
+	var order []string
+
+	// A record set that is deleted and created by the same request, such as
+	// when the type of its routing policy is changed, must keep both changes.
+	written := map[string]bool{}
+	for _, ch := range changes {
+		if ch.Action != types.ChangeActionDelete {
+			written[recordSetKey(ch.ResourceRecordSet)] = true
+		}
+	}
+
+	for _, ch := range changes {
+		set := ch.ResourceRecordSet
+
+		if !isGenerationalPTRSet(set) {
+			key := recordSetKey(set)
+			if ch.Action == types.ChangeActionDelete && written[key] {
+				key += " (replaced)"
+			}
+			if _, ok := c.changes[key]; !ok {
+				c.keys = append(c.keys, key)
+			}
+			c.changes[key] = ch
+			continue
+		}
+
+		key := "PTR " + strings.ToLower(aws.ToString(set.Name))
+
+		p, ok := ptrs[key]
+		if !ok {
+			p = &mergedPTRSet{}
+			ptrs[key] = p
+			order = append(order, key)
+		}
+
+		if ch.Action == types.ChangeActionDelete {
+			p.Current = set
+		} else {
+			p.Next = set
+		}
+	}
+
+	for _, key := range order {
+		p := ptrs[key]
+		p.Values = ptrValues(p.Next)
+
+		existing, ok := c.ptrs[key]
+		if !ok {
+			c.keys = append(c.keys, key)
+			c.ptrs[key] = p
+			continue
+		}
+
+		if !sameGeneration(existing.Current, p.Current) {
+			return false
+		}
+
+		current := ptrValues(p.Current)
+
+		for _, v := range p.Values {
+			if !containsValue(current, v) && !containsValue(existing.Values, v) {
+				existing.Values = append(existing.Values, v)
+			}
+		}
+
+		for _, v := range current {
+			if !containsValue(p.Values, v) {
+				existing.Values = slices.DeleteFunc(
+					existing.Values,
+					func(x string) bool { return strings.EqualFold(x, v) },
+				)
+			}
+		}
+
+		if existing.Next == nil {
+			existing.Next = p.Next
+		}
+	}
+
+	return true
+}
+
+// build returns the merged changes.
+func (c mergedChanges) build() []types.Change {
+	var changes []types.Change
+
+	for _, key := range c.keys {
+		if ch, ok := c.changes[key]; ok {
+			changes = append(changes, ch)
+			continue
+		}
+
+		p := c.ptrs[key]
+
+		if p.Current != nil {
+			changes = append(changes, types.Change{
+				Action:            types.ChangeActionDelete,
+				ResourceRecordSet: p.Current,
+			})
+		}
+
+		if len(p.Values) == 0 {
+			continue
+		}
+
+		next := p.Next
+		if next == nil {
+			// None of the requests created a new generation, yet values
+			// remain, so the next generation is derived from the current
+			// one.
+			gen, _ := unmarshalGeneration(p.Current.SetIdentifier)
+			next = &types.ResourceRecordSet{
+				SetIdentifier: marshalGeneration(gen + 1),
+				Weight:        aws.Int64(0),
+				Type:          types.RRTypePtr,
+				Name:          p.Current.Name,
+				TTL:           aws.Int64(int64(ptrTTL.Seconds())),
+			}
+		}
+
+		set := *next
+		set.ResourceRecords = nil
+		for _, v := range p.Values {
+			set.ResourceRecords = append(
+				set.ResourceRecords,
+				types.ResourceRecord{Value: aws.String(v)},
+			)
+		}
+
+		changes = append(changes, types.Change{
+			Action:            types.ChangeActionCreate,
+			ResourceRecordSet: &set,
+		})
+	}
+
+	return changes
+}
+
+// isGenerationalPTRSet returns true if set is a PTR record set that is shared
+// by many instances, and is therefore replaced with a new generation on each
+// change.
+func isGenerationalPTRSet(set *types.ResourceRecordSet) bool {
+	if set.Type != types.RRTypePtr {
+		return false
+	}
+	_, err := unmarshalGeneration(set.SetIdentifier)
+	return err == nil
+}
+
+// sameGeneration returns true if a and b are the same generation of a PTR
+// record set, or are both nil.
+func sameGeneration(a, b *types.ResourceRecordSet) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return aws.ToString(a.SetIdentifier) == aws.ToString(b.SetIdentifier)
+}
+
+// ptrValues returns the values of a PTR record set, which may be nil.
+func ptrValues(set *types.ResourceRecordSet) []string {
+	if set == nil {
+		return nil
+	}
+
+	var values []string
+	for _, rec := range set.ResourceRecords {
+		values = append(values, aws.ToString(rec.Value))
+	}
+
+	return values
+}
+
+// containsValue returns true if values contains v, without regard to case.
+func containsValue(values []string, v string) bool {
+	return slices.ContainsFunc(
+		values,
+		func(x string) bool { return strings.EqualFold(x, v) },
+	)
+}
+
+// resolve reports the result of applying the request.
+func (req *batchRequest) resolve(
+	out *route53.ChangeResourceRecordSetsOutput,
+	err error,
+) {
+	// The error is reported via the client that made the original request,
+	// which adds its own operation details.
+	var opErr *smithy.OperationError
+	if errors.As(err, &opErr) {
+		err = opErr.Err
+	}
+
+	req.output = out
+	req.err = err
+	close(req.done)
+}
+
+// recordSetKey returns a string that uniquely identifies a record set within a
+// hosted zone.
+func recordSetKey(set *types.ResourceRecordSet) string {
+	return fmt.Sprintf(
+		"%s %s %s",
+		strings.ToLower(aws.ToString(set.Name)),
+		set.Type,
+		aws.ToString(set.SetIdentifier),
+	)
+}
+
+// batchSize returns the number of resource record elements that Route 53
+// counts towards the maximum size of a request containing the given changes.
+func batchSize(changes []types.Change) int {
+	n := 0
+	for _, c := range changes {
+		n += changeSize(c)
+	}
+	return n
+}
+
+// changeSize returns the number of resource record elements that Route 53
+// counts towards the maximum size of a request for the given change.
+func changeSize(c types.Change) int {
+	n := len(c.ResourceRecordSet.ResourceRecords)
+	if n == 0 {
+		n = 1
+	}
+
+	if c.Action == types.ChangeActionUpsert {
+		n *= 2
+	}
+
+	return n
+}
+
+// normalizeZoneID returns the given hosted zone ID without the "/hostedzone/"
+// prefix that Route 53 includes in some responses.
+func normalizeZoneID(id string) string {
+	return strings.TrimPrefix(id, "/hostedzone/")
+}
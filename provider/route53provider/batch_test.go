@@ -0,0 +1,404 @@
+package route53provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"golang.org/x/exp/slices"
+)
+
+func TestBatcher(t *testing.T) {
+	const zoneID = "Z123"
+
+	ptrSet := func(gen uint64, values ...string) *types.ResourceRecordSet {
+		set := &types.ResourceRecordSet{
+			SetIdentifier: marshalGeneration(gen),
+			Weight:        aws.Int64(0),
+			Type:          types.RRTypePtr,
+			Name:          aws.String("_http._tcp.example.org."),
+			TTL:           aws.Int64(int64(ptrTTL.Seconds())),
+		}
+		for _, v := range values {
+			set.ResourceRecords = append(set.ResourceRecords, types.ResourceRecord{Value: aws.String(v)})
+		}
+		return set
+	}
+
+	srvSet := func(name string, port int) *types.ResourceRecordSet {
+		return &types.ResourceRecordSet{
+			Type: types.RRTypeSrv,
+			Name: aws.String(name),
+			TTL:  aws.Int64(60),
+			ResourceRecords: []types.ResourceRecord{
+				{Value: aws.String(fmt.Sprintf("0 0 %d host.example.org.", port))},
+			},
+		}
+	}
+
+	input := func(changes ...types.Change) *route53.ChangeResourceRecordSetsInput {
+		return &route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: aws.String(zoneID),
+			ChangeBatch:  &types.ChangeBatch{Changes: changes},
+		}
+	}
+
+	// apply applies each of the given inputs concurrently, and returns the
+	// error reported for each of them.
+	apply := func(
+		t *testing.T,
+		client *fakeChanger,
+		inputs ...*route53.ChangeResourceRecordSetsInput,
+	) []error {
+		t.Helper()
+
+		b := &batcher{
+			Client: client,
+			Window: 50 * time.Millisecond,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		errs := make([]error, len(inputs))
+		var g sync.WaitGroup
+
+		for i, in := range inputs {
+			g.Add(1)
+			go func() {
+				defer g.Done()
+				_, errs[i] = b.Apply(ctx, in)
+			}()
+
+			// Ensure the inputs are enqueued in order.
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		g.Wait()
+
+		return errs
+	}
+
+	t.Run("it coalesces changes to different record sets into a single request", func(t *testing.T) {
+		client := &fakeChanger{}
+
+		apply(
+			t,
+			client,
+			input(types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: srvSet("a._http._tcp.example.org.", 80)}),
+			input(types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: srvSet("b._http._tcp.example.org.", 80)}),
+		)
+
+		if len(client.inputs) != 1 {
+			t.Fatalf("unexpected number of requests: got %d, want 1", len(client.inputs))
+		}
+
+		if n := len(client.inputs[0].ChangeBatch.Changes); n != 2 {
+			t.Fatalf("unexpected number of changes: got %d, want 2", n)
+		}
+	})
+
+	t.Run("it applies the last change to the same record set", func(t *testing.T) {
+		client := &fakeChanger{}
+
+		apply(
+			t,
+			client,
+			input(types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: srvSet("a._http._tcp.example.org.", 80)}),
+			input(types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: srvSet("a._http._tcp.example.org.", 8080)}),
+		)
+
+		if len(client.inputs) != 1 {
+			t.Fatalf("unexpected number of requests: got %d, want 1", len(client.inputs))
+		}
+
+		changes := client.inputs[0].ChangeBatch.Changes
+		if len(changes) != 1 {
+			t.Fatalf("unexpected number of changes: got %d, want 1", len(changes))
+		}
+
+		if v := aws.ToString(changes[0].ResourceRecordSet.ResourceRecords[0].Value); v != "0 0 8080 host.example.org." {
+			t.Fatalf("unexpected value: %q", v)
+		}
+	})
+
+	t.Run("it keeps both changes to a record set that is replaced by a single request", func(t *testing.T) {
+		client := &fakeChanger{}
+
+		current := srvSet("a._http._tcp.example.org.", 80)
+		current.SetIdentifier = aws.String("cluster-a")
+		current.Weight = aws.Int64(10)
+
+		next := srvSet("a._http._tcp.example.org.", 80)
+		next.SetIdentifier = aws.String("cluster-a")
+		next.Region = types.ResourceRecordSetRegionUsEast1
+
+		apply(
+			t,
+			client,
+			input(
+				types.Change{Action: types.ChangeActionDelete, ResourceRecordSet: current},
+				types.Change{Action: types.ChangeActionCreate, ResourceRecordSet: next},
+			),
+			input(types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: srvSet("b._http._tcp.example.org.", 80)}),
+		)
+
+		if len(client.inputs) != 1 {
+			t.Fatalf("unexpected number of requests: got %d, want 1", len(client.inputs))
+		}
+
+		changes := client.inputs[0].ChangeBatch.Changes
+		if len(changes) != 3 {
+			t.Fatalf("unexpected number of changes: got %d, want 3", len(changes))
+		}
+
+		if changes[0].Action != types.ChangeActionDelete || changes[0].ResourceRecordSet != current {
+			t.Fatalf("expected the current record set to be deleted first, got %s", changes[0].Action)
+		}
+
+		if changes[1].Action != types.ChangeActionCreate || changes[1].ResourceRecordSet != next {
+			t.Fatalf("expected the replacement record set to be created, got %s", changes[1].Action)
+		}
+	})
+
+	t.Run("it combines the values added to and removed from a PTR record set", func(t *testing.T) {
+		client := &fakeChanger{}
+
+		current := ptrSet(3, "a._http._tcp.example.org.", "b._http._tcp.example.org.")
+
+		apply(
+			t,
+			client,
+			// Add "c".
+			input(
+				types.Change{Action: types.ChangeActionDelete, ResourceRecordSet: current},
+				types.Change{Action: types.ChangeActionCreate, ResourceRecordSet: ptrSet(4, "a._http._tcp.example.org.", "b._http._tcp.example.org.", "c._http._tcp.example.org.")},
+			),
+			// Remove "a".
+			input(
+				types.Change{Action: types.ChangeActionDelete, ResourceRecordSet: current},
+				types.Change{Action: types.ChangeActionCreate, ResourceRecordSet: ptrSet(4, "b._http._tcp.example.org.")},
+			),
+			// Add "d".
+			input(
+				types.Change{Action: types.ChangeActionDelete, ResourceRecordSet: current},
+				types.Change{Action: types.ChangeActionCreate, ResourceRecordSet: ptrSet(4, "a._http._tcp.example.org.", "b._http._tcp.example.org.", "d._http._tcp.example.org.")},
+			),
+		)
+
+		if len(client.inputs) != 1 {
+			t.Fatalf("unexpected number of requests: got %d, want 1", len(client.inputs))
+		}
+
+		changes := client.inputs[0].ChangeBatch.Changes
+		if len(changes) != 2 {
+			t.Fatalf("unexpected number of changes: got %d, want 2", len(changes))
+		}
+
+		if changes[0].Action != types.ChangeActionDelete || aws.ToString(changes[0].ResourceRecordSet.SetIdentifier) != aws.ToString(current.SetIdentifier) {
+			t.Fatalf("expected the current generation to be deleted, got %s %s", changes[0].Action, aws.ToString(changes[0].ResourceRecordSet.SetIdentifier))
+		}
+
+		if changes[1].Action != types.ChangeActionCreate || aws.ToString(changes[1].ResourceRecordSet.SetIdentifier) != aws.ToString(marshalGeneration(4)) {
+			t.Fatalf("expected the next generation to be created, got %s %s", changes[1].Action, aws.ToString(changes[1].ResourceRecordSet.SetIdentifier))
+		}
+
+		got := ptrValues(changes[1].ResourceRecordSet)
+		want := []string{"b._http._tcp.example.org.", "c._http._tcp.example.org.", "d._http._tcp.example.org."}
+		if !slices.Equal(got, want) {
+			t.Fatalf("unexpected values: got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("it creates the next generation of a PTR record set when its last value is removed and another is added", func(t *testing.T) {
+		client := &fakeChanger{}
+
+		current := ptrSet(0, "a._http._tcp.example.org.")
+
+		apply(
+			t,
+			client,
+			// Remove "a", which deletes the record set.
+			input(
+				types.Change{Action: types.ChangeActionDelete, ResourceRecordSet: current},
+			),
+			// Add "b".
+			input(
+				types.Change{Action: types.ChangeActionDelete, ResourceRecordSet: current},
+				types.Change{Action: types.ChangeActionCreate, ResourceRecordSet: ptrSet(1, "a._http._tcp.example.org.", "b._http._tcp.example.org.")},
+			),
+		)
+
+		changes := client.inputs[0].ChangeBatch.Changes
+		if len(changes) != 2 {
+			t.Fatalf("unexpected number of changes: got %d, want 2", len(changes))
+		}
+
+		got := ptrValues(changes[1].ResourceRecordSet)
+		want := []string{"b._http._tcp.example.org."}
+		if !slices.Equal(got, want) {
+			t.Fatalf("unexpected values: got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("it does not merge changes to different generations of a PTR record set", func(t *testing.T) {
+		client := &fakeChanger{}
+
+		apply(
+			t,
+			client,
+			input(
+				types.Change{Action: types.ChangeActionDelete, ResourceRecordSet: ptrSet(1, "a._http._tcp.example.org.")},
+				types.Change{Action: types.ChangeActionCreate, ResourceRecordSet: ptrSet(2, "a._http._tcp.example.org.", "b._http._tcp.example.org.")},
+			),
+			input(
+				types.Change{Action: types.ChangeActionDelete, ResourceRecordSet: ptrSet(2, "a._http._tcp.example.org.", "b._http._tcp.example.org.")},
+				types.Change{Action: types.ChangeActionCreate, ResourceRecordSet: ptrSet(3, "b._http._tcp.example.org.")},
+			),
+		)
+
+		if len(client.inputs) != 2 {
+			t.Fatalf("unexpected number of requests: got %d, want 2", len(client.inputs))
+		}
+	})
+
+	t.Run("it applies each request individually if the merged request fails", func(t *testing.T) {
+		invalid := errors.New("<invalid>")
+
+		client := &fakeChanger{
+			fail: func(in *route53.ChangeResourceRecordSetsInput) error {
+				for _, c := range in.ChangeBatch.Changes {
+					if aws.ToString(c.ResourceRecordSet.Name) == "b._http._tcp.example.org." {
+						return invalid
+					}
+				}
+				return nil
+			},
+		}
+
+		start := time.Now()
+
+		errs := apply(
+			t,
+			client,
+			input(types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: srvSet("a._http._tcp.example.org.", 80)}),
+			input(types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: srvSet("b._http._tcp.example.org.", 80)}),
+		)
+
+		if len(client.inputs) != 3 {
+			t.Fatalf("unexpected number of requests: got %d, want 3", len(client.inputs))
+		}
+
+		if errs[0] != nil {
+			t.Fatalf("unexpected error: %s", errs[0])
+		}
+
+		if !errors.Is(errs[1], invalid) {
+			t.Fatalf("unexpected error: got %v, want %v", errs[1], invalid)
+		}
+
+		if d := time.Since(start); d < batchBackoff {
+			t.Fatalf("expected the individual requests to be delayed by at least %s, took %s", batchBackoff, d)
+		}
+	})
+
+	t.Run("it does not back off beyond the deadline of a request", func(t *testing.T) {
+		client := &fakeChanger{
+			fail: func(in *route53.ChangeResourceRecordSetsInput) error {
+				if len(in.ChangeBatch.Changes) > 1 {
+					return errors.New("<invalid>")
+				}
+				return nil
+			},
+		}
+
+		b := &batcher{
+			Client: client,
+			Window: 50 * time.Millisecond,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// The second request's deadline is reached before the batch's first
+		// back-off has elapsed.
+		shortCtx, cancel := context.WithTimeout(context.Background(), batchBackoff/2)
+		defer cancel()
+
+		start := time.Now()
+		errs := make([]error, 2)
+		var g sync.WaitGroup
+
+		g.Add(2)
+		go func() {
+			defer g.Done()
+			_, errs[0] = b.Apply(ctx, input(types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: srvSet("a._http._tcp.example.org.", 80)}))
+		}()
+		time.Sleep(5 * time.Millisecond)
+		go func() {
+			defer g.Done()
+			_, errs[1] = b.Apply(shortCtx, input(types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: srvSet("b._http._tcp.example.org.", 80)}))
+		}()
+		g.Wait()
+
+		if errs[0] != nil {
+			t.Fatalf("unexpected error: %s", errs[0])
+		}
+
+		if !errors.Is(errs[1], context.DeadlineExceeded) {
+			t.Fatalf("unexpected error: got %v, want %v", errs[1], context.DeadlineExceeded)
+		}
+
+		if d := time.Since(start); d >= batchBackoff {
+			t.Fatalf("expected the request to fail before the back-off elapsed, took %s", d)
+		}
+
+		// Wait for the batch to finish, to ensure that the request that
+		// failed is not applied afterwards.
+		time.Sleep(batchBackoff)
+
+		client.m.Lock()
+		defer client.m.Unlock()
+
+		if len(client.inputs) != 2 {
+			t.Fatalf("unexpected number of requests: got %d, want 2", len(client.inputs))
+		}
+	})
+}
+
+// fakeChanger is a recordSetChanger that records the inputs it is given.
+type fakeChanger struct {
+	m      sync.Mutex
+	inputs []*route53.ChangeResourceRecordSetsInput
+	fail   func(*route53.ChangeResourceRecordSetsInput) error
+}
+
+func (c *fakeChanger) ChangeResourceRecordSets(
+	_ context.Context,
+	in *route53.ChangeResourceRecordSetsInput,
+	_ ...func(*route53.Options),
+) (*route53.ChangeResourceRecordSetsOutput, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.inputs = append(c.inputs, in)
+
+	if c.fail != nil {
+		if err := c.fail(in); err != nil {
+			return nil, err
+		}
+	}
+
+	return &route53.ChangeResourceRecordSetsOutput{
+		ChangeInfo: &types.ChangeInfo{
+			Id:     aws.String("C123"),
+			Status: types.ChangeStatusPending,
+		},
+	}, nil
+}
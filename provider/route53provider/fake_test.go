@@ -0,0 +1,358 @@
+package route53provider
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"golang.org/x/exp/slices"
+)
+
+// fakeRoute53 is an in-memory implementation of advertiserAPI for a single
+// hosted zone.
+//
+// It enforces the constraints that Route 53 places on changes to record sets
+// and health checks that the advertiser relies upon, such as rejecting the
+// creation of a record set that already exists, the deletion of a record set
+// that does not match exactly, mixing simple record sets with those that use a
+// routing policy, and deleting a health check that is still in use.
+type fakeRoute53 struct {
+	m            sync.Mutex
+	sets         []types.ResourceRecordSet
+	healthChecks map[string]types.HealthCheck
+	changes      []*route53.ChangeResourceRecordSetsInput
+	seq          int
+}
+
+var _ advertiserAPI = (*fakeRoute53)(nil)
+
+func (f *fakeRoute53) ListResourceRecordSets(
+	_ context.Context,
+	in *route53.ListResourceRecordSetsInput,
+	_ ...func(*route53.Options),
+) (*route53.ListResourceRecordSetsOutput, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	sets := slices.Clone(f.sets)
+	slices.SortFunc(sets, compareRecordSets)
+
+	start := types.ResourceRecordSet{
+		Name:          in.StartRecordName,
+		Type:          in.StartRecordType,
+		SetIdentifier: in.StartRecordIdentifier,
+	}
+
+	index, _ := slices.BinarySearchFunc(sets, start, compareRecordSets)
+	sets = sets[index:]
+
+	limit := int(aws.ToInt32(in.MaxItems))
+	if limit == 0 {
+		limit = 100
+	}
+
+	out := &route53.ListResourceRecordSetsOutput{}
+
+	if len(sets) > limit {
+		next := sets[limit]
+		out.IsTruncated = true
+		out.NextRecordName = next.Name
+		out.NextRecordType = next.Type
+		out.NextRecordIdentifier = next.SetIdentifier
+		sets = sets[:limit]
+	}
+
+	out.ResourceRecordSets = sets
+
+	return out, nil
+}
+
+func (f *fakeRoute53) ChangeResourceRecordSets(
+	_ context.Context,
+	in *route53.ChangeResourceRecordSetsInput,
+	_ ...func(*route53.Options),
+) (*route53.ChangeResourceRecordSetsOutput, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	sets := slices.Clone(f.sets)
+
+	for _, c := range in.ChangeBatch.Changes {
+		set := *c.ResourceRecordSet
+		index := slices.IndexFunc(
+			sets,
+			func(s types.ResourceRecordSet) bool {
+				return compareRecordSets(s, set) == 0
+			},
+		)
+
+		switch c.Action {
+		case types.ChangeActionCreate:
+			if index != -1 {
+				return nil, invalidChange(c, "it already exists")
+			}
+		case types.ChangeActionDelete:
+			if index == -1 || !sameRecordSet(sets[index], set) {
+				return nil, invalidChange(c, "it was not found")
+			}
+		case types.ChangeActionUpsert:
+			if index != -1 && routingType(sets[index]) != routingType(set) {
+				return nil, invalidChange(c, "the routing policy type can not be changed")
+			}
+		}
+
+		if c.Action != types.ChangeActionDelete {
+			if id := aws.ToString(set.HealthCheckId); id != "" {
+				if _, ok := f.healthChecks[id]; !ok {
+					return nil, invalidChange(c, "the health check does not exist")
+				}
+			}
+		}
+
+		if index != -1 {
+			sets = slices.Delete(sets, index, index+1)
+		}
+
+		if c.Action != types.ChangeActionDelete {
+			sets = append(sets, set)
+		}
+	}
+
+	for _, set := range sets {
+		for _, other := range sets {
+			if !strings.EqualFold(*set.Name, *other.Name) || set.Type != other.Type {
+				continue
+			}
+
+			if set.SetIdentifier == nil && other.SetIdentifier != nil {
+				return nil, invalidChangeBatch(
+					"simple %s record set %s conflicts with record sets that use a routing policy",
+					set.Type,
+					*set.Name,
+				)
+			}
+
+			if routingType(set) != routingType(other) {
+				return nil, invalidChangeBatch(
+					"%s record sets named %s use different types of routing policy",
+					set.Type,
+					*set.Name,
+				)
+			}
+		}
+	}
+
+	f.sets = sets
+	f.changes = append(f.changes, in)
+	f.seq++
+
+	return &route53.ChangeResourceRecordSetsOutput{
+		ChangeInfo: &types.ChangeInfo{
+			Id:     aws.String(fmt.Sprintf("/change/C%d", f.seq)),
+			Status: types.ChangeStatusPending,
+		},
+	}, nil
+}
+
+func (f *fakeRoute53) GetChange(
+	_ context.Context,
+	in *route53.GetChangeInput,
+	_ ...func(*route53.Options),
+) (*route53.GetChangeOutput, error) {
+	return &route53.GetChangeOutput{
+		ChangeInfo: &types.ChangeInfo{
+			Id:     in.Id,
+			Status: types.ChangeStatusInsync,
+		},
+	}, nil
+}
+
+func (f *fakeRoute53) GetHealthCheck(
+	_ context.Context,
+	in *route53.GetHealthCheckInput,
+	_ ...func(*route53.Options),
+) (*route53.GetHealthCheckOutput, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	hc, ok := f.healthChecks[aws.ToString(in.HealthCheckId)]
+	if !ok {
+		return nil, &types.NoSuchHealthCheck{}
+	}
+
+	return &route53.GetHealthCheckOutput{HealthCheck: &hc}, nil
+}
+
+func (f *fakeRoute53) CreateHealthCheck(
+	_ context.Context,
+	in *route53.CreateHealthCheckInput,
+	_ ...func(*route53.Options),
+) (*route53.CreateHealthCheckOutput, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	for _, hc := range f.healthChecks {
+		if aws.ToString(hc.CallerReference) == aws.ToString(in.CallerReference) {
+			return nil, &types.HealthCheckAlreadyExists{}
+		}
+	}
+
+	f.seq++
+
+	hc := types.HealthCheck{
+		Id:                aws.String(fmt.Sprintf("HC%d", f.seq)),
+		CallerReference:   in.CallerReference,
+		HealthCheckConfig: in.HealthCheckConfig,
+	}
+
+	if f.healthChecks == nil {
+		f.healthChecks = map[string]types.HealthCheck{}
+	}
+	f.healthChecks[*hc.Id] = hc
+
+	return &route53.CreateHealthCheckOutput{HealthCheck: &hc}, nil
+}
+
+func (f *fakeRoute53) DeleteHealthCheck(
+	_ context.Context,
+	in *route53.DeleteHealthCheckInput,
+	_ ...func(*route53.Options),
+) (*route53.DeleteHealthCheckOutput, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	id := aws.ToString(in.HealthCheckId)
+
+	if _, ok := f.healthChecks[id]; !ok {
+		return nil, &types.NoSuchHealthCheck{}
+	}
+
+	for _, set := range f.sets {
+		if aws.ToString(set.HealthCheckId) == id {
+			return nil, &types.HealthCheckInUse{}
+		}
+	}
+
+	delete(f.healthChecks, id)
+
+	return &route53.DeleteHealthCheckOutput{}, nil
+}
+
+// addHealthCheck adds a health check that was not created by Proclaim.
+func (f *fakeRoute53) addHealthCheck(id string) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.healthChecks == nil {
+		f.healthChecks = map[string]types.HealthCheck{}
+	}
+
+	f.healthChecks[id] = types.HealthCheck{
+		Id:              aws.String(id),
+		CallerReference: aws.String("<external>"),
+		HealthCheckConfig: &types.HealthCheckConfig{
+			Type: types.HealthCheckTypeTcp,
+		},
+	}
+}
+
+// recordSets returns the record sets with the given name and type, in the
+// order that Route 53 lists them.
+func (f *fakeRoute53) recordSets(name string, recordType types.RRType) []types.ResourceRecordSet {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	var sets []types.ResourceRecordSet
+	for _, set := range f.sets {
+		if strings.EqualFold(*set.Name, name) && set.Type == recordType {
+			sets = append(sets, set)
+		}
+	}
+
+	slices.SortFunc(sets, compareRecordSets)
+
+	return sets
+}
+
+// recordSet returns the only record set with the given name and type. It
+// panics if there is more than one.
+func (f *fakeRoute53) recordSet(name string, recordType types.RRType) (types.ResourceRecordSet, bool) {
+	sets := f.recordSets(name, recordType)
+
+	switch len(sets) {
+	case 0:
+		return types.ResourceRecordSet{}, false
+	case 1:
+		return sets[0], true
+	default:
+		panic(fmt.Sprintf("found %d %s record sets named %s", len(sets), recordType, name))
+	}
+}
+
+// requests returns the number of ChangeResourceRecordSets requests that have
+// been applied.
+func (f *fakeRoute53) requests() int {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	return len(f.changes)
+}
+
+// invalidChange returns the error that Route 53 reports when c can not be
+// applied.
+func invalidChange(c types.Change, reason string) error {
+	return invalidChangeBatch(
+		"tried to %s %s record set %s, but %s",
+		strings.ToLower(string(c.Action)),
+		c.ResourceRecordSet.Type,
+		aws.ToString(c.ResourceRecordSet.Name),
+		reason,
+	)
+}
+
+// invalidChangeBatch returns the error that Route 53 reports when a change
+// batch is rejected.
+func invalidChangeBatch(format string, args ...any) error {
+	message := fmt.Sprintf(format, args...)
+
+	return &types.InvalidChangeBatch{
+		Message:  aws.String(message),
+		Messages: []string{message},
+	}
+}
+
+// compareRecordSets compares record sets in the order that Route 53 lists
+// them, which is by name with the labels reversed, then by type, then by set
+// identifier.
+func compareRecordSets(a, b types.ResourceRecordSet) int {
+	if c := slices.Compare(
+		reversedLabels(aws.ToString(a.Name)),
+		reversedLabels(aws.ToString(b.Name)),
+	); c != 0 {
+		return c
+	}
+
+	if c := cmp.Compare(a.Type, b.Type); c != 0 {
+		return c
+	}
+
+	return cmp.Compare(aws.ToString(a.SetIdentifier), aws.ToString(b.SetIdentifier))
+}
+
+// reversedLabels returns the labels of a DNS name in reverse order.
+func reversedLabels(name string) []string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if name == "" {
+		return nil
+	}
+
+	labels := strings.Split(name, ".")
+	slices.Reverse(labels)
+
+	return labels
+}
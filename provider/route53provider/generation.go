@@ -0,0 +1,52 @@
+package route53provider
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// generationPrefix is the prefix to append to the numeric generation number
+// when encoding it in the SetIdentifier field of a Route 53 resource record
+// set.
+//
+// It matches the prefix used by the Route 53 advertiser in
+// github.com/dogmatiq/dissolve.
+const generationPrefix = "dnssd:generation"
+
+// marshalGeneration returns a string representation of the given generation
+// number suitable for being encoded in the SetIdentifier field of a Route 53
+// resource record set.
+//
+// Encoding the generation here allows us to identify resource record sets with
+// the same name and type by their generation (version).
+func marshalGeneration(n uint64) *string {
+	return aws.String(fmt.Sprintf("%s=%d", generationPrefix, n))
+}
+
+// unmarshalGeneration returns the generation number encoded in the
+// SetIdentifier field of a Route 53 resource record set.
+func unmarshalGeneration(gen *string) (uint64, error) {
+	if gen == nil {
+		return 0, errors.New("missing rr-set generation")
+	}
+
+	prefix, number, ok := strings.Cut(*gen, "=")
+	if !ok {
+		return 0, fmt.Errorf("invalid rr-set generation %q: missing '='", *gen)
+	}
+
+	if prefix != generationPrefix {
+		return 0, fmt.Errorf("invalid rr-set generation %q: unexpected key before '='", *gen)
+	}
+
+	n, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rr-set generation %q: invalid generation number: %w", *gen, err)
+	}
+
+	return n, nil
+}
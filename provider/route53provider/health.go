@@ -0,0 +1,21 @@
+package route53provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+)
+
+// HealthCheck returns an error if the provider's credentials are invalid or
+// the Route 53 API can not be reached.
+func (p *Provider) HealthCheck(ctx context.Context) error {
+	if _, err := p.client().GetHostedZoneCount(
+		ctx,
+		&route53.GetHostedZoneCountInput{},
+	); err != nil {
+		return fmt.Errorf("unable to get hosted zone count: %w", err)
+	}
+
+	return nil
+}
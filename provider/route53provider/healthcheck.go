@@ -0,0 +1,227 @@
+package route53provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/provider"
+)
+
+// healthCheckCallerReferencePrefix is the prefix of the caller reference of
+// each health check created by Proclaim. It is used to distinguish health
+// checks that Proclaim may delete from those that are merely referenced by a
+// service instance.
+const healthCheckCallerReferencePrefix = "proclaim:"
+
+// defaultHealthCheckFailureThreshold is the number of consecutive checks that
+// must fail before a target is considered unhealthy, if the health check does
+// not specify a threshold.
+const defaultHealthCheckFailureThreshold = 3
+
+// validateHealthCheck returns an error if hc can not be used to check the
+// health of an instance's records.
+func validateHealthCheck(hc provider.HealthCheck) error {
+	if hc.ID != "" {
+		if hc.Protocol != "" || hc.Path != "" || hc.FailureThreshold != 0 {
+			return errors.New("health check must not specify a protocol, path or failure threshold when it refers to an existing health check")
+		}
+		return nil
+	}
+
+	switch types.HealthCheckType(hc.Protocol) {
+	case "", types.HealthCheckTypeTcp:
+		if hc.Path != "" {
+			return errors.New("health check must not specify a path when using the TCP protocol")
+		}
+	case types.HealthCheckTypeHttp, types.HealthCheckTypeHttps:
+	default:
+		return fmt.Errorf("unsupported health check protocol %q", hc.Protocol)
+	}
+
+	if hc.FailureThreshold < 0 || hc.FailureThreshold > 10 {
+		return errors.New("health check failure threshold must be between 1 and 10")
+	}
+
+	return nil
+}
+
+// syncHealthCheck returns the ID of the health check to associate with the
+// given service instance's records.
+//
+// current is the ID of the health check that is currently associated with the
+// records, if any. If the advertiser's routing policy requires a health check
+// that Proclaim manages, current is re-used if it matches the instance's
+// target, otherwise a new health check is created.
+//
+// created is true if a new health check was created.
+func (a *advertiser) syncHealthCheck(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+	current string,
+) (id string, created bool, _ error) {
+	if a.Policy == nil || a.Policy.HealthCheck == nil {
+		return "", false, nil
+	}
+
+	hc := a.Policy.HealthCheck
+
+	if hc.ID != "" {
+		return hc.ID, false, nil
+	}
+
+	desired := healthCheckConfig(inst, *hc)
+
+	if current != "" {
+		existing, ok, err := a.getHealthCheck(ctx, current)
+		if err != nil {
+			return "", false, err
+		}
+
+		if ok && isOwnHealthCheck(existing) && sameHealthCheckConfig(*existing.HealthCheckConfig, *desired) {
+			return current, false, nil
+		}
+	}
+
+	out, err := a.Client.CreateHealthCheck(
+		ctx,
+		&route53.CreateHealthCheckInput{
+			CallerReference:   aws.String(healthCheckCallerReference(a.Policy.SetIdentifier)),
+			HealthCheckConfig: desired,
+		},
+	)
+	if err != nil {
+		return "", false, fmt.Errorf("unable to create health check: %w", err)
+	}
+
+	return aws.ToString(out.HealthCheck.Id), true, nil
+}
+
+// deleteHealthCheck deletes the health check with the given ID, provided that
+// it was created by Proclaim.
+//
+// Health checks that do not exist, or that were not created by Proclaim, are
+// left as-is.
+func (a *advertiser) deleteHealthCheck(ctx context.Context, id string) error {
+	if id == "" {
+		return nil
+	}
+
+	hc, ok, err := a.getHealthCheck(ctx, id)
+	if err != nil || !ok || !isOwnHealthCheck(hc) {
+		return err
+	}
+
+	if _, err := a.Client.DeleteHealthCheck(
+		ctx,
+		&route53.DeleteHealthCheckInput{
+			HealthCheckId: aws.String(id),
+		},
+	); err != nil {
+		var notFound *types.NoSuchHealthCheck
+		if errors.As(err, &notFound) {
+			return nil
+		}
+
+		return fmt.Errorf("unable to delete health check: %w", err)
+	}
+
+	return nil
+}
+
+// getHealthCheck returns the health check with the given ID.
+func (a *advertiser) getHealthCheck(
+	ctx context.Context,
+	id string,
+) (types.HealthCheck, bool, error) {
+	out, err := a.Client.GetHealthCheck(
+		ctx,
+		&route53.GetHealthCheckInput{
+			HealthCheckId: aws.String(id),
+		},
+	)
+	if err != nil {
+		var notFound *types.NoSuchHealthCheck
+		if errors.As(err, &notFound) {
+			return types.HealthCheck{}, false, nil
+		}
+
+		return types.HealthCheck{}, false, fmt.Errorf("unable to get health check: %w", err)
+	}
+
+	return *out.HealthCheck, true, nil
+}
+
+// healthCheckConfig returns the configuration of a health check of the given
+// service instance's target host and port.
+func healthCheckConfig(
+	inst dnssd.ServiceInstance,
+	hc provider.HealthCheck,
+) *types.HealthCheckConfig {
+	cfg := &types.HealthCheckConfig{
+		Type:                     types.HealthCheckTypeTcp,
+		FullyQualifiedDomainName: aws.String(strings.TrimSuffix(inst.TargetHost, ".")),
+		Port:                     aws.Int32(int32(inst.TargetPort)),
+		FailureThreshold:         aws.Int32(defaultHealthCheckFailureThreshold),
+	}
+
+	if hc.Protocol != "" {
+		cfg.Type = types.HealthCheckType(hc.Protocol)
+	}
+
+	if hc.Path != "" {
+		cfg.ResourcePath = aws.String(hc.Path)
+	}
+
+	if hc.FailureThreshold != 0 {
+		cfg.FailureThreshold = aws.Int32(hc.FailureThreshold)
+	}
+
+	return cfg
+}
+
+// sameHealthCheckConfig returns true if a and b check the same target in the
+// same way.
+//
+// Only the attributes that are set by healthCheckConfig() are compared.
+func sameHealthCheckConfig(a, b types.HealthCheckConfig) bool {
+	return a.Type == b.Type &&
+		strings.EqualFold(aws.ToString(a.FullyQualifiedDomainName), aws.ToString(b.FullyQualifiedDomainName)) &&
+		aws.ToInt32(a.Port) == aws.ToInt32(b.Port) &&
+		aws.ToString(a.ResourcePath) == aws.ToString(b.ResourcePath) &&
+		aws.ToInt32(a.FailureThreshold) == aws.ToInt32(b.FailureThreshold)
+}
+
+// isOwnHealthCheck returns true if hc was created by Proclaim.
+func isOwnHealthCheck(hc types.HealthCheck) bool {
+	return strings.HasPrefix(aws.ToString(hc.CallerReference), healthCheckCallerReferencePrefix) &&
+		hc.HealthCheckConfig != nil
+}
+
+// healthCheckCallerReference returns a new caller reference for a health check
+// created by Proclaim.
+//
+// Route 53 never accepts the same caller reference twice, even after the
+// health check that used it has been deleted, so the reference includes the
+// current time.
+func healthCheckCallerReference(setID string) string {
+	ref := fmt.Sprintf(
+		"%s%d:%s",
+		healthCheckCallerReferencePrefix,
+		time.Now().UnixNano(),
+		setID,
+	)
+
+	// Route 53 limits caller references to 64 characters.
+	if len(ref) > 64 {
+		ref = ref[:64]
+	}
+
+	return ref
+}
@@ -0,0 +1,313 @@
+package route53provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/provider"
+)
+
+func TestAdvertiser_healthChecks(t *testing.T) {
+	instance := func() dnssd.ServiceInstance {
+		return dnssd.ServiceInstance{
+			ServiceInstanceName: dnssd.ServiceInstanceName{
+				Name:        "Instance A",
+				ServiceType: "_http._tcp",
+				Domain:      "example.org",
+			},
+			TargetHost: "host.example.org",
+			TargetPort: 8080,
+			TTL:        60 * time.Second,
+		}
+	}
+
+	policy := func(hc *provider.HealthCheck) provider.RoutingPolicy {
+		weight := int64(10)
+		return provider.RoutingPolicy{
+			SetIdentifier: "cluster-a",
+			Weight:        &weight,
+			HealthCheck:   hc,
+		}
+	}
+
+	setup := func(t *testing.T, hc *provider.HealthCheck) (*advertiser, *fakeRoute53) {
+		t.Helper()
+
+		client := &fakeRoute53{}
+		a := &advertiser{Client: client, ZoneID: "Z123"}
+
+		x, err := a.WithRoutingPolicy(policy(hc))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return x.(*advertiser), client
+	}
+
+	advertise := func(t *testing.T, a *advertiser, inst dnssd.ServiceInstance) {
+		t.Helper()
+
+		if _, err := a.Advertise(context.Background(), inst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	unadvertise := func(t *testing.T, a *advertiser, inst dnssd.ServiceInstance) {
+		t.Helper()
+
+		if _, err := a.Unadvertise(context.Background(), inst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// healthCheckID returns the ID of the health check associated with the
+	// instance's SRV and TXT record sets.
+	healthCheckID := func(t *testing.T, client *fakeRoute53, inst dnssd.ServiceInstance) string {
+		t.Helper()
+
+		srv, ok := client.recordSet(inst.Absolute(), types.RRTypeSrv)
+		if !ok {
+			t.Fatal("expected an SRV record set")
+		}
+
+		txt, ok := client.recordSet(inst.Absolute(), types.RRTypeTxt)
+		if !ok {
+			t.Fatal("expected a TXT record set")
+		}
+
+		id := aws.ToString(srv.HealthCheckId)
+		if id != aws.ToString(txt.HealthCheckId) {
+			t.Fatalf("SRV and TXT record sets use different health checks: %q and %q", id, aws.ToString(txt.HealthCheckId))
+		}
+
+		return id
+	}
+
+	t.Run("it rejects invalid health checks", func(t *testing.T) {
+		a := &advertiser{Client: &fakeRoute53{}, ZoneID: "Z123"}
+
+		cases := []struct {
+			Name        string
+			HealthCheck provider.HealthCheck
+		}{
+			{
+				"existing health check with a protocol",
+				provider.HealthCheck{ID: "<id>", Protocol: "HTTP"},
+			},
+			{
+				"TCP health check with a path",
+				provider.HealthCheck{Protocol: "TCP", Path: "/health"},
+			},
+			{
+				"unsupported protocol",
+				provider.HealthCheck{Protocol: "ICMP"},
+			},
+			{
+				"failure threshold out of range",
+				provider.HealthCheck{FailureThreshold: 11},
+			},
+		}
+
+		for _, c := range cases {
+			t.Run(c.Name, func(t *testing.T) {
+				if _, err := a.WithRoutingPolicy(policy(&c.HealthCheck)); err == nil {
+					t.Fatal("expected an error")
+				}
+			})
+		}
+	})
+
+	t.Run("it creates a health check of the target host and port", func(t *testing.T) {
+		a, client := setup(t, &provider.HealthCheck{
+			Protocol: "HTTPS",
+			Path:     "/health",
+		})
+		inst := instance()
+
+		advertise(t, a, inst)
+
+		id := healthCheckID(t, client, inst)
+		if id == "" {
+			t.Fatal("expected the records to be associated with a health check")
+		}
+
+		hc := client.healthChecks[id]
+		cfg := hc.HealthCheckConfig
+
+		if !strings.HasPrefix(aws.ToString(hc.CallerReference), healthCheckCallerReferencePrefix) {
+			t.Fatalf("unexpected caller reference: %q", aws.ToString(hc.CallerReference))
+		}
+
+		if cfg.Type != types.HealthCheckTypeHttps {
+			t.Fatalf("unexpected type: got %q, want %q", cfg.Type, types.HealthCheckTypeHttps)
+		}
+
+		if got := aws.ToString(cfg.FullyQualifiedDomainName); got != "host.example.org" {
+			t.Fatalf("unexpected domain name: got %q, want %q", got, "host.example.org")
+		}
+
+		if got := aws.ToInt32(cfg.Port); got != 8080 {
+			t.Fatalf("unexpected port: got %d, want 8080", got)
+		}
+
+		if got := aws.ToString(cfg.ResourcePath); got != "/health" {
+			t.Fatalf("unexpected path: got %q, want %q", got, "/health")
+		}
+
+		if got := aws.ToInt32(cfg.FailureThreshold); got != defaultHealthCheckFailureThreshold {
+			t.Fatalf("unexpected failure threshold: got %d, want %d", got, defaultHealthCheckFailureThreshold)
+		}
+
+		// The PTR record set is shared with other instances.
+		ptr, _ := client.recordSet("_http._tcp.example.org.", types.RRTypePtr)
+		if ptr.HealthCheckId != nil {
+			t.Fatal("did not expect the PTR record set to be associated with a health check")
+		}
+	})
+
+	t.Run("it re-uses the health check if the target has not changed", func(t *testing.T) {
+		a, client := setup(t, &provider.HealthCheck{})
+		inst := instance()
+
+		advertise(t, a, inst)
+		id := healthCheckID(t, client, inst)
+
+		changed, err := a.Advertise(context.Background(), inst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed {
+			t.Fatal("did not expect a change to be reported")
+		}
+
+		if got := healthCheckID(t, client, inst); got != id {
+			t.Fatalf("unexpected health check: got %q, want %q", got, id)
+		}
+
+		if n := len(client.healthChecks); n != 1 {
+			t.Fatalf("unexpected number of health checks: got %d, want 1", n)
+		}
+	})
+
+	t.Run("it replaces the health check if the target changes", func(t *testing.T) {
+		a, client := setup(t, &provider.HealthCheck{})
+		inst := instance()
+
+		advertise(t, a, inst)
+		prev := healthCheckID(t, client, inst)
+
+		inst.TargetPort = 8443
+		advertise(t, a, inst)
+
+		id := healthCheckID(t, client, inst)
+		if id == prev {
+			t.Fatal("expected a new health check")
+		}
+
+		if got := aws.ToInt32(client.healthChecks[id].HealthCheckConfig.Port); got != 8443 {
+			t.Fatalf("unexpected port: got %d, want 8443", got)
+		}
+
+		if _, ok := client.healthChecks[prev]; ok {
+			t.Fatal("expected the previous health check to be deleted")
+		}
+	})
+
+	t.Run("it deletes the health check when it is removed from the routing policy", func(t *testing.T) {
+		a, client := setup(t, &provider.HealthCheck{})
+		inst := instance()
+
+		advertise(t, a, inst)
+		prev := healthCheckID(t, client, inst)
+
+		x, err := a.WithRoutingPolicy(policy(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		advertise(t, x.(*advertiser), inst)
+
+		if id := healthCheckID(t, client, inst); id != "" {
+			t.Fatalf("did not expect the records to be associated with a health check, got %q", id)
+		}
+
+		if _, ok := client.healthChecks[prev]; ok {
+			t.Fatal("expected the health check to be deleted")
+		}
+	})
+
+	t.Run("it deletes the health check when the routing policy is removed", func(t *testing.T) {
+		a, client := setup(t, &provider.HealthCheck{})
+		inst := instance()
+
+		advertise(t, a, inst)
+		prev := healthCheckID(t, client, inst)
+
+		advertise(t, &advertiser{Client: client, ZoneID: a.ZoneID}, inst)
+
+		if _, ok := client.healthChecks[prev]; ok {
+			t.Fatal("expected the health check to be deleted")
+		}
+	})
+
+	t.Run("it deletes the health check when the instance is unadvertised", func(t *testing.T) {
+		a, client := setup(t, &provider.HealthCheck{})
+		inst := instance()
+
+		advertise(t, a, inst)
+		unadvertise(t, a, inst)
+
+		if n := len(client.healthChecks); n != 0 {
+			t.Fatalf("unexpected number of health checks: got %d, want 0", n)
+		}
+	})
+
+	t.Run("it associates the records with an existing health check without deleting it", func(t *testing.T) {
+		a, client := setup(t, &provider.HealthCheck{ID: "HC-EXTERNAL"})
+		client.addHealthCheck("HC-EXTERNAL")
+		inst := instance()
+
+		advertise(t, a, inst)
+
+		if id := healthCheckID(t, client, inst); id != "HC-EXTERNAL" {
+			t.Fatalf("unexpected health check: got %q, want %q", id, "HC-EXTERNAL")
+		}
+
+		unadvertise(t, a, inst)
+
+		if _, ok := client.healthChecks["HC-EXTERNAL"]; !ok {
+			t.Fatal("did not expect the existing health check to be deleted")
+		}
+	})
+
+	t.Run("it deletes a health check that it created if the records can not be changed", func(t *testing.T) {
+		a, client := setup(t, &provider.HealthCheck{})
+		inst := instance()
+
+		// Another cluster has advertised the instance using a different type
+		// of routing policy, so the advertiser's record sets can not coexist
+		// with it.
+		client.sets = append(client.sets, types.ResourceRecordSet{
+			Name:          aws.String(inst.Absolute()),
+			Type:          types.RRTypeSrv,
+			TTL:           aws.Int64(60),
+			SetIdentifier: aws.String("cluster-b"),
+			Region:        types.ResourceRecordSetRegionUsEast1,
+			ResourceRecords: []types.ResourceRecord{
+				{Value: aws.String("0 0 8080 other.example.org.")},
+			},
+		})
+
+		if _, err := a.Advertise(context.Background(), inst); err == nil {
+			t.Fatal("expected an error")
+		}
+
+		if n := len(client.healthChecks); n != 0 {
+			t.Fatalf("unexpected number of health checks: got %d, want 0", n)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package route53provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/miekg/dns"
+)
+
+// Plan returns the changes to DNS records that Advertise would make in order
+// to advertise the given service instance, without making them.
+func (a *advertiser) Plan(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+	options ...dnssd.AdvertiseOption,
+) ([]provider.Change, error) {
+	// Address records are only managed if the instance is advertised with
+	// explicit IP addresses, as per Advertise().
+	addresses := len(provider.AddressRecords(inst, options...)) != 0
+
+	current, err := a.instanceRecords(ctx, inst, true, addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.PlanChanges(
+		current,
+		dnssd.NewRecords(inst, options...),
+	), nil
+}
+
+// PlanUnadvertise returns the changes to DNS records that Unadvertise would
+// make in order to unadvertise the given service instance, without making
+// them.
+func (a *advertiser) PlanUnadvertise(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+) ([]provider.Change, error) {
+	// As per Unadvertise(), the PTR records are kept if the instance is still
+	// advertised using another routing policy.
+	sets, err := a.findRecordSets(ctx, inst.Absolute(), types.RRTypeSrv)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := false
+	for _, set := range sets {
+		if aws.ToString(set.SetIdentifier) != a.setIdentifier() {
+			shared = true
+			break
+		}
+	}
+
+	current, err := a.instanceRecords(ctx, inst, !shared, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.PlanChanges(current, nil), nil
+}
+
+// instanceRecords returns the records that currently advertise the given
+// service instance within the advertiser's record sets.
+//
+// The instance's PTR records and the address records of its target host are
+// only included if ptrs and addresses are true, respectively.
+func (a *advertiser) instanceRecords(
+	ctx context.Context,
+	inst dnssd.ServiceInstance,
+	ptrs, addresses bool,
+) ([]dns.RR, error) {
+	var current []dns.RR
+
+	// PTR record sets are shared by many instances, so only the value that
+	// refers to this instance is relevant. They also share a single TTL that
+	// is not derived from any instance, so the TTL is not planned.
+	ttl := uint32(inst.TTL.Seconds())
+
+	if ptrs {
+		ptr, ok, err := a.findRecordSet(
+			ctx,
+			dnssd.AbsoluteInstanceEnumerationDomain(inst.ServiceType, inst.Domain),
+			types.RRTypePtr,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sets, err := a.findSubTypePTRs(ctx, inst)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			sets = append(sets, ptr)
+		}
+
+		for _, set := range sets {
+			if i := indexOf(set, inst.Absolute()); i != -1 {
+				rr, err := parseRecord(set, *set.ResourceRecords[i].Value)
+				if err != nil {
+					return nil, err
+				}
+
+				rr.Header().Ttl = ttl
+				current = append(current, rr)
+			}
+		}
+	}
+
+	for _, t := range []types.RRType{types.RRTypeSrv, types.RRTypeTxt} {
+		records, err := a.currentRecords(ctx, inst.Absolute(), t)
+		if err != nil {
+			return nil, err
+		}
+		current = append(current, records...)
+	}
+
+	if addresses {
+		for _, t := range []types.RRType{types.RRTypeA, types.RRTypeAaaa} {
+			records, err := a.currentRecords(ctx, dns.Fqdn(inst.TargetHost), t)
+			if err != nil {
+				return nil, err
+			}
+			current = append(current, records...)
+		}
+	}
+
+	return current, nil
+}
+
+// currentRecords returns the records in the advertiser's record set with the
+// given name and type.
+func (a *advertiser) currentRecords(
+	ctx context.Context,
+	name string,
+	recordType types.RRType,
+) ([]dns.RR, error) {
+	set, ok, err := a.findOwnRecordSet(ctx, name, recordType)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	var records []dns.RR
+
+	for _, rec := range set.ResourceRecords {
+		rr, err := parseRecord(set, *rec.Value)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rr)
+	}
+
+	return records, nil
+}
+
+// parseRecord returns the DNS record with the given value within a record set.
+func parseRecord(set types.ResourceRecordSet, value string) (dns.RR, error) {
+	var ttl int64
+	if set.TTL != nil {
+		ttl = *set.TTL
+	}
+
+	rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", decimalEscapes(*set.Name), ttl, set.Type, value))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s record: %w", set.Type, err)
+	}
+
+	return rr, nil
+}
+
+// octalEscape matches the escape sequences that Route 53 uses for special
+// characters within DNS names.
+var octalEscape = regexp.MustCompile(`\\[0-7]{3}`)
+
+// decimalEscapes returns name with Route 53's octal escape sequences replaced
+// with the decimal escape sequences used by zone files.
+//
+// See https://docs.aws.amazon.com/Route53/latest/DeveloperGuide/DomainNameFormat.html.
+func decimalEscapes(name string) string {
+	return octalEscape.ReplaceAllStringFunc(
+		name,
+		func(esc string) string {
+			n, _ := strconv.ParseUint(esc[1:], 8, 8)
+			return fmt.Sprintf("\\%03d", n)
+		},
+	)
+}
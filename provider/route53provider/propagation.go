@@ -0,0 +1,63 @@
+package route53provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/dogmatiq/proclaim/provider"
+)
+
+// IsPropagated returns true if the change with the given ID has propagated to
+// all of the Route 53 name servers.
+func (a *advertiser) IsPropagated(ctx context.Context, id string) (bool, error) {
+	out, err := a.Client.GetChange(
+		ctx,
+		&route53.GetChangeInput{
+			Id: aws.String(id),
+		},
+	)
+	if err != nil {
+		// Route 53 only retains the status of recent changes, so a change
+		// that no longer exists has long since propagated.
+		var notFound *types.NoSuchChange
+		if errors.As(err, &notFound) {
+			return true, nil
+		}
+
+		return false, fmt.Errorf("unable to get change: %w", err)
+	}
+
+	return out.ChangeInfo.Status == types.ChangeStatusInsync, nil
+}
+
+// trackChanges adds middleware to a Route 53 client's stack that records the
+// ID of the change made by each ChangeResourceRecordSets request using
+// provider.RecordChangeID().
+func trackChanges(s *middleware.Stack) error {
+	return s.Initialize.Add(
+		middleware.InitializeMiddlewareFunc(
+			"proclaim:track-changes",
+			func(
+				ctx context.Context,
+				in middleware.InitializeInput,
+				next middleware.InitializeHandler,
+			) (middleware.InitializeOutput, middleware.Metadata, error) {
+				out, md, err := next.HandleInitialize(ctx, in)
+
+				if o, ok := out.Result.(*route53.ChangeResourceRecordSetsOutput); ok && err == nil {
+					if o.ChangeInfo != nil && o.ChangeInfo.Status != types.ChangeStatusInsync {
+						provider.RecordChangeID(ctx, aws.ToString(o.ChangeInfo.Id))
+					}
+				}
+
+				return out, md, err
+			},
+		),
+		middleware.Before,
+	)
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
-	route53advertiser "github.com/dogmatiq/dissolve/dnssd/advertiser/route53"
 	"github.com/dogmatiq/proclaim/provider"
+	"golang.org/x/time/rate"
 )
 
 const defaultPartition = "aws"
@@ -18,6 +20,21 @@ const defaultPartition = "aws"
 type Provider struct {
 	Client      *route53.Client
 	PartitionID string
+
+	// BatchWindow is the amount of time to wait for other changes to the same
+	// hosted zone before applying a change. Changes made within the window are
+	// applied using a single request. If it is zero, changes are applied
+	// immediately.
+	BatchWindow time.Duration
+
+	// RateLimit is the maximum number of requests per second made to the
+	// Route 53 API, shared by all of the provider's reads and writes. If it is
+	// zero, defaultRateLimit is used.
+	RateLimit float64
+
+	clientsOnce sync.Once
+	limited     *route53.Client
+	wrapped     *route53.Client
 }
 
 // ID returns a short unique identifier for the provider.
@@ -46,7 +63,7 @@ func (p *Provider) AdvertiserByID(
 		return nil, err
 	}
 
-	if _, err := p.Client.GetHostedZone(
+	if _, err := p.client().GetHostedZone(
 		ctx,
 		&route53.GetHostedZoneInput{
 			Id: aws.String(zoneID),
@@ -56,10 +73,8 @@ func (p *Provider) AdvertiserByID(
 	}
 
 	return &advertiser{
-		&route53advertiser.Advertiser{
-			Client: p.Client,
-		},
-		zoneID,
+		Client: p.advertiserClient(),
+		ZoneID: zoneID,
 	}, nil
 }
 
@@ -73,7 +88,7 @@ func (p *Provider) AdvertiserByDomain(
 ) (provider.Advertiser, bool, error) {
 	domain += "."
 
-	out, err := p.Client.ListHostedZonesByName(
+	out, err := p.client().ListHostedZonesByName(
 		ctx,
 		&route53.ListHostedZonesByNameInput{
 			DNSName:  aws.String(domain),
@@ -95,13 +110,65 @@ func (p *Provider) AdvertiserByDomain(
 	}
 
 	return &advertiser{
-		&route53advertiser.Advertiser{
-			Client: p.Client,
-		},
-		*zone.Id,
+		Client: p.advertiserClient(),
+		ZoneID: *zone.Id,
 	}, true, nil
 }
 
+// client returns the client used to make requests to Route 53 on behalf of
+// the provider itself, which is subject to the provider's rate limit.
+func (p *Provider) client() *route53.Client {
+	p.initClients()
+	return p.limited
+}
+
+// advertiserClient returns the client used by advertisers to read and modify
+// DNS records.
+//
+// It shares the provider's rate limit, records the ID of each change so that
+// its propagation can be tracked, and coalesces changes made within the batch
+// window, if any.
+func (p *Provider) advertiserClient() *route53.Client {
+	p.initClients()
+	return p.wrapped
+}
+
+// initClients builds the clients returned by client() and advertiserClient().
+func (p *Provider) initClients() {
+	p.clientsOnce.Do(func() {
+		limit := p.RateLimit
+		if limit <= 0 {
+			limit = defaultRateLimit
+		}
+
+		l := rate.NewLimiter(rate.Limit(limit), max(1, int(limit)))
+
+		p.limited = route53.New(
+			p.Client.Options(),
+			func(o *route53.Options) {
+				o.APIOptions = append(o.APIOptions, rateLimit(l))
+			},
+		)
+
+		p.wrapped = route53.New(
+			p.limited.Options(),
+			func(o *route53.Options) {
+				if p.BatchWindow > 0 {
+					b := &batcher{
+						Client: p.limited,
+						Window: p.BatchWindow,
+					}
+					o.APIOptions = append(o.APIOptions, b.Install)
+				}
+
+				// The change tracking middleware is added last so that it
+				// observes the result of any batched request.
+				o.APIOptions = append(o.APIOptions, trackChanges)
+			},
+		)
+	})
+}
+
 func (p *Provider) partitionID() string {
 	if p.PartitionID == "" {
 		return defaultPartition
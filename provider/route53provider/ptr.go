@@ -0,0 +1,178 @@
+package route53provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"golang.org/x/exp/slices"
+)
+
+// ptrTTL is the TTL of the PTR records that enumerate service sub-types,
+// service types and browsing domains.
+//
+// With Route 53 the only way to return an unlimited number of PTR records with
+// the same name is to put them in the same "record set", which means they all
+// share a TTL.
+const ptrTTL = 30 * time.Second
+
+// findRecordSet returns the record set with the given name and type.
+func (a *advertiser) findRecordSet(
+	ctx context.Context,
+	name string,
+	recordType types.RRType,
+) (types.ResourceRecordSet, bool, error) {
+	out, err := a.Client.ListResourceRecordSets(
+		ctx,
+		&route53.ListResourceRecordSetsInput{
+			HostedZoneId:    aws.String(a.ZoneID),
+			StartRecordName: aws.String(name),
+			StartRecordType: recordType,
+			MaxItems:        aws.Int32(1),
+		},
+	)
+	if err != nil {
+		return types.ResourceRecordSet{}, false, fmt.Errorf("unable to list resource record sets: %w", err)
+	}
+
+	if len(out.ResourceRecordSets) == 0 {
+		return types.ResourceRecordSet{}, false, nil
+	}
+
+	set := out.ResourceRecordSets[0]
+
+	if !strings.EqualFold(*set.Name, name) || set.Type != recordType {
+		return types.ResourceRecordSet{}, false, nil
+	}
+
+	return set, true, nil
+}
+
+// syncPTRValue adds changes to cs that add value to the PTR record set with
+// the given name if present is true, or remove it otherwise.
+func (a *advertiser) syncPTRValue(
+	ctx context.Context,
+	name, value string,
+	present bool,
+	cs *types.ChangeBatch,
+) error {
+	current, ok, err := a.findRecordSet(ctx, name, types.RRTypePtr)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		if present {
+			createPTRSet(name, value, cs)
+		}
+		return nil
+	}
+
+	index := indexOf(current, value)
+
+	if present && index == -1 {
+		return replacePTRSet(
+			current,
+			append(
+				slices.Clone(current.ResourceRecords),
+				types.ResourceRecord{Value: aws.String(value)},
+			),
+			cs,
+		)
+	}
+
+	if !present && index != -1 {
+		return replacePTRSet(
+			current,
+			slices.Delete(
+				slices.Clone(current.ResourceRecords),
+				index,
+				index+1,
+			),
+			cs,
+		)
+	}
+
+	return nil
+}
+
+// createPTRSet adds a change to cs that creates a new PTR record set with the
+// given name containing a single value.
+func createPTRSet(
+	name, value string,
+	cs *types.ChangeBatch,
+) {
+	cs.Changes = append(
+		cs.Changes,
+		types.Change{
+			Action: types.ChangeActionCreate,
+			ResourceRecordSet: &types.ResourceRecordSet{
+				SetIdentifier: marshalGeneration(0),
+				Weight:        aws.Int64(0),
+				Type:          types.RRTypePtr,
+				Name:          aws.String(name),
+				TTL:           aws.Int64(int64(ptrTTL.Seconds())),
+				ResourceRecords: []types.ResourceRecord{
+					{Value: aws.String(value)},
+				},
+			},
+		},
+	)
+}
+
+// replacePTRSet adds changes to cs that replace the PTR record set in current
+// with a record set of the next generation containing the given records.
+//
+// If records is empty the record set is deleted entirely.
+func replacePTRSet(
+	current types.ResourceRecordSet,
+	records []types.ResourceRecord,
+	cs *types.ChangeBatch,
+) error {
+	gen, err := unmarshalGeneration(current.SetIdentifier)
+	if err != nil {
+		return err
+	}
+
+	cs.Changes = append(
+		cs.Changes,
+		types.Change{
+			Action:            types.ChangeActionDelete,
+			ResourceRecordSet: &current,
+		},
+	)
+
+	if len(records) != 0 {
+		cs.Changes = append(
+			cs.Changes,
+			types.Change{
+				Action: types.ChangeActionCreate,
+				ResourceRecordSet: &types.ResourceRecordSet{
+					SetIdentifier:   marshalGeneration(gen + 1),
+					Weight:          aws.Int64(0),
+					Type:            types.RRTypePtr,
+					Name:            current.Name,
+					TTL:             aws.Int64(int64(ptrTTL.Seconds())),
+					ResourceRecords: records,
+				},
+			},
+		)
+	}
+
+	return nil
+}
+
+// indexOf returns the index of the given value in a PTR resource record set,
+// or -1 if it is not present.
+func indexOf(set types.ResourceRecordSet, value string) int {
+	for i, rec := range set.ResourceRecords {
+		if strings.EqualFold(*rec.Value, value) {
+			return i
+		}
+	}
+	return -1
+}
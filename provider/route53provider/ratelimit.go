@@ -0,0 +1,43 @@
+package route53provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/smithy-go/middleware"
+	"golang.org/x/time/rate"
+)
+
+// defaultRateLimit is the maximum number of requests per second made to the
+// Route 53 API if the provider does not specify a limit.
+//
+// Route 53 permits five requests per second per AWS account.
+const defaultRateLimit = 5
+
+// rateLimit returns a function that adds middleware to a Route 53 client's
+// stack that waits for l before each request is sent, including each retry.
+//
+// Every client that shares l, such as the one used to read and write the
+// advertisers' records and the one used to find hosted zones, is limited to
+// the same overall rate.
+func rateLimit(l *rate.Limiter) func(*middleware.Stack) error {
+	return func(s *middleware.Stack) error {
+		return s.Finalize.Add(
+			middleware.FinalizeMiddlewareFunc(
+				"proclaim:rate-limit",
+				func(
+					ctx context.Context,
+					in middleware.FinalizeInput,
+					next middleware.FinalizeHandler,
+				) (middleware.FinalizeOutput, middleware.Metadata, error) {
+					if err := l.Wait(ctx); err != nil {
+						return middleware.FinalizeOutput{}, middleware.Metadata{}, fmt.Errorf("unable to wait for rate limit: %w", err)
+					}
+
+					return next.HandleFinalize(ctx, in)
+				},
+			),
+			middleware.After,
+		)
+	}
+}
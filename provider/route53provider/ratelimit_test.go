@@ -0,0 +1,75 @@
+package route53provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go/middleware"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimit(t *testing.T) {
+	// send applies the rate limiting middleware to a request that is counted,
+	// but never sent.
+	send := func(ctx context.Context, l *rate.Limiter, count *int) error {
+		s := middleware.NewStack("test", func() any { return nil })
+		if err := rateLimit(l)(s); err != nil {
+			t.Fatal(err)
+		}
+
+		h := middleware.DecorateHandler(
+			middleware.HandlerFunc(
+				func(context.Context, any) (any, middleware.Metadata, error) {
+					*count++
+					return nil, middleware.Metadata{}, nil
+				},
+			),
+			s,
+		)
+
+		_, _, err := h.Handle(ctx, nil)
+		return err
+	}
+
+	t.Run("it limits the rate at which requests are sent", func(t *testing.T) {
+		l := rate.NewLimiter(rate.Limit(20), 1)
+		count := 0
+		start := time.Now()
+
+		for range 3 {
+			if err := send(context.Background(), l, &count); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if count != 3 {
+			t.Fatalf("unexpected number of requests: got %d, want 3", count)
+		}
+
+		if d := time.Since(start); d < 100*time.Millisecond {
+			t.Fatalf("expected the requests to take at least 100ms, took %s", d)
+		}
+	})
+
+	t.Run("it does not send the request if the context's deadline is reached first", func(t *testing.T) {
+		l := rate.NewLimiter(rate.Limit(1), 1)
+		count := 0
+
+		if err := send(context.Background(), l, &count); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		err := send(ctx, l, &count)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+
+		if count != 1 {
+			t.Fatalf("unexpected number of requests: got %d, want 1", count)
+		}
+	})
+}
@@ -0,0 +1,336 @@
+package route53provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/miekg/dns"
+	"golang.org/x/exp/slices"
+)
+
+// WithRoutingPolicy returns an advertiser that advertises DNS-SD service
+// instances using the given routing policy.
+func (a *advertiser) WithRoutingPolicy(p provider.RoutingPolicy) (provider.Advertiser, error) {
+	if p.SetIdentifier == "" {
+		return nil, errors.New("routing policy must have a set identifier")
+	}
+
+	n := 0
+	if p.Weight != nil {
+		n++
+	}
+	if p.Region != "" {
+		n++
+	}
+	if p.Geolocation != nil {
+		n++
+	}
+	if p.Failover != "" {
+		n++
+	}
+
+	if n != 1 {
+		return nil, errors.New("routing policy must specify exactly one of weight, region, geolocation or failover")
+	}
+
+	switch types.ResourceRecordSetFailover(p.Failover) {
+	case "", types.ResourceRecordSetFailoverPrimary, types.ResourceRecordSetFailoverSecondary:
+	default:
+		return nil, fmt.Errorf("unsupported failover role %q", p.Failover)
+	}
+
+	if p.HealthCheck != nil {
+		if err := validateHealthCheck(*p.HealthCheck); err != nil {
+			return nil, err
+		}
+	}
+
+	x := *a
+	x.Policy = &p
+
+	return &x, nil
+}
+
+// syncRecordSet adds changes to cs that make the advertiser's record set with
+// the given name and type contain exactly the records in desired, associated
+// with the health check that has the given ID, if any.
+//
+// Route 53 does not allow a simple record set to coexist with record sets that
+// use a routing policy, nor does it allow the type of an existing record set's
+// routing policy to be changed. Any such record sets are deleted before the
+// desired record set is created, within the same change batch. Health checks
+// that are no longer used by any record set are returned by
+// orphanedHealthChecks().
+//
+// If desired is empty the record set is deleted.
+func (a *advertiser) syncRecordSet(
+	ctx context.Context,
+	name string,
+	recordType types.RRType,
+	ttl time.Duration,
+	healthCheckID string,
+	desired []dns.RR,
+	cs *types.ChangeBatch,
+) error {
+	sets, err := a.findRecordSets(ctx, name, recordType)
+	if err != nil {
+		return err
+	}
+
+	var (
+		current     types.ResourceRecordSet
+		ok          bool
+		conflicting []types.ResourceRecordSet
+	)
+
+	for _, set := range sets {
+		if aws.ToString(set.SetIdentifier) == a.setIdentifier() {
+			current, ok = set, true
+		} else if a.Policy == nil || set.SetIdentifier == nil {
+			conflicting = append(conflicting, set)
+		}
+	}
+
+	if len(desired) == 0 {
+		if ok {
+			cs.Changes = append(
+				cs.Changes,
+				types.Change{
+					Action:            types.ChangeActionDelete,
+					ResourceRecordSet: &current,
+				},
+			)
+		}
+		return nil
+	}
+
+	set := types.ResourceRecordSet{
+		Name: aws.String(name),
+		Type: recordType,
+		TTL:  aws.Int64(int64(ttl.Seconds())),
+	}
+
+	for _, rr := range desired {
+		set.ResourceRecords = append(
+			set.ResourceRecords,
+			types.ResourceRecord{
+				Value: aws.String(strings.TrimPrefix(rr.String(), rr.Header().String())),
+			},
+		)
+	}
+
+	a.route(&set)
+	set.HealthCheckId = optionalString(healthCheckID)
+
+	if ok && sameRecordSet(current, set) && len(conflicting) == 0 {
+		return nil
+	}
+
+	if ok && routingType(current) != routingType(set) {
+		conflicting = append(conflicting, current)
+		ok = false
+	}
+
+	for _, c := range conflicting {
+		cs.Changes = append(
+			cs.Changes,
+			types.Change{
+				Action:            types.ChangeActionDelete,
+				ResourceRecordSet: &c,
+			},
+		)
+	}
+
+	action := types.ChangeActionUpsert
+	if !ok {
+		action = types.ChangeActionCreate
+	}
+
+	cs.Changes = append(
+		cs.Changes,
+		types.Change{
+			Action:            action,
+			ResourceRecordSet: &set,
+		},
+	)
+
+	return nil
+}
+
+// orphanedHealthChecks returns the IDs of the health checks that are
+// associated with record sets deleted by cs, but not with any record set that
+// it creates or updates.
+func orphanedHealthChecks(cs *types.ChangeBatch) []string {
+	var deleted, used []string
+
+	for _, c := range cs.Changes {
+		id := aws.ToString(c.ResourceRecordSet.HealthCheckId)
+		if id == "" {
+			continue
+		}
+
+		if c.Action == types.ChangeActionDelete {
+			deleted = append(deleted, id)
+		} else {
+			used = append(used, id)
+		}
+	}
+
+	var orphaned []string
+	for _, id := range deleted {
+		if !slices.Contains(used, id) && !slices.Contains(orphaned, id) {
+			orphaned = append(orphaned, id)
+		}
+	}
+
+	return orphaned
+}
+
+// routingType returns the type of the routing policy used by set, or "simple"
+// if it does not use a routing policy.
+func routingType(set types.ResourceRecordSet) string {
+	switch {
+	case set.Weight != nil:
+		return "weighted"
+	case set.Region != "":
+		return "latency"
+	case set.GeoLocation != nil:
+		return "geolocation"
+	case set.Failover != "":
+		return "failover"
+	default:
+		return "simple"
+	}
+}
+
+// route applies the advertiser's routing policy, if any, to set.
+func (a *advertiser) route(set *types.ResourceRecordSet) {
+	p := a.Policy
+	if p == nil {
+		return
+	}
+
+	set.SetIdentifier = aws.String(p.SetIdentifier)
+	set.Weight = p.Weight
+
+	if p.Region != "" {
+		set.Region = types.ResourceRecordSetRegion(p.Region)
+	}
+
+	if g := p.Geolocation; g != nil {
+		set.GeoLocation = &types.GeoLocation{
+			ContinentCode:   optionalString(g.Continent),
+			CountryCode:     optionalString(g.Country),
+			SubdivisionCode: optionalString(g.Subdivision),
+		}
+	}
+
+	if p.Failover != "" {
+		set.Failover = types.ResourceRecordSetFailover(p.Failover)
+	}
+}
+
+// setIdentifier returns the set identifier of the advertiser's record sets, or
+// an empty string if it uses simple record sets.
+func (a *advertiser) setIdentifier() string {
+	if a.Policy == nil {
+		return ""
+	}
+	return a.Policy.SetIdentifier
+}
+
+// findOwnRecordSet returns the record set with the given name and type that
+// belongs to the advertiser.
+//
+// If the advertiser has a routing policy, the record set is identified by the
+// policy's set identifier.
+func (a *advertiser) findOwnRecordSet(
+	ctx context.Context,
+	name string,
+	recordType types.RRType,
+) (types.ResourceRecordSet, bool, error) {
+	if a.Policy == nil {
+		return a.findRecordSet(ctx, name, recordType)
+	}
+
+	sets, err := a.findRecordSets(ctx, name, recordType)
+	if err != nil {
+		return types.ResourceRecordSet{}, false, err
+	}
+
+	for _, set := range sets {
+		if aws.ToString(set.SetIdentifier) == a.Policy.SetIdentifier {
+			return set, true, nil
+		}
+	}
+
+	return types.ResourceRecordSet{}, false, nil
+}
+
+// findRecordSets returns all of the record sets with the given name and type,
+// regardless of their set identifier.
+func (a *advertiser) findRecordSets(
+	ctx context.Context,
+	name string,
+	recordType types.RRType,
+) ([]types.ResourceRecordSet, error) {
+	in := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(a.ZoneID),
+		StartRecordName: aws.String(name),
+		StartRecordType: recordType,
+	}
+
+	var sets []types.ResourceRecordSet
+
+	for {
+		out, err := a.Client.ListResourceRecordSets(ctx, in)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list resource record sets: %w", err)
+		}
+
+		for _, set := range out.ResourceRecordSets {
+			if !strings.EqualFold(*set.Name, name) || set.Type != recordType {
+				return sets, nil
+			}
+
+			sets = append(sets, set)
+		}
+
+		if !out.IsTruncated {
+			return sets, nil
+		}
+
+		in.StartRecordName = out.NextRecordName
+		in.StartRecordType = out.NextRecordType
+		in.StartRecordIdentifier = out.NextRecordIdentifier
+	}
+}
+
+// sameRecordSet returns true if a and b have the same values, TTL and routing
+// policy.
+func sameRecordSet(a, b types.ResourceRecordSet) bool {
+	return aws.ToInt64(a.TTL) == aws.ToInt64(b.TTL) &&
+		sameValues(a.ResourceRecords, b.ResourceRecords) &&
+		aws.ToString(a.SetIdentifier) == aws.ToString(b.SetIdentifier) &&
+		reflect.DeepEqual(a.Weight, b.Weight) &&
+		a.Region == b.Region &&
+		a.Failover == b.Failover &&
+		reflect.DeepEqual(a.GeoLocation, b.GeoLocation) &&
+		aws.ToString(a.HealthCheckId) == aws.ToString(b.HealthCheckId)
+}
+
+// optionalString returns a pointer to s, or nil if s is empty.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
@@ -0,0 +1,248 @@
+package route53provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/provider"
+)
+
+func TestAdvertiser_WithRoutingPolicy(t *testing.T) {
+	inst := dnssd.ServiceInstance{
+		ServiceInstanceName: dnssd.ServiceInstanceName{
+			Name:        "Instance A",
+			ServiceType: "_http._tcp",
+			Domain:      "example.org",
+		},
+		TargetHost: "host.example.org",
+		TargetPort: 8080,
+		TTL:        60 * time.Second,
+	}
+
+	weighted := func(setID string) provider.RoutingPolicy {
+		weight := int64(10)
+		return provider.RoutingPolicy{
+			SetIdentifier: setID,
+			Weight:        &weight,
+		}
+	}
+
+	// withPolicy returns an advertiser that uses the given routing policy.
+	withPolicy := func(t *testing.T, a *advertiser, p provider.RoutingPolicy) *advertiser {
+		t.Helper()
+
+		x, err := a.WithRoutingPolicy(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return x.(*advertiser)
+	}
+
+	advertise := func(t *testing.T, a *advertiser) {
+		t.Helper()
+
+		if _, err := a.Advertise(context.Background(), inst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	unadvertise := func(t *testing.T, a *advertiser) {
+		t.Helper()
+
+		if _, err := a.Unadvertise(context.Background(), inst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// expectSets verifies that the instance's SRV and TXT record sets use the
+	// given set identifiers, in order. An empty identifier refers to a simple
+	// record set.
+	expectSets := func(t *testing.T, client *fakeRoute53, setIDs ...string) {
+		t.Helper()
+
+		for _, rt := range []types.RRType{types.RRTypeSrv, types.RRTypeTxt} {
+			sets := client.recordSets(inst.Absolute(), rt)
+
+			var got []string
+			for _, set := range sets {
+				got = append(got, aws.ToString(set.SetIdentifier))
+			}
+
+			if len(got) != len(setIDs) {
+				t.Fatalf("unexpected %s record sets: got %q, want %q", rt, got, setIDs)
+			}
+
+			for i := range got {
+				if got[i] != setIDs[i] {
+					t.Fatalf("unexpected %s record sets: got %q, want %q", rt, got, setIDs)
+				}
+			}
+		}
+	}
+
+	setup := func() (*advertiser, *fakeRoute53) {
+		client := &fakeRoute53{}
+		return &advertiser{Client: client, ZoneID: "Z123"}, client
+	}
+
+	t.Run("it rejects invalid routing policies", func(t *testing.T) {
+		a, _ := setup()
+		weight := int64(10)
+
+		cases := []struct {
+			Name   string
+			Policy provider.RoutingPolicy
+		}{
+			{
+				"missing set identifier",
+				provider.RoutingPolicy{Weight: &weight},
+			},
+			{
+				"no routing policy",
+				provider.RoutingPolicy{SetIdentifier: "cluster-a"},
+			},
+			{
+				"several routing policies",
+				provider.RoutingPolicy{SetIdentifier: "cluster-a", Weight: &weight, Region: "us-east-1"},
+			},
+			{
+				"unsupported failover role",
+				provider.RoutingPolicy{SetIdentifier: "cluster-a", Failover: "TERTIARY"},
+			},
+		}
+
+		for _, c := range cases {
+			t.Run(c.Name, func(t *testing.T) {
+				if _, err := a.WithRoutingPolicy(c.Policy); err == nil {
+					t.Fatal("expected an error")
+				}
+			})
+		}
+	})
+
+	t.Run("it advertises the instance using record sets that coexist with those of other set identifiers", func(t *testing.T) {
+		a, client := setup()
+
+		advertise(t, withPolicy(t, a, weighted("cluster-a")))
+		advertise(t, withPolicy(t, a, weighted("cluster-b")))
+
+		expectSets(t, client, "cluster-a", "cluster-b")
+
+		srv := client.recordSets(inst.Absolute(), types.RRTypeSrv)[0]
+		if got := aws.ToInt64(srv.Weight); got != 10 {
+			t.Fatalf("unexpected weight: got %d, want 10", got)
+		}
+	})
+
+	t.Run("it keeps the PTR records while another set identifier still advertises the instance", func(t *testing.T) {
+		a, client := setup()
+		clusterA := withPolicy(t, a, weighted("cluster-a"))
+		clusterB := withPolicy(t, a, weighted("cluster-b"))
+
+		advertise(t, clusterA)
+		advertise(t, clusterB)
+
+		unadvertise(t, clusterA)
+		expectSets(t, client, "cluster-b")
+
+		if _, ok := client.recordSet("_http._tcp.example.org.", types.RRTypePtr); !ok {
+			t.Fatal("expected the PTR record set to remain")
+		}
+
+		unadvertise(t, clusterB)
+		expectSets(t, client)
+
+		if _, ok := client.recordSet("_http._tcp.example.org.", types.RRTypePtr); ok {
+			t.Fatal("did not expect a PTR record set")
+		}
+	})
+
+	t.Run("it replaces the record sets in a single request when a routing policy is added or removed", func(t *testing.T) {
+		a, client := setup()
+
+		advertise(t, a)
+		expectSets(t, client, "")
+
+		before := client.requests()
+		advertise(t, withPolicy(t, a, weighted("cluster-a")))
+		expectSets(t, client, "cluster-a")
+
+		if n := client.requests() - before; n != 1 {
+			t.Fatalf("unexpected number of requests: got %d, want 1", n)
+		}
+
+		before = client.requests()
+		advertise(t, a)
+		expectSets(t, client, "")
+
+		if n := client.requests() - before; n != 1 {
+			t.Fatalf("unexpected number of requests: got %d, want 1", n)
+		}
+	})
+
+	t.Run("it replaces the record sets of another set identifier when advertising without a routing policy", func(t *testing.T) {
+		a, client := setup()
+
+		advertise(t, withPolicy(t, a, weighted("cluster-a")))
+		advertise(t, withPolicy(t, a, weighted("cluster-b")))
+
+		advertise(t, a)
+		expectSets(t, client, "")
+	})
+
+	t.Run("it replaces the record sets when the type of routing policy changes", func(t *testing.T) {
+		a, client := setup()
+
+		advertise(t, withPolicy(t, a, weighted("cluster-a")))
+		advertise(
+			t,
+			withPolicy(t, a, provider.RoutingPolicy{
+				SetIdentifier: "cluster-a",
+				Region:        "us-east-1",
+			}),
+		)
+
+		expectSets(t, client, "cluster-a")
+
+		srv := client.recordSets(inst.Absolute(), types.RRTypeSrv)[0]
+		if routingType(srv) != "latency" {
+			t.Fatalf("unexpected routing policy type: got %q, want %q", routingType(srv), "latency")
+		}
+	})
+
+	t.Run("it updates the record sets in place when the routing policy changes without changing type", func(t *testing.T) {
+		a, client := setup()
+
+		advertise(t, withPolicy(t, a, weighted("cluster-a")))
+
+		p := weighted("cluster-a")
+		*p.Weight = 20
+		x := withPolicy(t, a, p)
+
+		changed, err := x.Advertise(context.Background(), inst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !changed {
+			t.Fatal("expected a change to be reported")
+		}
+
+		srv := client.recordSets(inst.Absolute(), types.RRTypeSrv)[0]
+		if got := aws.ToInt64(srv.Weight); got != 20 {
+			t.Fatalf("unexpected weight: got %d, want 20", got)
+		}
+
+		changed, err = x.Advertise(context.Background(), inst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed {
+			t.Fatal("did not expect a change to be reported")
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package provider
+
+// RoutingPolicy determines how a DNS provider chooses between several sets of
+// DNS records with the same name and type, such as those of an instance that
+// is advertised by several clusters.
+//
+// Exactly one of Weight, Region, Geolocation or Failover must be set.
+type RoutingPolicy struct {
+	// SetIdentifier distinguishes the records from other records with the
+	// same name and type.
+	SetIdentifier string
+
+	// Weight, if non-nil, selects records in proportion to their weight
+	// relative to the total weight of all records with the same name and type.
+	Weight *int64
+
+	// Region, if non-empty, selects the records associated with the region
+	// that has the lowest latency to the client.
+	Region string
+
+	// Geolocation, if non-nil, selects records based on the location of the
+	// client.
+	Geolocation *Geolocation
+
+	// Failover, if non-empty, is either "PRIMARY" or "SECONDARY". Secondary
+	// records are only used when the primary records are unhealthy.
+	Failover string
+
+	// HealthCheck, if non-nil, is the health check that determines whether
+	// the records are returned.
+	HealthCheck *HealthCheck
+}
+
+// HealthCheck describes a health check that is associated with the records of
+// a service instance.
+//
+// If ID is empty, the advertiser creates a health check of the instance's
+// target host and port, and deletes it when the instance is unadvertised.
+type HealthCheck struct {
+	ID               string
+	Protocol         string
+	Path             string
+	FailureThreshold int32
+}
+
+// Geolocation describes the location of the clients to which records are
+// returned by a geolocation routing policy.
+type Geolocation struct {
+	Continent   string
+	Country     string
+	Subdivision string
+}
+
+// RoutingPolicyAdvertiser is an optional interface for advertisers that
+// support routing policies.
+type RoutingPolicyAdvertiser interface {
+	// WithRoutingPolicy returns an advertiser that advertises DNS-SD service
+	// instances using the given routing policy.
+	//
+	// The records of an instance that is advertised using a routing policy
+	// coexist with other records with the same name that use the same type of
+	// routing policy, but a different set identifier.
+	WithRoutingPolicy(p RoutingPolicy) (Advertiser, error)
+}
+
+// AsRoutingPolicyAdvertiser returns a as a RoutingPolicyAdvertiser, if it
+// supports routing policies.
+func AsRoutingPolicyAdvertiser(a Advertiser) (RoutingPolicyAdvertiser, bool) {
+	if r, ok := a.(*restrictedAdvertiser); ok {
+		return r.routing()
+	}
+	ra, ok := a.(RoutingPolicyAdvertiser)
+	return ra, ok
+}
@@ -0,0 +1,89 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/miekg/dns"
+	"golang.org/x/exp/slices"
+)
+
+// unadvertiseStaleAddresses removes the address records of hosts that were
+// previously advertised for the given instance, but are no longer required by
+// it or by any other instance.
+//
+// The records that were previously advertised are taken from the instance's
+// status.
+//
+// It does nothing if the advertiser does not manage address records.
+//
+// It returns true if any changes to DNS records were made.
+func (r *Reconciler) unadvertiseStaleAddresses(
+	ctx context.Context,
+	a provider.Advertiser,
+	res *crd.DNSSDServiceInstance,
+	keep string,
+) (bool, error) {
+	aa, ok := provider.AsAddressAdvertiser(a)
+	if !ok {
+		return false, nil
+	}
+
+	var stale []string
+
+	for _, rec := range res.Status.Records {
+		if rec.Type != "A" && rec.Type != "AAAA" {
+			continue
+		}
+
+		host := strings.ToLower(rec.Name)
+		if host != keep && !slices.Contains(stale, host) {
+			stale = append(stale, host)
+		}
+	}
+
+	if len(stale) == 0 {
+		return false, nil
+	}
+
+	peers, err := r.peers(ctx, res)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+
+	for _, host := range stale {
+		if slices.ContainsFunc(
+			peers,
+			func(peer crd.DNSSDServiceInstance) bool {
+				return managedHost(&peer) == host
+			},
+		) {
+			continue
+		}
+
+		ok, err := aa.UnadvertiseAddresses(ctx, host)
+		if err != nil {
+			return false, fmt.Errorf("unable to unadvertise addresses of %q: %w", host, err)
+		}
+		changed = changed || ok
+	}
+
+	return changed, nil
+}
+
+// managedHost returns the fully-qualified name of the target host whose
+// address records are managed on behalf of the given instance.
+//
+// It returns an empty string if the instance does not specify any addresses.
+func managedHost(res *crd.DNSSDServiceInstance) string {
+	if len(res.Spec.Instance.Targets[0].Addresses) == 0 {
+		return ""
+	}
+
+	return strings.ToLower(dns.Fqdn(res.Spec.ToDissolve().TargetHost))
+}
@@ -2,6 +2,8 @@ package reconciler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/dogmatiq/proclaim/crd"
 	"github.com/dogmatiq/proclaim/provider"
@@ -13,10 +15,17 @@ func (r *Reconciler) getOrAssociateAdvertiser(
 	ctx context.Context,
 	res *crd.DNSSDServiceInstance,
 ) (provider.Advertiser, bool, error) {
-	if res.Status.Provider != "" {
-		return r.getAdvertiser(ctx, res)
+	if res.Status.Provider == "" {
+		return r.associateAdvertiser(ctx, res)
 	}
-	return r.associateAdvertiser(ctx, res)
+
+	a, ok, err := r.getAdvertiser(ctx, res)
+	if ok || err != nil {
+		return a, ok, err
+	}
+
+	a, ok, _, err = r.missingProvider(ctx, res)
+	return a, ok, err
 }
 
 // associateAdvertiser finds the appropriate advertiser for the given DNS-SD
@@ -26,35 +35,18 @@ func (r *Reconciler) associateAdvertiser(
 	ctx context.Context,
 	res *crd.DNSSDServiceInstance,
 ) (provider.Advertiser, bool, error) {
-	exhaustive := true
-
-	for _, p := range r.Providers {
-		a, ok, err := p.AdvertiserByDomain(ctx, res.Spec.Instance.Domain)
-		if err != nil {
-			crd.ProviderError(
-				r.Manager,
-				res,
-				p.ID(),
-				p.Describe(),
-				err,
-			)
-
-			exhaustive = false
-
-			if ctx.Err() != nil {
-				return nil, false, ctx.Err()
-			}
-		}
-
-		if !ok {
-			continue
-		}
+	p, a, denied, exhaustive, err := r.candidateAdvertiser(ctx, res)
+	if err != nil {
+		return nil, false, err
+	}
 
+	if p != nil {
 		if err := r.update(
 			res,
 			crd.MergeCondition(crd.InstanceAdoptedCondition()),
 			crd.UpdateProviderDescription(p.Describe()),
 			crd.AssociateProvider(p.ID(), a.ID()),
+			crd.AssociateController(r.ControllerName),
 		); err != nil {
 			return nil, false, err
 		}
@@ -64,7 +56,16 @@ func (r *Reconciler) associateAdvertiser(
 		return a, true, nil
 	}
 
-	if exhaustive {
+	if exhaustive && len(denied) != 0 {
+		crd.InstanceDenied(r.Manager, res, denied)
+
+		if err := r.update(
+			res,
+			crd.MergeCondition(crd.InstanceDeniedCondition(denied)),
+		); err != nil {
+			return nil, false, err
+		}
+	} else if exhaustive {
 		crd.InstanceIgnored(r.Manager, res)
 
 		if err := r.update(
@@ -78,24 +79,115 @@ func (r *Reconciler) associateAdvertiser(
 	return nil, false, nil
 }
 
+// candidateAdvertiser returns the first of the available providers that can
+// advertise the given DNS-SD service instance, and the advertiser it would use,
+// without associating it with the resource. The provider is nil if there is no
+// such provider.
+//
+// denied describes the providers that are not permitted to advertise on the
+// instance's domain. exhaustive is false if any of the providers could not be
+// queried.
+func (r *Reconciler) candidateAdvertiser(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) (_ provider.Provider, _ provider.Advertiser, denied []string, exhaustive bool, _ error) {
+	exhaustive = true
+
+	for _, p := range r.providers() {
+		// Check the provider's domain policy first, so that a provider is
+		// never asked about a domain that it is not permitted to advertise on.
+		if rp, ok := p.(provider.Restricted); ok {
+			if err := rp.DomainPolicy().Check(res.Spec.Instance.Domain); err != nil {
+				denied = append(denied, fmt.Sprintf("%s: %s", p.Describe(), err))
+				continue
+			}
+		}
+
+		a, ok, err := p.AdvertiserByDomain(ctx, res.Spec.Instance.Domain)
+		if err != nil {
+			crd.ProviderError(
+				r.Manager,
+				res,
+				p.ID(),
+				p.Describe(),
+				err,
+			)
+
+			exhaustive = false
+
+			if ctx.Err() != nil {
+				return nil, nil, nil, false, ctx.Err()
+			}
+		}
+
+		if !ok {
+			continue
+		}
+
+		a, ok, err = withRoutingPolicy(a, res)
+		if err != nil {
+			crd.ProviderError(
+				r.Manager,
+				res,
+				p.ID(),
+				p.Describe(),
+				err,
+			)
+
+			exhaustive = false
+			continue
+		}
+
+		if !ok {
+			// The provider can not advertise the instance's routing policy,
+			// so it is not considered to manage the domain.
+			continue
+		}
+
+		return p, a, nil, true, nil
+	}
+
+	return nil, nil, denied, exhaustive, nil
+}
+
+// getAdvertiser returns the advertiser that the given DNS-SD service instance
+// is associated with.
+//
+// ok is false if the instance's provider is not configured, in which case the
+// caller may apply r.MissingProviderPolicy. If the provider is configured but
+// the advertiser can not be obtained, an error is returned instead.
 func (r *Reconciler) getAdvertiser(
 	ctx context.Context,
 	res *crd.DNSSDServiceInstance,
 ) (provider.Advertiser, bool, error) {
-	for _, p := range r.Providers {
+	for _, p := range r.providers() {
 		if p.ID() != res.Status.Provider {
 			continue
 		}
 
-		// Make sure the provider's description is up-to-date.
+		// Make sure the provider's description is up-to-date, and that
+		// instances adopted by a version that did not record the controller
+		// are recognized as belonging to this controller.
 		if err := r.update(
 			res,
 			crd.UpdateProviderDescription(p.Describe()),
+			crd.If(
+				res.Status.Controller == "",
+				crd.AssociateController(r.ControllerName),
+			),
 		); err != nil {
 			return nil, false, err
 		}
 
 		a, err := p.AdvertiserByID(ctx, res.Status.Advertiser)
+		if err == nil {
+			var ok bool
+			a, ok, err = withRoutingPolicy(a, res)
+			if err == nil && !ok {
+				err = errors.New("the provider does not support routing policies")
+			}
+		}
+
 		if err != nil {
 			crd.ProviderError(
 				r.Manager,
@@ -104,12 +196,25 @@ func (r *Reconciler) getAdvertiser(
 				p.Describe(),
 				err,
 			)
-			return nil, false, ctx.Err()
+
+			// The provider is configured, but failing. The error is returned
+			// so that the instance is reconciled again, rather than being
+			// treated as though the provider is missing.
+			return nil, false, fmt.Errorf("unable to get advertiser from %s: %w", p.Describe(), err)
 		}
 
 		return a, true, nil
 	}
 
+	// If the provider is built from a DNSProvider resource that exists, but has
+	// not been loaded yet, retry later rather than treating it as unknown.
+	if ok, err := r.isPendingProvider(ctx, res.Status.Provider); ok || err != nil {
+		if err == nil {
+			err = fmt.Errorf("provider %q has not been loaded", res.Status.Provider)
+		}
+		return nil, false, err
+	}
+
 	// This reconciler does not know about the provider that is associated with
 	// the resource. This is likely because the resource is managed by some
 	// other instance of Proclaim.
@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/dogmatiq/dissolve/dnssd"
 	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/miekg/dns"
+	"golang.org/x/exp/slices"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -20,7 +24,9 @@ func (r *Reconciler) advertise(
 	ctx context.Context,
 	res *crd.DNSSDServiceInstance,
 ) (reconcile.Result, error) {
-	if controllerutil.AddFinalizer(res, crd.FinalizerName) {
+	// In dry-run mode the records are never removed, so the finalizer is not
+	// required.
+	if !r.DryRun && controllerutil.AddFinalizer(res, crd.FinalizerName) {
 		if err := r.Client.Update(ctx, res); err != nil {
 			return reconcile.Result{}, fmt.Errorf("unable to add finalizer: %w", err)
 		}
@@ -33,30 +39,116 @@ func (r *Reconciler) advertise(
 		)
 	}
 
-	if r.shouldAdvertise(res) {
-		if err := r.doAdvertise(ctx, res); err != nil {
+	winner, err := r.conflictWinner(ctx, res)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if winner != nil {
+		// There is no point retrying until the instance that takes precedence
+		// is deleted, or can no longer be advertised, at which point this
+		// instance is re-queued by the watch.
+		crd.InstanceConflict(r.Manager, res, winner)
+		return reconcile.Result{}, r.update(
+			res,
+			crd.MergeCondition(crd.InstanceConflictCondition(winner)),
+		)
+	}
+
+	if result, forbidden, err := r.enforcePolicies(ctx, res); forbidden || err != nil {
+		return result, err
+	}
+
+	if a := res.Condition(crd.ConditionTypeAdopted); (a.Reason == crd.ReasonConflict || a.Reason == crd.ReasonForbidden) && res.Status.Provider != "" {
+		// The conflict has been resolved, or the instance is permitted again,
+		// and the instance was already associated with a provider before.
+		if err := r.update(
+			res,
+			crd.MergeCondition(crd.InstanceAdoptedCondition()),
+		); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	spec, err := r.resolveSpec(ctx, res)
+	if err != nil {
+		if !isAttributeSourceError(err) {
+			return reconcile.Result{}, fmt.Errorf("unable to resolve attributes: %w", err)
+		}
+
+		// There is no point retrying until the referenced ConfigMap or
+		// Secret changes, at which point the instance is re-queued by the
+		// watch.
+		crd.AttributeSourceError(r.Manager, res, err)
+		return reconcile.Result{}, r.update(
+			res,
+			crd.MergeCondition(crd.AttributeSourceErrorCondition(err)),
+		)
+	}
+
+	if invalid := spec.InvalidAddresses(); len(invalid) != 0 {
+		// There is no point retrying until the instance's spec changes, at
+		// which point it is re-queued by the watch.
+		crd.InvalidAddresses(r.Manager, res, invalid)
+		return reconcile.Result{}, r.update(
+			res,
+			crd.MergeCondition(crd.InvalidAddressesCondition(invalid)),
+		)
+	}
+
+	if r.DryRun {
+		return r.plan(ctx, res, spec)
+	}
+
+	if result, migrating, err := r.migrate(ctx, res, spec); migrating || err != nil {
+		return result, err
+	}
+
+	if result, propagating, err := r.waitForPropagation(ctx, res); propagating || err != nil {
+		return result, err
+	}
+
+	if r.shouldAdvertise(res, spec) {
+		if err := r.doAdvertise(ctx, res, spec); err != nil {
 			return reconcile.Result{}, err
 		}
 	}
 
 	if r.shouldDiscover(res) {
-		ttl, err := r.doDiscover(ctx, res)
+		ttl, err := r.doDiscover(ctx, res, spec)
 		return r.requeueResult(res, ttl), err
 	}
 
+	if !r.isDiscoveryEnabled(res) {
+		if err := r.update(
+			res,
+			crd.MergeCondition(crd.DiscoveryDisabledCondition()),
+		); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
 	return r.requeueResult(res, 0), nil
 }
 
 func (r *Reconciler) doAdvertise(
 	ctx context.Context,
 	res *crd.DNSSDServiceInstance,
+	spec crd.DNSSDServiceInstanceSpec,
 ) error {
 	a, ok, err := r.getOrAssociateAdvertiser(ctx, res)
 	if !ok || err != nil {
 		return err
 	}
 
-	changed, err := a.Advertise(ctx, res.Spec.ToDissolve())
+	ctx, tracked := provider.TrackChanges(ctx)
+	changed, err := r.advertiseWith(ctx, a, res, spec)
+	propagating := tracked.IDs()
+
+	var records []dns.RR
+	if err == nil {
+		records, err = advertisedRecords(ctx, a, spec, changed)
+	}
 
 	advertised := res.Condition(crd.ConditionTypeAdvertised)
 
@@ -69,6 +161,9 @@ func (r *Reconciler) doAdvertise(
 			err,
 		)
 		advertised = crd.AdvertiseErrorCondition(err)
+	} else if changed && len(propagating) != 0 {
+		crd.DNSRecordsUpdated(r.Manager, res)
+		advertised = crd.DNSRecordsPropagatingCondition()
 	} else if changed {
 		crd.DNSRecordsUpdated(r.Manager, res)
 		advertised = crd.DNSRecordsUpdatedCondition()
@@ -82,10 +177,97 @@ func (r *Reconciler) doAdvertise(
 	return r.update(
 		res,
 		crd.MergeCondition(advertised),
+		crd.If(
+			err == nil,
+			crd.RecordsAdvertised(records),
+			crd.UpdatePropagatingChanges(propagating),
+		),
 	)
 }
 
-func (r *Reconciler) shouldAdvertise(res *crd.DNSSDServiceInstance) bool {
+// advertisedRecords returns the records of the given service instance that the
+// advertiser reports as present after advertising it.
+//
+// changed is true if advertising the instance made any changes. If it is false,
+// all of the desired records were already present. Otherwise, records that the
+// advertiser still plans to create, such as those that it rejected or
+// transformed, are excluded, so that they are advertised again. If the
+// advertiser can not plan changes, all of the desired records are assumed to
+// be present.
+func advertisedRecords(
+	ctx context.Context,
+	a provider.Advertiser,
+	spec crd.DNSSDServiceInstanceSpec,
+	changed bool,
+) ([]dns.RR, error) {
+	inst := spec.ToDissolve()
+	options := spec.AdvertiseOptions()
+
+	if !changed {
+		return dnssd.NewRecords(inst, options...), nil
+	}
+
+	p, ok := provider.AsPlanner(a)
+	if !ok {
+		return dnssd.NewRecords(inst, options...), nil
+	}
+
+	changes, err := p.Plan(ctx, inst, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []dns.RR
+
+	for _, rr := range dnssd.NewRecords(inst, options...) {
+		if !slices.ContainsFunc(
+			changes,
+			func(c provider.Change) bool {
+				return c.Action == provider.Create && dns.IsDuplicate(c.Record, rr)
+			},
+		) {
+			records = append(records, rr)
+		}
+	}
+
+	return records, nil
+}
+
+// advertiseWith adds/updates the DNS records of the given service instance
+// using a specific advertiser.
+//
+// It returns true if any changes to DNS records were made.
+func (r *Reconciler) advertiseWith(
+	ctx context.Context,
+	a provider.Advertiser,
+	res *crd.DNSSDServiceInstance,
+	spec crd.DNSSDServiceInstanceSpec,
+) (bool, error) {
+	inst := spec.ToDissolve()
+
+	changed, err := a.Advertise(ctx, inst, spec.AdvertiseOptions()...)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := r.unadvertiseStaleAddresses(ctx, a, res, managedHost(res))
+	if err != nil {
+		return false, err
+	}
+	changed = changed || ok
+
+	ok, err = r.advertiseEnumeration(ctx, a, inst)
+	if err != nil {
+		return false, err
+	}
+
+	return changed || ok, nil
+}
+
+func (r *Reconciler) shouldAdvertise(
+	res *crd.DNSSDServiceInstance,
+	spec crd.DNSSDServiceInstanceSpec,
+) bool {
 	a := res.Condition(crd.ConditionTypeAdvertised)
 	d := res.Condition(crd.ConditionTypeDiscoverable)
 
@@ -98,6 +280,23 @@ func (r *Reconciler) shouldAdvertise(res *crd.DNSSDServiceInstance) bool {
 	} else if a.ObservedGeneration < res.Generation {
 		should = true
 		reason = "resource updated since last advertised"
+	} else if len(res.Status.Records) != 0 && !res.HasRecords(dnssd.NewRecords(spec.ToDissolve(), spec.AdvertiseOptions()...)) {
+		// The desired records can change without the resource itself being
+		// modified, for example when an attribute refers to a ConfigMap.
+		//
+		// If the records in the status are unknown, such as after upgrading
+		// from a version that did not record them, drift detection determines
+		// whether the instance needs to be re-advertised.
+		should = true
+		reason = "records changed since last advertised"
+	} else if !r.isDiscoveryEnabled(res) {
+		// Without discovery the only way to detect drift is to ask the
+		// provider to verify the records.
+		should = true
+		reason = "drift detection (discovery disabled)"
+	} else if d.Reason == crd.ReasonLookupResultStale {
+		should = false
+		reason = "discovered records are potentially cached"
 	} else if d.Status != metav1.ConditionTrue {
 		should = true
 		reason = "not discoverable"
@@ -134,6 +333,12 @@ func (r *Reconciler) shouldDiscover(res *crd.DNSSDServiceInstance) bool {
 	} else if a.ObservedGeneration < res.Generation {
 		should = false
 		reason = "resource updated since last advertised"
+	} else if a.Reason == crd.ReasonPropagating {
+		should = false
+		reason = "changes are propagating"
+	} else if !r.isDiscoveryEnabled(res) {
+		should = false
+		reason = "discovery disabled"
 	} else if d.Status != metav1.ConditionTrue {
 		should = true
 		reason = "not discoverable"
@@ -171,9 +376,12 @@ func (r *Reconciler) requeueResult(
 		reason = "not advertised"
 	} else if a.ObservedGeneration < res.Generation {
 		reason = "resource updated since last advertised"
-	} else if d.Status == metav1.ConditionTrue {
+	} else if a.Reason == crd.ReasonPropagating {
+		reason = "changes are propagating"
+		delay = propagationPollInterval
+	} else if !r.isDiscoveryEnabled(res) || d.Status == metav1.ConditionTrue {
 		reason = "drift detection"
-		delay = 10 * res.Spec.Instance.TTL.Duration
+		delay = r.driftDetectionInterval(res)
 	} else if discoveredTTL == 0 {
 		// We have no TTL information from actual DNS records, so we compute
 		// something based on the TTL.
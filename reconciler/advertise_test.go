@@ -0,0 +1,88 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/miekg/dns"
+)
+
+func TestAdvertisedRecords(t *testing.T) {
+	spec := crd.DNSSDServiceInstanceSpec{
+		Instance: crd.Instance{
+			Name:        "Instance",
+			ServiceType: "_test._tcp",
+			Domain:      "example.org",
+		},
+	}
+
+	desired := dnssd.NewRecords(spec.ToDissolve(), spec.AdvertiseOptions()...)
+
+	t.Run("it does not plan changes if advertising made no changes", func(t *testing.T) {
+		a := &planningAdvertiser{}
+
+		records, err := advertisedRecords(context.Background(), a, spec, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if a.plans != 0 {
+			t.Fatalf("unexpected number of plans: got %d, want 0", a.plans)
+		}
+
+		if len(records) != len(desired) {
+			t.Fatalf("unexpected number of records: got %d, want %d", len(records), len(desired))
+		}
+	})
+
+	t.Run("it excludes the records that the advertiser still plans to create", func(t *testing.T) {
+		a := &planningAdvertiser{
+			changes: []provider.Change{
+				{Action: provider.Create, Record: desired[0]},
+			},
+		}
+
+		records, err := advertisedRecords(context.Background(), a, spec, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if a.plans != 1 {
+			t.Fatalf("unexpected number of plans: got %d, want 1", a.plans)
+		}
+
+		if len(records) != len(desired)-1 {
+			t.Fatalf("unexpected number of records: got %d, want %d", len(records), len(desired)-1)
+		}
+
+		for _, rr := range records {
+			if dns.IsDuplicate(rr, desired[0]) {
+				t.Fatalf("did not expect %s", rr)
+			}
+		}
+	})
+}
+
+// planningAdvertiser is a provider.Planner that always plans the same changes,
+// and records the number of times it has planned them.
+type planningAdvertiser struct {
+	testAdvertiser
+
+	changes []provider.Change
+	plans   int
+}
+
+var _ provider.Planner = (*planningAdvertiser)(nil)
+
+func (a *planningAdvertiser) Plan(context.Context, dnssd.ServiceInstance, ...dnssd.AdvertiseOption) ([]provider.Change, error) {
+	a.plans++
+	return a.changes, nil
+}
+
+func (a *planningAdvertiser) PlanUnadvertise(context.Context, dnssd.ServiceInstance) ([]provider.Change, error) {
+	a.plans++
+	return nil, nil
+}
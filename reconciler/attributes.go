@@ -0,0 +1,216 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dogmatiq/proclaim/crd"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// configMapIndex is the name of the field index that maps each service
+	// instance to the names of the ConfigMaps referenced by its attributes.
+	configMapIndex = "proclaim.dogmatiq.io/attribute-config-maps"
+
+	// secretIndex is the name of the field index that maps each service
+	// instance to the names of the Secrets referenced by its attributes.
+	secretIndex = "proclaim.dogmatiq.io/attribute-secrets"
+)
+
+// attributeSourceError indicates that a ConfigMap or Secret referenced by an
+// attribute, or the referenced key within it, does not exist.
+type attributeSourceError struct {
+	Kind, Name, Key string
+}
+
+func (e attributeSourceError) Error() string {
+	return fmt.Sprintf("%s %q does not contain a %q key", e.Kind, e.Name, e.Key)
+}
+
+// secretNotAllowedError indicates that a Secret referenced by an attribute
+// has not opted in to being used as an attribute source.
+type secretNotAllowedError struct {
+	Name string
+}
+
+func (e secretNotAllowedError) Error() string {
+	return fmt.Sprintf(
+		"Secret %q can not be used as an attribute source unless it has the %s=true label",
+		e.Name,
+		crd.AttributeSourceLabel,
+	)
+}
+
+// secretsDisabledError indicates that an attribute refers to a Secret, but
+// Secrets can not be used as attribute sources.
+type secretsDisabledError struct {
+	Name string
+}
+
+func (e secretsDisabledError) Error() string {
+	return fmt.Sprintf(
+		"Secret %q can not be used as an attribute source because Secret attribute sources are disabled",
+		e.Name,
+	)
+}
+
+// resolveSpec returns a copy of the resource's spec in which attribute values
+// that refer to a ConfigMap or Secret are replaced with the referenced values.
+//
+// It returns an error that wraps an attributeSourceError if any of the
+// required values are missing, or a crd.AttributeValueError if any of the
+// values are invalid.
+func (r *Reconciler) resolveSpec(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) (crd.DNSSDServiceInstanceSpec, error) {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	return res.Spec.ResolveAttributes(
+		func(src crd.AttributeValueSource) (string, bool, error) {
+			if ref := src.ConfigMapKeyRef; ref != nil {
+				obj := &corev1.ConfigMap{}
+				if err := reader.Get(
+					ctx,
+					client.ObjectKey{Namespace: res.Namespace, Name: ref.Name},
+					obj,
+				); client.IgnoreNotFound(err) != nil {
+					return "", false, err
+				}
+
+				if v, ok := obj.Data[ref.Key]; ok {
+					return v, true, nil
+				}
+
+				if v, ok := obj.BinaryData[ref.Key]; ok {
+					return string(v), true, nil
+				}
+
+				return missingAttributeSource(ref.Optional, "ConfigMap", ref.Name, ref.Key)
+			}
+
+			ref := src.SecretKeyRef
+			if !r.EnableSecretAttributeSources {
+				return "", false, secretsDisabledError{ref.Name}
+			}
+
+			obj := &corev1.Secret{}
+			if err := reader.Get(
+				ctx,
+				client.ObjectKey{Namespace: res.Namespace, Name: ref.Name},
+				obj,
+			); err != nil {
+				if apierrors.IsNotFound(err) {
+					return missingAttributeSource(ref.Optional, "Secret", ref.Name, ref.Key)
+				}
+				return "", false, err
+			}
+
+			// The values of a Secret are copied into DNS records, and into the
+			// status and events of the instance, which are readable by anyone
+			// that can read the instance. The Secret must opt in to this.
+			if obj.Labels[crd.AttributeSourceLabel] != "true" {
+				return "", false, secretNotAllowedError{ref.Name}
+			}
+
+			if v, ok := obj.Data[ref.Key]; ok {
+				return string(v), true, nil
+			}
+
+			return missingAttributeSource(ref.Optional, "Secret", ref.Name, ref.Key)
+		},
+	)
+}
+
+// missingAttributeSource returns the result of resolving an attribute value
+// from a ConfigMap or Secret key that does not exist.
+func missingAttributeSource(optional *bool, kind, name, key string) (string, bool, error) {
+	if optional != nil && *optional {
+		return "", false, nil
+	}
+	return "", false, attributeSourceError{kind, name, key}
+}
+
+// isAttributeSourceError returns true if err indicates that an attribute value
+// is invalid, or that a value referenced by an attribute does not exist or may
+// not be used.
+func isAttributeSourceError(err error) bool {
+	var (
+		e  attributeSourceError
+		ne secretNotAllowedError
+		de secretsDisabledError
+		ve crd.AttributeValueError
+	)
+	return errors.As(err, &e) ||
+		errors.As(err, &ne) ||
+		errors.As(err, &de) ||
+		errors.As(err, &ve) ||
+		apierrors.IsNotFound(err)
+}
+
+// indexAttributeSources returns a field indexer function that returns the
+// names of the ConfigMaps or Secrets referenced by a service instance's
+// attributes.
+func indexAttributeSources(kind string) client.IndexerFunc {
+	return func(obj client.Object) []string {
+		res := obj.(*crd.DNSSDServiceInstance)
+
+		var names []string
+		for _, src := range res.Spec.AttributeValueSources() {
+			switch {
+			case kind == configMapIndex && src.ConfigMapKeyRef != nil:
+				names = append(names, src.ConfigMapKeyRef.Name)
+			case kind == secretIndex && src.SecretKeyRef != nil:
+				names = append(names, src.SecretKeyRef.Name)
+			}
+		}
+
+		return names
+	}
+}
+
+// enqueueReferencingInstances returns an event handler that enqueues a
+// reconcile request for each service instance that references the changed
+// object, according to the given field index.
+func (r *Reconciler) enqueueReferencingInstances(index string) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(
+		func(ctx context.Context, obj client.Object) []reconcile.Request {
+			list := &crd.DNSSDServiceInstanceList{}
+			if err := r.Client.List(
+				ctx,
+				list,
+				client.InNamespace(obj.GetNamespace()),
+				client.MatchingFields{index: obj.GetName()},
+			); err != nil {
+				r.Logger.Error(
+					err,
+					"unable to list service instances that reference attribute source",
+					"namespace", obj.GetNamespace(),
+					"name", obj.GetName(),
+				)
+				return nil
+			}
+
+			var requests []reconcile.Request
+			for _, res := range list.Items {
+				requests = append(
+					requests,
+					reconcile.Request{
+						NamespacedName: client.ObjectKeyFromObject(&res),
+					},
+				)
+			}
+
+			return requests
+		},
+	)
+}
@@ -0,0 +1,180 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/crd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// instanceNameIndex is the name of the field index that maps each service
+// instance to its fully-qualified DNS-SD instance name.
+const instanceNameIndex = "proclaim.dogmatiq.io/instance-name"
+
+// indexInstanceName is a client.IndexerFunc that returns the fully-qualified
+// instance name of a service instance. DNS names are case-insensitive, so the
+// name is lowercased.
+func indexInstanceName(obj client.Object) []string {
+	res := obj.(*crd.DNSSDServiceInstance)
+
+	return []string{
+		strings.ToLower(
+			dnssd.AbsoluteServiceInstanceName(
+				res.Spec.Instance.Name,
+				res.Spec.Instance.ServiceType,
+				res.Spec.Instance.Domain,
+			),
+		),
+	}
+}
+
+// conflictWinner returns the service instance that takes precedence over res
+// because it has the same fully-qualified instance name, or nil if there is
+// no such instance.
+//
+// The oldest instance takes precedence. Instances that are being deleted, or
+// that can not be advertised, are disregarded.
+func (r *Reconciler) conflictWinner(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) (*crd.DNSSDServiceInstance, error) {
+	list := &crd.DNSSDServiceInstanceList{}
+	if err := r.instanceReader().List(
+		ctx,
+		list,
+		client.MatchingFields{
+			instanceNameIndex: indexInstanceName(res)[0],
+		},
+	); err != nil {
+		return nil, fmt.Errorf("unable to list instances with the same name: %w", err)
+	}
+
+	var winner *crd.DNSSDServiceInstance
+
+	for i := range list.Items {
+		x := &list.Items[i]
+
+		if x.UID == res.UID || x.DeletionTimestamp != nil || !canBeAdvertised(x) || canCoexist(x, res) {
+			continue
+		}
+
+		if takesPrecedence(x, res) && (winner == nil || takesPrecedence(x, winner)) {
+			winner = x
+		}
+	}
+
+	return winner, nil
+}
+
+// instanceReader returns the reader used to find the service instances that
+// may conflict with, or share enumeration records with, a managed instance.
+//
+// If the reconciler is scoped, its cache does not contain the instances
+// outside of its scope, so a separate cache of every instance in the cluster
+// is used instead.
+func (r *Reconciler) instanceReader() client.Reader {
+	if r.instances != nil {
+		return r.instances
+	}
+	return r.Client
+}
+
+// canBeAdvertised returns false if res can not be advertised in its current
+// state, because it is forbidden by a DNSSDPolicy or the domain policies of
+// the providers do not permit its domain.
+//
+// Such an instance does not take precedence over any other instance with the
+// same fully-qualified name.
+func canBeAdvertised(res *crd.DNSSDServiceInstance) bool {
+	switch res.Condition(crd.ConditionTypeAdopted).Reason {
+	case crd.ReasonForbidden, crd.ReasonDomainDenied:
+		return false
+	default:
+		return true
+	}
+}
+
+// canCoexist returns true if a and b can both be advertised despite having the
+// same fully-qualified instance name, because they are advertised using
+// routing policies with different set identifiers.
+func canCoexist(a, b *crd.DNSSDServiceInstance) bool {
+	return a.Spec.Routing != nil &&
+		b.Spec.Routing != nil &&
+		a.Spec.Routing.SetIdentifier != b.Spec.Routing.SetIdentifier
+}
+
+// takesPrecedence returns true if a takes precedence over b when they have
+// the same fully-qualified instance name.
+func takesPrecedence(a, b *crd.DNSSDServiceInstance) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+
+	return a.Name < b.Name
+}
+
+// enqueueConflictingInstances returns an event handler that enqueues a
+// reconcile request for each service instance with the same fully-qualified
+// instance name as the changed instance, so that the next oldest instance is
+// advertised when the current one is deleted or can no longer be advertised.
+func (r *Reconciler) enqueueConflictingInstances() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(
+		func(ctx context.Context, obj client.Object) []reconcile.Request {
+			list := &crd.DNSSDServiceInstanceList{}
+			if err := r.Client.List(
+				ctx,
+				list,
+				client.MatchingFields{
+					instanceNameIndex: indexInstanceName(obj)[0],
+				},
+			); err != nil {
+				r.Logger.Error(err, "unable to list instances with the same name")
+				return nil
+			}
+
+			var requests []reconcile.Request
+			for _, x := range list.Items {
+				if x.UID != obj.GetUID() {
+					requests = append(
+						requests,
+						reconcile.Request{
+							NamespacedName: client.ObjectKeyFromObject(&x),
+						},
+					)
+				}
+			}
+
+			return requests
+		},
+	)
+}
+
+// conflictPredicate is the predicate used to filter the events passed to
+// enqueueConflictingInstances().
+//
+// Status updates, which are frequent, are ignored, unless they change whether
+// the instance can be advertised.
+var conflictPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		prev := e.ObjectOld.(*crd.DNSSDServiceInstance)
+		next := e.ObjectNew.(*crd.DNSSDServiceInstance)
+
+		return prev.Generation != next.Generation ||
+			prev.DeletionTimestamp.IsZero() != next.DeletionTimestamp.IsZero() ||
+			canBeAdvertised(prev) != canBeAdvertised(next)
+	},
+	GenericFunc: func(event.GenericEvent) bool {
+		return false
+	},
+}
@@ -0,0 +1,196 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dogmatiq/proclaim/crd"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTakesPrecedence(t *testing.T) {
+	older := metav1.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	newer := metav1.NewTime(older.Add(time.Second))
+
+	instance := func(ns, name string, created metav1.Time) *crd.DNSSDServiceInstance {
+		return &crd.DNSSDServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         ns,
+				Name:              name,
+				CreationTimestamp: created,
+			},
+		}
+	}
+
+	cases := []struct {
+		Name string
+		A, B *crd.DNSSDServiceInstance
+		Want bool
+	}{
+		{
+			Name: "older instance takes precedence",
+			A:    instance("z", "z", older),
+			B:    instance("a", "a", newer),
+			Want: true,
+		},
+		{
+			Name: "newer instance does not take precedence",
+			A:    instance("a", "a", newer),
+			B:    instance("z", "z", older),
+			Want: false,
+		},
+		{
+			Name: "same creation time, lower namespace takes precedence",
+			A:    instance("a", "z", older),
+			B:    instance("b", "a", older),
+			Want: true,
+		},
+		{
+			Name: "same creation time, higher namespace does not take precedence",
+			A:    instance("b", "a", older),
+			B:    instance("a", "z", older),
+			Want: false,
+		},
+		{
+			Name: "same creation time and namespace, lower name takes precedence",
+			A:    instance("a", "a", older),
+			B:    instance("a", "b", older),
+			Want: true,
+		},
+		{
+			Name: "same instance does not take precedence over itself",
+			A:    instance("a", "a", older),
+			B:    instance("a", "a", older),
+			Want: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			if got := takesPrecedence(c.A, c.B); got != c.Want {
+				t.Fatalf("unexpected result: got %t, want %t", got, c.Want)
+			}
+		})
+	}
+}
+
+func TestCanCoexist(t *testing.T) {
+	instance := func(routing *crd.RoutingPolicy) *crd.DNSSDServiceInstance {
+		return &crd.DNSSDServiceInstance{
+			Spec: crd.DNSSDServiceInstanceSpec{
+				Routing: routing,
+			},
+		}
+	}
+
+	cases := []struct {
+		Name string
+		A, B *crd.DNSSDServiceInstance
+		Want bool
+	}{
+		{
+			Name: "neither has a routing policy",
+			A:    instance(nil),
+			B:    instance(nil),
+			Want: false,
+		},
+		{
+			Name: "only one has a routing policy",
+			A:    instance(&crd.RoutingPolicy{SetIdentifier: "a"}),
+			B:    instance(nil),
+			Want: false,
+		},
+		{
+			Name: "same set identifier",
+			A:    instance(&crd.RoutingPolicy{SetIdentifier: "a"}),
+			B:    instance(&crd.RoutingPolicy{SetIdentifier: "a"}),
+			Want: false,
+		},
+		{
+			Name: "different set identifiers",
+			A:    instance(&crd.RoutingPolicy{SetIdentifier: "a"}),
+			B:    instance(&crd.RoutingPolicy{SetIdentifier: "b"}),
+			Want: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			if got := canCoexist(c.A, c.B); got != c.Want {
+				t.Fatalf("unexpected result: got %t, want %t", got, c.Want)
+			}
+			if got := canCoexist(c.B, c.A); got != c.Want {
+				t.Fatalf("unexpected result with arguments reversed: got %t, want %t", got, c.Want)
+			}
+		})
+	}
+}
+
+func TestReconciler_conflictWinner(t *testing.T) {
+	setup := func(t *testing.T, conditions ...metav1.Condition) (*Reconciler, *crd.DNSSDServiceInstance) {
+		t.Helper()
+
+		cli, res := setupTestInstance(t)
+
+		// The fake client does not record creation times, so the winner is
+		// determined by its namespace, which sorts before "default".
+		x := &crd.DNSSDServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "archive",
+				Name:      "instance",
+				UID:       "<archive>",
+			},
+			Spec: res.Spec,
+		}
+
+		if err := cli.Create(context.Background(), x); err != nil {
+			t.Fatal(err)
+		}
+
+		x.Status.Conditions = conditions
+		if err := cli.Status().Update(context.Background(), x); err != nil {
+			t.Fatal(err)
+		}
+
+		return &Reconciler{Client: cli}, res
+	}
+
+	t.Run("the oldest instance takes precedence", func(t *testing.T) {
+		r, res := setup(t)
+
+		winner, err := r.conflictWinner(context.Background(), res)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if winner == nil || winner.Namespace != "archive" {
+			t.Fatalf("unexpected winner: got %v, want archive/instance", winner)
+		}
+	})
+
+	t.Run("instances that can not be advertised do not take precedence", func(t *testing.T) {
+		cases := []struct {
+			Name      string
+			Condition metav1.Condition
+		}{
+			{"forbidden", crd.InstanceForbiddenCondition("<reason>")},
+			{"domain denied", crd.InstanceDeniedCondition([]string{"<reason>"})},
+		}
+
+		for _, c := range cases {
+			t.Run(c.Name, func(t *testing.T) {
+				r, res := setup(t, c.Condition)
+
+				winner, err := r.conflictWinner(context.Background(), res)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if winner != nil {
+					t.Fatalf("did not expect a winner, got %s/%s", winner.Namespace, winner.Name)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package reconciler
+
+import (
+	"context"
+
+	"github.com/dogmatiq/proclaim/crd"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// SetupWithManager registers a controller that uses r to reconcile
+// crd.DNSSDServiceInstance resources with r.Manager.
+func (r *Reconciler) SetupWithManager(ctx context.Context) error {
+	indexer := r.Manager.GetFieldIndexer()
+
+	for _, index := range []string{configMapIndex, secretIndex} {
+		if err := indexer.IndexField(
+			ctx,
+			&crd.DNSSDServiceInstance{},
+			index,
+			indexAttributeSources(index),
+		); err != nil {
+			return err
+		}
+	}
+
+	if err := indexer.IndexField(
+		ctx,
+		&crd.DNSSDServiceInstance{},
+		instanceNameIndex,
+		indexInstanceName,
+	); err != nil {
+		return err
+	}
+
+	if r.Scoped {
+		if err := r.setupInstanceCache(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := r.Manager.Add(healthMonitor{r}); err != nil {
+		return err
+	}
+
+	if r.BuildProvider != nil {
+		if err := r.setupProviderController(); err != nil {
+			return err
+		}
+	}
+
+	b := builder.
+		ControllerManagedBy(r.Manager).
+		For(
+			&crd.DNSSDServiceInstance{},
+			builder.WithPredicates(
+				// Annotations are not part of the generation, but are used
+				// to request migrations.
+				predicate.Or(
+					predicate.GenerationChangedPredicate{},
+					predicate.AnnotationChangedPredicate{},
+				),
+				predicate.NewPredicateFuncs(
+					func(obj client.Object) bool {
+						return r.isResponsible(obj.(*crd.DNSSDServiceInstance))
+					},
+				),
+			),
+		).
+		// Only the metadata of ConfigMaps and Secrets is watched, so that their
+		// content is not cached. The referenced values are read directly from
+		// the API server when they are needed.
+		WatchesMetadata(
+			&corev1.ConfigMap{},
+			r.enqueueReferencingInstances(configMapIndex),
+		)
+
+	// Instances with the same fully-qualified name are re-queued when one of
+	// them is created, marked for deletion, deleted, has its spec changed or
+	// becomes (un)advertisable, so that a conflict is resolved as soon as the
+	// instance that takes precedence is no longer a candidate.
+	//
+	// If the reconciler is scoped, the instance that takes precedence may be
+	// outside of its scope, so the changes are watched using the cache of
+	// every instance in the cluster.
+	if r.instances != nil {
+		b = b.WatchesRawSource(
+			source.Kind[client.Object](
+				r.instances,
+				&crd.DNSSDServiceInstance{},
+				r.enqueueConflictingInstances(),
+				conflictPredicate,
+			),
+		)
+	} else {
+		b = b.Watches(
+			&crd.DNSSDServiceInstance{},
+			r.enqueueConflictingInstances(),
+			builder.WithPredicates(conflictPredicate),
+		)
+	}
+
+	// Instances are re-queued when the policies that may apply to them change,
+	// or when the labels of their namespace change, so that they are
+	// advertised or unadvertised as soon as they are permitted or forbidden.
+	//
+	// The DNSSDPolicy CRD may not be installed, in which case there are no
+	// policies to watch.
+	if ok, err := r.isInstalled(&crd.DNSSDPolicy{}); err != nil {
+		return err
+	} else if ok {
+		b = b.
+			Watches(
+				&crd.DNSSDPolicy{},
+				handler.EnqueueRequestsFromMapFunc(r.instancesAffectedByPolicy),
+				builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+			).
+			Watches(
+				&corev1.Namespace{},
+				handler.EnqueueRequestsFromMapFunc(r.instancesInNamespace),
+				builder.WithPredicates(predicate.LabelChangedPredicate{}),
+			)
+	}
+
+	// Secrets are only watched if they can be used as attribute sources, so
+	// that the reconciler does not otherwise require permission to list them.
+	if r.EnableSecretAttributeSources {
+		b = b.WatchesMetadata(
+			&corev1.Secret{},
+			r.enqueueReferencingInstances(secretIndex),
+		)
+	}
+
+	return b.Complete(r)
+}
+
+// setupInstanceCache sets up a cache of every service instance in the
+// cluster, regardless of the reconciler's scope, indexed by fully-qualified
+// instance name.
+func (r *Reconciler) setupInstanceCache(ctx context.Context) error {
+	c, err := cache.New(
+		r.Manager.GetConfig(),
+		cache.Options{
+			HTTPClient:                  r.Manager.GetHTTPClient(),
+			Scheme:                      r.Manager.GetScheme(),
+			Mapper:                      r.Manager.GetRESTMapper(),
+			ReaderFailOnMissingInformer: true,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := c.IndexField(
+		ctx,
+		&crd.DNSSDServiceInstance{},
+		instanceNameIndex,
+		indexInstanceName,
+	); err != nil {
+		return err
+	}
+
+	if err := r.Manager.Add(c); err != nil {
+		return err
+	}
+
+	r.instances = c
+
+	return nil
+}
+
+// isInstalled returns true if the API server supports the kind of obj.
+func (r *Reconciler) isInstalled(obj client.Object) (bool, error) {
+	gvk, err := r.Client.GroupVersionKindFor(obj)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := r.Manager.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
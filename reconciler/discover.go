@@ -3,37 +3,65 @@ package reconciler
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dogmatiq/dissolve/dnssd"
 	"github.com/dogmatiq/proclaim/crd"
+	"github.com/miekg/dns"
 	"golang.org/x/exp/slices"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// isDiscoveryEnabled returns true if DNS-SD discovery should be performed for
+// the given service instance.
+func (r *Reconciler) isDiscoveryEnabled(res *crd.DNSSDServiceInstance) bool {
+	if e := res.Spec.Discovery.Enabled; e != nil {
+		return *e
+	}
+	return !r.DisableDiscovery
+}
+
+// driftDetectionInterval returns the interval at which the given service
+// instance is checked for drift once it has been advertised.
+func (r *Reconciler) driftDetectionInterval(res *crd.DNSSDServiceInstance) time.Duration {
+	if d := res.Spec.Discovery.Interval.Duration; d > 0 {
+		return d
+	}
+
+	if r.DriftDetectionInterval > 0 {
+		return r.DriftDetectionInterval
+	}
+
+	return 10 * res.Spec.Instance.TTL.Duration
+}
+
 func (r *Reconciler) doDiscover(
 	ctx context.Context,
 	res *crd.DNSSDServiceInstance,
+	spec crd.DNSSDServiceInstanceSpec,
 ) (time.Duration, error) {
-	ttl, discoverable := r.computeDiscoverable(ctx, res)
+	ttl, discoverable, drift := r.computeDiscoverable(ctx, res, spec)
 	return ttl, r.update(
 		res,
 		crd.MergeCondition(discoverable),
+		crd.UpdateDrift(drift),
 	)
 }
 
 func (r *Reconciler) computeDiscoverable(
 	ctx context.Context,
 	res *crd.DNSSDServiceInstance,
-) (time.Duration, metav1.Condition) {
+	spec crd.DNSSDServiceInstanceSpec,
+) (time.Duration, metav1.Condition, []crd.Difference) {
 	instances, err := r.Resolver.EnumerateInstances(
 		ctx,
 		res.Spec.Instance.ServiceType,
 		res.Spec.Instance.Domain,
 	)
 	if err != nil {
-		return 0, crd.DiscoveryErrorCondition(err)
+		return 0, crd.DiscoveryErrorCondition(err), nil
 	}
 
 	if !slices.ContainsFunc(
@@ -43,7 +71,7 @@ func (r *Reconciler) computeDiscoverable(
 		},
 	) {
 		crd.NegativeBrowseResult(r.Manager, res)
-		return 0, crd.NegativeBrowseResultCondition()
+		return 0, crd.NegativeBrowseResultCondition(), nil
 	}
 
 	observed, ok, err := r.Resolver.LookupInstance(
@@ -54,56 +82,334 @@ func (r *Reconciler) computeDiscoverable(
 	)
 	if err != nil {
 		crd.DiscoveryError(r.Manager, res, err)
-		return 0, crd.DiscoveryErrorCondition(err)
+		return 0, crd.DiscoveryErrorCondition(err), nil
 	}
 	if !ok {
 		crd.NegativeLookupResult(r.Manager, res)
-		return 0, crd.NegativeLookupResultCondition()
+		return 0, crd.NegativeLookupResultCondition(), nil
 	}
 
-	desired := res.Spec.ToDissolve()
+	subtypes, err := r.compareSubtypes(ctx, res)
+	if err != nil {
+		crd.DiscoveryError(r.Manager, res, err)
+		return 0, crd.DiscoveryErrorCondition(err), nil
+	}
+
+	desired := spec.ToDissolve()
+
+	d := res.Condition(crd.ConditionTypeDiscoverable)
+
+	if drift := append(compare(observed, desired), subtypes...); len(drift) != 0 {
+		// If the differences can be explained by DNS caching, wait for the
+		// cached records to expire before treating them as real divergence
+		// that requires the instance to be re-advertised.
+		if isCacheArtifact(res, observed, drift, time.Now()) {
+			crd.LookupResultStale(r.Manager, res, drift)
+			return observed.TTL, crd.LookupResultStaleCondition(drift), drift
+		}
 
-	if drift, ok := compare(observed, desired); !ok {
 		crd.LookupResultOutOfSync(r.Manager, res, drift)
-		return observed.TTL, crd.LookupResultOutOfSyncCondition(drift)
+		return observed.TTL, crd.LookupResultOutOfSyncCondition(drift), drift
 	}
 
-	d := res.Condition(crd.ConditionTypeDiscoverable)
 	if d.Status != metav1.ConditionTrue {
 		crd.Discovered(r.Manager, res)
 	}
-	return observed.TTL, crd.DiscoveredCondition()
+	return observed.TTL, crd.DiscoveredCondition(), nil
 }
 
-// compare returns a (very) brief human-readable description of the differences
-// between the observed and desired service instance records.
-func compare(observed, desired dnssd.ServiceInstance) (string, bool) {
-	if !strings.EqualFold(observed.TargetHost, desired.TargetHost) {
-		return fmt.Sprintf("host %q != %q", observed.TargetHost, desired.TargetHost), false
-	}
+// compareSubtypes returns a difference for each of the service sub-types in
+// the instance's specification that the instance can not be discovered by.
+func (r *Reconciler) compareSubtypes(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) ([]crd.Difference, error) {
+	var diff []crd.Difference
 
-	if observed.TargetPort != desired.TargetPort {
-		return fmt.Sprintf("port %d != %d", observed.TargetPort, desired.TargetPort), false
+	for i, st := range res.Spec.Instance.Subtypes {
+		instances, err := r.Resolver.EnumerateInstancesBySubType(
+			ctx,
+			st,
+			res.Spec.Instance.ServiceType,
+			res.Spec.Instance.Domain,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.ContainsFunc(
+			instances,
+			func(v string) bool {
+				return strings.EqualFold(v, res.Spec.Instance.Name)
+			},
+		) {
+			diff = append(
+				diff,
+				crd.Difference{
+					Field:   fmt.Sprintf("subtypes[%d]", i),
+					Desired: st,
+				},
+			)
+		}
 	}
 
-	if observed.Priority != desired.Priority {
-		return fmt.Sprintf("priority %d != %d", observed.Priority, desired.Priority), false
+	return diff, nil
+}
+
+// compare returns the differences between the observed and desired service
+// instance records.
+//
+// It returns an empty slice if the records are in sync.
+func compare(observed, desired dnssd.ServiceInstance) []crd.Difference {
+	var diff []crd.Difference
+
+	add := func(field, o, d string) {
+		diff = append(
+			diff,
+			crd.Difference{
+				Field:    field,
+				Observed: o,
+				Desired:  d,
+			},
+		)
 	}
 
-	if observed.Weight != desired.Weight {
-		return fmt.Sprintf("weight %d != %d", observed.Weight, desired.Weight), false
+	addUint := func(field string, o, d uint16) {
+		if o != d {
+			add(field, strconv.FormatUint(uint64(o), 10), strconv.FormatUint(uint64(d), 10))
+		}
 	}
 
-	if !observed.Attributes.Equal(desired.Attributes) {
-		return "attribute mismatch", false
+	// The resolver only reports a single SRV record, which always corresponds
+	// to the first (and currently only) target.
+	if !strings.EqualFold(observed.TargetHost, desired.TargetHost) {
+		add("targets[0].host", observed.TargetHost, desired.TargetHost)
 	}
+	addUint("targets[0].port", observed.TargetPort, desired.TargetPort)
+	addUint("targets[0].priority", observed.Priority, desired.Priority)
+	addUint("targets[0].weight", observed.Weight, desired.Weight)
+
+	diff = append(diff, compareAttributes(observed.Attributes, desired.Attributes)...)
 
 	// The TTL of the observed instance may be less than the desired TTL based
 	// on how old the DNS server's cache is. So long as the observed TTL does
 	// not *exceed* the desired TTL, we consider the records to be in sync.
 	if observed.TTL > desired.TTL {
-		return fmt.Sprintf("ttl %d > %d", observed.TTL, desired.TTL), false
+		add("ttl", observed.TTL.String(), desired.TTL.String())
+	}
+
+	return diff
+}
+
+// isCacheArtifact returns true if the given differences between the observed
+// and desired records could be caused by a DNS cache that still holds records
+// from before the instance was last advertised.
+//
+// A difference is a cache artifact if the observed value matches the records
+// that the instance was previously advertised with, and those records may
+// still be cached as of the given time.
+//
+// A TTL that exceeds the desired TTL is expected for some time after the TTL
+// is reduced, even if the previous records are unknown. As the observed TTL
+// bounds how long such records are cached, it is only considered a cache
+// artifact if the previous lookup was not already stale.
+func isCacheArtifact(
+	res *crd.DNSSDServiceInstance,
+	observed dnssd.ServiceInstance,
+	diff []crd.Difference,
+	now time.Time,
+) bool {
+	previous, enumerated, ok := parseInstance(res, res.CachedRecords(now))
+
+	var previousDiff []crd.Difference
+	if ok {
+		previousDiff = compare(observed, previous)
+	}
+
+	stale := res.Condition(crd.ConditionTypeDiscoverable).Reason == crd.ReasonLookupResultStale
+
+	for _, d := range diff {
+		switch {
+		case d.Field == "ttl":
+			if !ok && stale {
+				return false
+			}
+		case strings.HasPrefix(d.Field, "subtypes["):
+			// A missing sub-type can be explained by a cached negative
+			// response if the instance did not previously have the sub-type.
+			if !ok || slices.Contains(
+				enumerated,
+				strings.ToLower(
+					dnssd.AbsoluteSelectiveInstanceEnumerationDomain(
+						d.Desired,
+						res.Spec.Instance.ServiceType,
+						res.Spec.Instance.Domain,
+					),
+				),
+			) {
+				return false
+			}
+		default:
+			if !ok || slices.ContainsFunc(
+				previousDiff,
+				func(x crd.Difference) bool {
+					return x.Field == d.Field
+				},
+			) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// parseInstance returns the service instance described by the given records,
+// and the lowercase names of the PTR records that refer to it.
+//
+// ok is false if the records do not include the instance's SRV record.
+func parseInstance(
+	res *crd.DNSSDServiceInstance,
+	records []crd.Record,
+) (_ dnssd.ServiceInstance, enumerated []string, ok bool) {
+	inst := dnssd.ServiceInstance{
+		ServiceInstanceName: dnssd.ServiceInstanceName{
+			Name:        res.Spec.Instance.Name,
+			ServiceType: res.Spec.Instance.ServiceType,
+			Domain:      res.Spec.Instance.Domain,
+		},
+	}
+
+	name := inst.Absolute()
+
+	for _, r := range records {
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", r.Name, r.TTL, r.Type, r.Value))
+		if err != nil || rr == nil {
+			continue
+		}
+
+		switch rr := rr.(type) {
+		case *dns.SRV:
+			if strings.EqualFold(rr.Hdr.Name, name) {
+				ok = true
+				inst.TargetHost = strings.TrimSuffix(rr.Target, ".")
+				inst.TargetPort = rr.Port
+				inst.Priority = rr.Priority
+				inst.Weight = rr.Weight
+				inst.TTL = time.Duration(rr.Hdr.Ttl) * time.Second
+			}
+		case *dns.TXT:
+			if strings.EqualFold(rr.Hdr.Name, name) {
+				var attrs dnssd.Attributes
+				for _, pair := range rr.Txt {
+					attrs, _, _ = attrs.WithTXT(pair)
+				}
+				if !attrs.IsEmpty() {
+					inst.Attributes = append(inst.Attributes, attrs)
+				}
+			}
+		case *dns.PTR:
+			if strings.EqualFold(rr.Ptr, name) {
+				enumerated = append(enumerated, strings.ToLower(rr.Hdr.Name))
+			}
+		}
+	}
+
+	return inst, enumerated, ok
+}
+
+// compareAttributes returns the differences between the observed and desired
+// attributes, key by key.
+//
+// Each attribute set corresponds to a TXT record, and there is no guarantee
+// that TXT records are discovered in the same order that they are advertised.
+// Therefore, sets that are equal are matched first, regardless of their
+// position, and any remaining sets are compared in order.
+//
+// Each difference refers to the position of the desired set within the spec.
+// Observed sets that are not compared to any desired set are referred to by
+// positions beyond the end of the desired sets.
+func compareAttributes(observed, desired dnssd.AttributeCollection) []crd.Difference {
+	if observed.Equal(desired) {
+		return nil
+	}
+
+	observed = slices.Clone(observed)
+	desired = slices.Clone(desired)
+
+	n := len(desired)
+	positions := make([]int, n)
+	for i := range positions {
+		positions[i] = i
+	}
+
+	for i := 0; i < len(desired); i++ {
+		j := slices.IndexFunc(observed, desired[i].Equal)
+		if j != -1 {
+			observed = slices.Delete(observed, j, j+1)
+			desired = slices.Delete(desired, i, i+1)
+			positions = slices.Delete(positions, i, i+1)
+			i--
+		}
+	}
+
+	var diff []crd.Difference
+
+	for i := 0; i < max(len(observed), len(desired)); i++ {
+		var o, d map[string]string
+
+		pos := n + i - len(positions)
+		if i < len(positions) {
+			pos = positions[i]
+		}
+
+		if i < len(observed) {
+			o = attributeValues(observed[i])
+		}
+		if i < len(desired) {
+			d = attributeValues(desired[i])
+		}
+
+		var keys []string
+		for k := range o {
+			keys = append(keys, k)
+		}
+		for k := range d {
+			if _, ok := o[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			if o[k] != d[k] {
+				diff = append(
+					diff,
+					crd.Difference{
+						Field:    fmt.Sprintf("attributes[%d].%s", pos, k),
+						Observed: o[k],
+						Desired:  d[k],
+					},
+				)
+			}
+		}
+	}
+
+	return diff
+}
+
+// attributeValues returns a map of attribute key to its representation within
+// a TXT record, that is "key=value" for key/value pairs, or "key" for flags.
+func attributeValues(attrs dnssd.Attributes) map[string]string {
+	values := map[string]string{}
+
+	for k, v := range attrs.Pairs() {
+		values[k] = k + "=" + string(v)
+	}
+
+	for k := range attrs.Flags() {
+		values[k] = k
 	}
 
-	return "", true
+	return values
 }
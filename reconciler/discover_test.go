@@ -0,0 +1,307 @@
+package reconciler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/crd"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCompare(t *testing.T) {
+	desired := testInstance()
+
+	cases := []struct {
+		Name     string
+		Observed func(*dnssd.ServiceInstance)
+		Want     []crd.Difference
+	}{
+		{
+			Name:     "in sync",
+			Observed: func(*dnssd.ServiceInstance) {},
+		},
+		{
+			Name: "target host differs only by case",
+			Observed: func(i *dnssd.ServiceInstance) {
+				i.TargetHost = "A.EXAMPLE.ORG"
+			},
+		},
+		{
+			Name: "observed TTL is lower than desired",
+			Observed: func(i *dnssd.ServiceInstance) {
+				i.TTL = 10 * time.Second
+			},
+		},
+		{
+			Name: "observed TTL exceeds desired",
+			Observed: func(i *dnssd.ServiceInstance) {
+				i.TTL = 120 * time.Second
+			},
+			Want: []crd.Difference{
+				{Field: "ttl", Observed: "2m0s", Desired: "1m0s"},
+			},
+		},
+		{
+			Name: "SRV values differ",
+			Observed: func(i *dnssd.ServiceInstance) {
+				i.TargetHost = "b.example.org"
+				i.TargetPort = 4321
+				i.Priority = 1
+				i.Weight = 2
+			},
+			Want: []crd.Difference{
+				{Field: "targets[0].host", Observed: "b.example.org", Desired: "a.example.org"},
+				{Field: "targets[0].port", Observed: "4321", Desired: "1234"},
+				{Field: "targets[0].priority", Observed: "1", Desired: "10"},
+				{Field: "targets[0].weight", Observed: "2", Desired: "20"},
+			},
+		},
+		{
+			Name: "attribute value differs",
+			Observed: func(i *dnssd.ServiceInstance) {
+				i.Attributes = dnssd.AttributeCollection{
+					dnssd.NewAttributes().WithPair("key", []byte("other")),
+				}
+			},
+			Want: []crd.Difference{
+				{Field: "attributes[0].key", Observed: "key=other", Desired: "key=value"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			observed := testInstance()
+			c.Observed(&observed)
+
+			got := compare(observed, desired)
+			if !reflect.DeepEqual(got, c.Want) {
+				t.Fatalf("unexpected differences: got %v, want %v", got, c.Want)
+			}
+		})
+	}
+}
+
+func TestCompareAttributes(t *testing.T) {
+	a := dnssd.NewAttributes().WithPair("a", []byte("1"))
+	b := dnssd.NewAttributes().WithPair("b", []byte("2")).WithFlag("f")
+
+	cases := []struct {
+		Name     string
+		Observed dnssd.AttributeCollection
+		Desired  dnssd.AttributeCollection
+		Want     []crd.Difference
+	}{
+		{
+			Name:     "equal",
+			Observed: dnssd.AttributeCollection{a, b},
+			Desired:  dnssd.AttributeCollection{a, b},
+		},
+		{
+			Name:     "same sets in a different order",
+			Observed: dnssd.AttributeCollection{b, a},
+			Desired:  dnssd.AttributeCollection{a, b},
+		},
+		{
+			Name:     "missing set",
+			Observed: dnssd.AttributeCollection{a},
+			Desired:  dnssd.AttributeCollection{a, b},
+			Want: []crd.Difference{
+				{Field: "attributes[1].b", Desired: "b=2"},
+				{Field: "attributes[1].f", Desired: "f"},
+			},
+		},
+		{
+			Name:     "unexpected set",
+			Observed: dnssd.AttributeCollection{b, a},
+			Desired:  dnssd.AttributeCollection{b},
+			Want: []crd.Difference{
+				{Field: "attributes[1].a", Observed: "a=1"},
+			},
+		},
+		{
+			Name:     "unexpected sets",
+			Observed: dnssd.AttributeCollection{a, b},
+			Desired:  dnssd.AttributeCollection{},
+			Want: []crd.Difference{
+				{Field: "attributes[0].a", Observed: "a=1"},
+				{Field: "attributes[1].b", Observed: "b=2"},
+				{Field: "attributes[1].f", Observed: "f"},
+			},
+		},
+		{
+			Name:     "set differs after a matching set",
+			Observed: dnssd.AttributeCollection{a, dnssd.NewAttributes().WithPair("b", []byte("3")).WithFlag("f")},
+			Desired:  dnssd.AttributeCollection{a, b},
+			Want: []crd.Difference{
+				{Field: "attributes[1].b", Observed: "b=3", Desired: "b=2"},
+			},
+		},
+		{
+			Name:     "flag replaced by pair",
+			Observed: dnssd.AttributeCollection{dnssd.NewAttributes().WithFlag("k")},
+			Desired:  dnssd.AttributeCollection{dnssd.NewAttributes().WithPair("k", []byte("v"))},
+			Want: []crd.Difference{
+				{Field: "attributes[0].k", Observed: "k", Desired: "k=v"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			got := compareAttributes(c.Observed, c.Desired)
+			if !reflect.DeepEqual(got, c.Want) {
+				t.Fatalf("unexpected differences: got %v, want %v", got, c.Want)
+			}
+		})
+	}
+}
+
+func TestIsCacheArtifact(t *testing.T) {
+	now := time.Now()
+
+	previous := testInstance()
+	previous.TargetPort = 80
+
+	desired := testInstance()
+
+	cases := []struct {
+		Name     string
+		Observed func(*dnssd.ServiceInstance)
+		Status   func(*crd.DNSSDServiceInstance)
+		Subtypes []string
+		Want     bool
+	}{
+		{
+			Name: "observed TTL exceeds desired",
+			Observed: func(i *dnssd.ServiceInstance) {
+				i.TTL = 120 * time.Second
+			},
+			Want: true,
+		},
+		{
+			Name: "observed TTL still exceeds desired after waiting for it to expire",
+			Observed: func(i *dnssd.ServiceInstance) {
+				i.TTL = 120 * time.Second
+			},
+			Status: func(res *crd.DNSSDServiceInstance) {
+				res.Status.Conditions = []metav1.Condition{
+					crd.LookupResultStaleCondition(nil),
+				}
+			},
+			Want: false,
+		},
+		{
+			Name: "observed value matches previous records that may still be cached",
+			Observed: func(i *dnssd.ServiceInstance) {
+				i.TargetPort = 80
+			},
+			Status: withPreviousRecords(previous, now.Add(time.Minute)),
+			Want:   true,
+		},
+		{
+			Name: "observed value matches previous records that have expired",
+			Observed: func(i *dnssd.ServiceInstance) {
+				i.TargetPort = 80
+			},
+			Status: withPreviousRecords(previous, now.Add(-time.Minute)),
+			Want:   false,
+		},
+		{
+			Name: "observed value matches neither the previous nor desired records",
+			Observed: func(i *dnssd.ServiceInstance) {
+				i.TargetPort = 8080
+			},
+			Status: withPreviousRecords(previous, now.Add(time.Minute)),
+			Want:   false,
+		},
+		{
+			Name: "observed value differs without previous records",
+			Observed: func(i *dnssd.ServiceInstance) {
+				i.TargetPort = 80
+			},
+			Want: false,
+		},
+		{
+			Name:     "new sub-type is not yet discoverable",
+			Observed: func(*dnssd.ServiceInstance) {},
+			Status:   withPreviousRecords(previous, now.Add(time.Minute)),
+			Subtypes: []string{"_printer"},
+			Want:     true,
+		},
+		{
+			Name:     "previously advertised sub-type is not discoverable",
+			Observed: func(*dnssd.ServiceInstance) {},
+			Status:   withPreviousRecords(previous, now.Add(time.Minute), dnssd.WithServiceSubType("_printer")),
+			Subtypes: []string{"_printer"},
+			Want:     false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			res := &crd.DNSSDServiceInstance{
+				Spec: crd.DNSSDServiceInstanceSpec{
+					Instance: crd.Instance{
+						Name:        desired.Name,
+						ServiceType: desired.ServiceType,
+						Domain:      desired.Domain,
+					},
+				},
+			}
+			if c.Status != nil {
+				c.Status(res)
+			}
+
+			observed := testInstance()
+			c.Observed(&observed)
+
+			diff := compare(observed, desired)
+			for _, st := range c.Subtypes {
+				diff = append(diff, crd.Difference{Field: "subtypes[0]", Desired: st})
+			}
+
+			if got := isCacheArtifact(res, observed, diff, now); got != c.Want {
+				t.Fatalf("unexpected result: got %t, want %t (differences: %v)", got, c.Want, diff)
+			}
+		})
+	}
+}
+
+// testInstance returns a service instance for use in tests.
+func testInstance() dnssd.ServiceInstance {
+	return dnssd.ServiceInstance{
+		ServiceInstanceName: dnssd.ServiceInstanceName{
+			Name:        "Instance A",
+			ServiceType: "_test._tcp",
+			Domain:      "example.org",
+		},
+		TargetHost: "a.example.org",
+		TargetPort: 1234,
+		Priority:   10,
+		Weight:     20,
+		Attributes: dnssd.AttributeCollection{
+			dnssd.NewAttributes().WithPair("key", []byte("value")),
+		},
+		TTL: 60 * time.Second,
+	}
+}
+
+// withPreviousRecords returns a function that sets the previous records of a
+// service instance to those of inst.
+func withPreviousRecords(
+	inst dnssd.ServiceInstance,
+	expire time.Time,
+	options ...dnssd.AdvertiseOption,
+) func(*crd.DNSSDServiceInstance) {
+	return func(res *crd.DNSSDServiceInstance) {
+		for _, rr := range dnssd.NewRecords(inst, options...) {
+			res.Status.PreviousRecords = append(res.Status.PreviousRecords, crd.NewRecord(rr))
+		}
+
+		e := metav1.NewTime(expire)
+		res.Status.PreviousRecordsExpire = &e
+	}
+}
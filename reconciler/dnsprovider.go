@@ -0,0 +1,409 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// providers returns all of the providers available to the reconciler, that
+// is, r.Providers followed by those built from crd.DNSProvider resources.
+func (r *Reconciler) providers() []provider.Provider {
+	r.dynamicM.RLock()
+	defer r.dynamicM.RUnlock()
+
+	providers := slices.Clone(r.Providers)
+
+	names := maps.Keys(r.dynamic)
+	slices.Sort(names)
+
+	for _, n := range names {
+		providers = append(providers, r.dynamic[n])
+	}
+
+	return providers
+}
+
+// registerProvider makes a provider built from the crd.DNSProvider with the
+// given name available to the reconciler, replacing any existing provider
+// built from the same resource.
+func (r *Reconciler) registerProvider(name string, p *dynamicProvider) {
+	r.dynamicM.Lock()
+	defer r.dynamicM.Unlock()
+
+	if _, ok := r.dynamic[name]; !ok {
+		r.Logger.Info(
+			"provider enabled",
+			"id", p.ID(),
+		)
+	}
+
+	if r.dynamic == nil {
+		r.dynamic = map[string]*dynamicProvider{}
+	}
+
+	r.dynamic[name] = p
+}
+
+// dynamicProvider returns the provider built from the crd.DNSProvider with the
+// given name, if any.
+func (r *Reconciler) dynamicProvider(name string) (*dynamicProvider, bool) {
+	r.dynamicM.RLock()
+	defer r.dynamicM.RUnlock()
+
+	p, ok := r.dynamic[name]
+	return p, ok
+}
+
+// unregisterProvider removes the provider built from the crd.DNSProvider with
+// the given name, if any.
+func (r *Reconciler) unregisterProvider(name string) {
+	r.dynamicM.Lock()
+	defer r.dynamicM.Unlock()
+
+	if p, ok := r.dynamic[name]; ok {
+		delete(r.dynamic, name)
+
+		r.Logger.Info(
+			"provider disabled",
+			"id", p.ID(),
+		)
+	}
+}
+
+// isPendingProvider returns true if the given provider ID refers to a
+// crd.DNSProvider that should be used by this reconciler, but has not been
+// built yet.
+func (r *Reconciler) isPendingProvider(ctx context.Context, id string) (bool, error) {
+	name, ok := strings.CutPrefix(id, dynamicProviderPrefix)
+	if !ok || r.BuildProvider == nil {
+		return false, nil
+	}
+
+	res := &crd.DNSProvider{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: name}, res); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	return res.DeletionTimestamp == nil && res.Spec.InstanceClassName == r.InstanceClass, nil
+}
+
+// setupProviderController registers a controller that builds providers from
+// crd.DNSProvider resources.
+//
+// Like the controller that reconciles service instances, it only runs on the
+// leader, so that standby replicas do not read the credentials secrets, and
+// the status of each resource is only updated by one replica.
+func (r *Reconciler) setupProviderController() error {
+	return builder.
+		ControllerManagedBy(r.Manager).
+		For(&crd.DNSProvider{}).
+		WatchesMetadata(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.providersReferencingSecret),
+		).
+		Complete(providerReconciler{r})
+}
+
+// providersReferencingSecret returns a request for each crd.DNSProvider that
+// obtains its credentials from the given Secret.
+func (r *Reconciler) providersReferencingSecret(
+	ctx context.Context,
+	obj client.Object,
+) []reconcile.Request {
+	list := &crd.DNSProviderList{}
+	if err := r.Client.List(ctx, list); err != nil {
+		r.Logger.Error(err, "unable to list DNS providers")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, p := range list.Items {
+		if ref := p.Spec.CredentialsSecretRef; ref != nil &&
+			ref.Namespace == obj.GetNamespace() &&
+			ref.Name == obj.GetName() {
+			requests = append(
+				requests,
+				reconcile.Request{
+					NamespacedName: client.ObjectKeyFromObject(&p),
+				},
+			)
+		}
+	}
+
+	return requests
+}
+
+// providerReconciler builds providers from crd.DNSProvider resources and
+// makes them available to the Reconciler.
+type providerReconciler struct {
+	r *Reconciler
+}
+
+// Reconcile builds (or rebuilds) the provider for the crd.DNSProvider
+// referred to by the request.
+func (pr providerReconciler) Reconcile(
+	ctx context.Context,
+	req reconcile.Request,
+) (reconcile.Result, error) {
+	r := pr.r
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	res := &crd.DNSProvider{}
+	if err := r.Client.Get(ctx, req.NamespacedName, res); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.unregisterProvider(req.Name)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if res.DeletionTimestamp != nil || res.Spec.InstanceClassName != r.InstanceClass {
+		// Any provider belonging to another instance class is managed, and
+		// has its status reported, by some other instance of Proclaim.
+		r.unregisterProvider(req.Name)
+		return reconcile.Result{}, nil
+	}
+
+	// The resource is re-queued periodically to check the provider's health.
+	// The provider is only rebuilt if the resource or its credentials have
+	// changed since it was last built.
+	version := r.credentialsVersion(ctx, res)
+
+	p, ok := r.dynamicProvider(req.Name)
+	if !ok || p.generation != res.Generation || p.credentialsVersion != version {
+		var err error
+		p, err = r.buildProvider(ctx, res)
+		if err != nil {
+			if ctx.Err() != nil {
+				return reconcile.Result{}, ctx.Err()
+			}
+
+			r.unregisterProvider(req.Name)
+
+			return reconcile.Result{}, r.updateProvider(
+				ctx,
+				res,
+				"", "",
+				crd.ProviderConfigurationErrorCondition(err),
+				func() { crd.ProviderConfigurationError(r.Manager, res, err) },
+			)
+		}
+
+		p.credentialsVersion = version
+		r.registerProvider(req.Name, p)
+	}
+
+	ready := crd.ProviderReadyCondition()
+	event := func() {}
+
+	hctx, cancel := context.WithTimeout(ctx, provider.Timeout)
+	err := p.HealthCheck(hctx)
+	cancel()
+
+	if err != nil {
+		ready = crd.ProviderUnhealthyCondition(err)
+		event = func() { crd.ProviderUnhealthy(r.Manager, res, err) }
+	}
+
+	interval := r.HealthCheckInterval
+	if interval <= 0 {
+		interval = 1 * time.Minute
+	}
+
+	return reconcile.Result{RequeueAfter: interval}, r.updateProvider(
+		ctx,
+		res,
+		p.ID(),
+		p.Describe(),
+		ready,
+		event,
+	)
+}
+
+// updateProvider updates the status of the given crd.DNSProvider and records
+// an event if the status of its Ready condition has changed.
+func (r *Reconciler) updateProvider(
+	ctx context.Context,
+	res *crd.DNSProvider,
+	id, desc string,
+	ready metav1.Condition,
+	event func(),
+) error {
+	prev := res.Condition(crd.ConditionTypeReady)
+
+	if err := crd.UpdateDNSProviderStatus(ctx, r.Client, res, id, desc, ready); err != nil {
+		return fmt.Errorf("unable to update status sub-resource: %w", err)
+	}
+
+	if prev.Status != ready.Status || prev.Reason != ready.Reason {
+		event()
+	}
+
+	return nil
+}
+
+// buildProvider builds the provider described by the given crd.DNSProvider.
+func (r *Reconciler) buildProvider(
+	ctx context.Context,
+	res *crd.DNSProvider,
+) (*dynamicProvider, error) {
+	if r.BuildProvider == nil {
+		return nil, errors.New("DNS providers are not supported by this controller")
+	}
+
+	var credentials map[string][]byte
+
+	if ref := res.Spec.CredentialsSecretRef; ref != nil {
+		if ref.Namespace == "" {
+			return nil, errors.New("the namespace of the credentials secret must be specified")
+		}
+
+		reader := r.APIReader
+		if reader == nil {
+			reader = r.Client
+		}
+
+		secret := &corev1.Secret{}
+		if err := reader.Get(
+			ctx,
+			client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name},
+			secret,
+		); err != nil {
+			return nil, fmt.Errorf("unable to read credentials secret: %w", err)
+		}
+
+		credentials = secret.Data
+	}
+
+	p, err := r.BuildProvider(ctx, res.Spec, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamicProvider{
+		Provider:   p,
+		name:       res.Name,
+		generation: res.Generation,
+		policy: provider.DomainPolicy{
+			Allowed: res.Spec.Domains,
+			Denied:  res.Spec.DeniedDomains,
+		},
+	}, nil
+}
+
+// credentialsVersion returns the resource version of the credentials secret
+// referenced by the given crd.DNSProvider, as seen by the cache.
+//
+// It returns an empty string if there is no such secret.
+func (r *Reconciler) credentialsVersion(
+	ctx context.Context,
+	res *crd.DNSProvider,
+) string {
+	ref := res.Spec.CredentialsSecretRef
+	if ref == nil {
+		return ""
+	}
+
+	// Only the metadata of secrets is watched, and therefore cached.
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+
+	if err := r.Client.Get(
+		ctx,
+		client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name},
+		obj,
+	); err != nil {
+		return ""
+	}
+
+	return obj.ResourceVersion
+}
+
+// dynamicProviderPrefix is the prefix of the ID of each provider built from a
+// crd.DNSProvider.
+const dynamicProviderPrefix = "dnsprovider."
+
+// dynamicProvider is a provider.Provider built from a crd.DNSProvider.
+type dynamicProvider struct {
+	provider.Provider
+
+	name   string
+	policy provider.DomainPolicy
+
+	// generation is the generation of the crd.DNSProvider from which the
+	// provider was built, and credentialsVersion is the resource version of
+	// its credentials secret.
+	generation         int64
+	credentialsVersion string
+}
+
+// ID returns a unique identifier for the provider, based on the name of the
+// crd.DNSProvider resource, such that several resources of the same type may
+// be used at once.
+func (p *dynamicProvider) ID() string {
+	return dynamicProviderPrefix + p.name
+}
+
+// Describe returns a human-readable description of the provider.
+func (p *dynamicProvider) Describe() string {
+	return fmt.Sprintf("%s (%s)", p.Provider.Describe(), p.name)
+}
+
+// DomainPolicy returns the policy that restricts the provider's domains.
+func (p *dynamicProvider) DomainPolicy() provider.DomainPolicy {
+	return p.policy
+}
+
+// AdvertiserByID returns the Advertiser with the given identity structure,
+// restricted to the domains permitted by the provider's domain policy.
+func (p *dynamicProvider) AdvertiserByID(
+	ctx context.Context,
+	id map[string]any,
+) (provider.Advertiser, error) {
+	a, err := p.Provider.AdvertiserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return provider.RestrictAdvertiser(a, p.policy), nil
+}
+
+// AdvertiserByDomain returns the Advertiser used to advertise services on
+// the given domain.
+//
+// ok is false if the domain is not permitted by the provider's domain policy,
+// or the underlying provider does not manage it.
+func (p *dynamicProvider) AdvertiserByDomain(
+	ctx context.Context,
+	domain string,
+) (provider.Advertiser, bool, error) {
+	if p.policy.Check(domain) != nil {
+		return nil, false, nil
+	}
+
+	return p.Provider.AdvertiserByDomain(ctx, domain)
+}
+
+// HealthCheck returns an error if the underlying provider is unhealthy.
+func (p *dynamicProvider) HealthCheck(ctx context.Context) error {
+	if c, ok := p.Provider.(provider.HealthChecker); ok {
+		return c.HealthCheck(ctx)
+	}
+	return nil
+}
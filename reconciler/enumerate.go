@@ -0,0 +1,258 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+)
+
+// advertiseEnumeration advertises the records that allow DNS-SD clients to
+// enumerate the service type and domain of the given service instance, if
+// enabled and supported by the advertiser.
+//
+// It returns true if any changes to DNS records were made.
+func (r *Reconciler) advertiseEnumeration(
+	ctx context.Context,
+	a provider.Advertiser,
+	inst dnssd.ServiceInstance,
+) (bool, error) {
+	changed := false
+
+	if ea, ok := provider.AsEnumerationAdvertiser(a); ok && r.EnableServiceTypeEnumeration {
+		ok, err := ea.AdvertiseServiceType(ctx, inst.ServiceType, inst.Domain, inst.TTL)
+		if err != nil {
+			return false, fmt.Errorf("unable to advertise service type: %w", err)
+		}
+		changed = changed || ok
+	}
+
+	if r.EnableBrowsingDomainEnumeration {
+		parent, domain, ok, err := r.parentAdvertiser(ctx, inst.Domain)
+		if err != nil {
+			return false, err
+		}
+
+		if ok {
+			ok, err := parent.AdvertiseBrowsingDomain(ctx, inst.Domain, domain, inst.TTL)
+			if err != nil {
+				return false, fmt.Errorf("unable to advertise browsing domain: %w", err)
+			}
+			changed = changed || ok
+		}
+	}
+
+	return changed, nil
+}
+
+// unadvertiseEnumeration removes the records that allow DNS-SD clients to
+// enumerate the service type and domain of the given service instance, if
+// they are not required by any other service instances.
+//
+// It returns true if any changes to DNS records were made.
+func (r *Reconciler) unadvertiseEnumeration(
+	ctx context.Context,
+	a provider.Advertiser,
+	res *crd.DNSSDServiceInstance,
+	inst dnssd.ServiceInstance,
+) (bool, error) {
+	changed, err := r.unadvertiseServiceType(ctx, a, res, inst)
+	if err != nil || !r.EnableBrowsingDomainEnumeration {
+		return changed, err
+	}
+
+	_, sameDomain, err := r.countPeers(ctx, res)
+	if err != nil {
+		return false, err
+	}
+
+	if sameDomain == 0 {
+		parent, domain, ok, err := r.parentAdvertiser(ctx, inst.Domain)
+		if err != nil {
+			return false, err
+		}
+
+		if ok {
+			ok, err := parent.UnadvertiseBrowsingDomain(ctx, inst.Domain, domain)
+			if err != nil {
+				return false, fmt.Errorf("unable to unadvertise browsing domain: %w", err)
+			}
+			changed = changed || ok
+		}
+	}
+
+	return changed, nil
+}
+
+// unadvertiseServiceType removes the record that allows DNS-SD clients to
+// enumerate the service type of the given service instance from a's zone, if
+// it is not required by any other service instances.
+//
+// Unlike the browsing domain records, which are published by the advertiser of
+// the parent domain, this record is published by the instance's own advertiser.
+//
+// It returns true if any changes to DNS records were made.
+func (r *Reconciler) unadvertiseServiceType(
+	ctx context.Context,
+	a provider.Advertiser,
+	res *crd.DNSSDServiceInstance,
+	inst dnssd.ServiceInstance,
+) (bool, error) {
+	ea, ok := provider.AsEnumerationAdvertiser(a)
+	if !ok || !r.EnableServiceTypeEnumeration {
+		return false, nil
+	}
+
+	sameType, _, err := r.countPeers(ctx, res)
+	if err != nil || sameType != 0 {
+		return false, err
+	}
+
+	changed, err := ea.UnadvertiseServiceType(ctx, inst.ServiceType, inst.Domain)
+	if err != nil {
+		return false, fmt.Errorf("unable to unadvertise service type: %w", err)
+	}
+
+	return changed, nil
+}
+
+// countPeers returns the number of other service instances that are not
+// being deleted that share the service type and domain of the given instance,
+// and the number that share its domain.
+func (r *Reconciler) countPeers(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) (sameType, sameDomain int, _ error) {
+	peers, err := r.peers(ctx, res)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, peer := range peers {
+		if !strings.EqualFold(peer.Spec.Instance.Domain, res.Spec.Instance.Domain) {
+			continue
+		}
+
+		sameDomain++
+
+		if strings.EqualFold(peer.Spec.Instance.ServiceType, res.Spec.Instance.ServiceType) {
+			sameType++
+		}
+	}
+
+	return sameType, sameDomain, nil
+}
+
+// peers returns all service instances other than res that are not being
+// deleted.
+//
+// If the reconciler is scoped, instances outside of its scope are included.
+func (r *Reconciler) peers(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) ([]crd.DNSSDServiceInstance, error) {
+	list := &crd.DNSSDServiceInstanceList{}
+	if err := r.instanceReader().List(ctx, list); err != nil {
+		return nil, fmt.Errorf("unable to list service instances: %w", err)
+	}
+
+	var peers []crd.DNSSDServiceInstance
+
+	for _, peer := range list.Items {
+		if peer.UID != res.UID && peer.DeletionTimestamp.IsZero() {
+			peers = append(peers, peer)
+		}
+	}
+
+	return peers, nil
+}
+
+// parentAdvertiserCacheKey is the context key for the cache used by
+// parentAdvertiser().
+type parentAdvertiserCacheKey struct{}
+
+// parentAdvertiserCache maps a domain to the result of looking up the
+// advertiser of its parent domain.
+type parentAdvertiserCache map[string]parentAdvertiserResult
+
+type parentAdvertiserResult struct {
+	Advertiser provider.EnumerationAdvertiser
+	Parent     string
+	OK         bool
+}
+
+// withParentAdvertiserCache returns a context that caches the results of
+// parentAdvertiser() for the lifetime of a single reconciliation, so that each
+// provider is asked for the parent domain's advertiser at most once.
+func withParentAdvertiserCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, parentAdvertiserCacheKey{}, parentAdvertiserCache{})
+}
+
+// parentAdvertiser returns the advertiser for the parent of the given domain,
+// which is where the records that advertise the domain as a browsing domain
+// are published.
+//
+// ok is false if the domain has no parent, or none of the providers manage it
+// with an advertiser that supports enumeration.
+func (r *Reconciler) parentAdvertiser(
+	ctx context.Context,
+	domain string,
+) (_ provider.EnumerationAdvertiser, parent string, ok bool, _ error) {
+	cache, _ := ctx.Value(parentAdvertiserCacheKey{}).(parentAdvertiserCache)
+	key := strings.ToLower(domain)
+
+	if c, ok := cache[key]; ok {
+		return c.Advertiser, c.Parent, c.OK, nil
+	}
+
+	a, parent, ok, err := r.findParentAdvertiser(ctx, domain)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	if cache != nil {
+		cache[key] = parentAdvertiserResult{a, parent, ok}
+	}
+
+	return a, parent, ok, nil
+}
+
+// findParentAdvertiser returns the advertiser for the parent of the given
+// domain, without consulting the cache.
+func (r *Reconciler) findParentAdvertiser(
+	ctx context.Context,
+	domain string,
+) (_ provider.EnumerationAdvertiser, parent string, ok bool, _ error) {
+	_, parent, ok = strings.Cut(domain, ".")
+	if !ok {
+		return nil, "", false, nil
+	}
+
+	for _, p := range r.providers() {
+		if rp, ok := p.(provider.Restricted); ok && rp.DomainPolicy().Check(parent) != nil {
+			continue
+		}
+
+		a, ok, err := p.AdvertiserByDomain(ctx, parent)
+		if err != nil {
+			return nil, "", false, fmt.Errorf("unable to find advertiser for %q: %w", parent, err)
+		}
+		if ok {
+			if ea, ok := provider.AsEnumerationAdvertiser(a); ok {
+				return ea, parent, true, nil
+			}
+		}
+	}
+
+	r.Logger.Info(
+		"not advertising browsing domain",
+		"domain", domain,
+		"parent", parent,
+		"reason", "parent domain is not managed by any provider that supports enumeration",
+	)
+
+	return nil, "", false, nil
+}
@@ -0,0 +1,199 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dogmatiq/proclaim/provider"
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// providerHealth is the result of the most recent health check of a provider.
+type providerHealth struct {
+	Description string    `json:"description"`
+	Healthy     bool      `json:"healthy"`
+	Message     string    `json:"message,omitempty"`
+	LastChecked time.Time `json:"lastChecked"`
+}
+
+// CheckStarted is a healthz.Checker that reports whether the reconciler has
+// started.
+//
+// It does not reflect the health of the providers, as an unhealthy provider,
+// or the absence of any providers, does not prevent the reconciler from
+// managing the instances of other providers, and removing the replica from
+// service would not make the providers any healthier. Provider health is
+// reported separately by CheckProviders.
+func (r *Reconciler) CheckStarted(*http.Request) error {
+	if !r.started.Load() {
+		return errors.New("the reconciler has not started")
+	}
+	return nil
+}
+
+// CheckProviders is a healthz.Checker that reports whether the reconciler is
+// able to advertise DNS-SD service instances.
+//
+// It fails if there are no providers, or if the most recent health check of
+// any provider failed. It is intended to be queried directly, or excluded from
+// the readiness probe, rather than used to remove the replica from service.
+func (r *Reconciler) CheckProviders(*http.Request) error {
+	if len(r.providers()) == 0 {
+		return errors.New("no providers are enabled")
+	}
+
+	r.healthM.RLock()
+	defer r.healthM.RUnlock()
+
+	if r.health == nil {
+		return errors.New("providers have not been checked yet")
+	}
+
+	var unhealthy []string
+	for id, h := range r.health {
+		if !h.Healthy {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s: %s", id, h.Message))
+		}
+	}
+
+	if len(unhealthy) != 0 {
+		slices.Sort(unhealthy)
+		return fmt.Errorf("unhealthy providers: %s", strings.Join(unhealthy, "; "))
+	}
+
+	return nil
+}
+
+// healthMonitor is a manager.Runnable that periodically checks the health of
+// each provider.
+type healthMonitor struct {
+	r *Reconciler
+}
+
+// NeedLeaderElection returns false, so that every replica becomes ready once
+// it has started, not just the leader.
+func (m healthMonitor) NeedLeaderElection() bool {
+	return false
+}
+
+// Start checks the health of each provider until ctx is canceled.
+func (m healthMonitor) Start(ctx context.Context) error {
+	m.r.started.Store(true)
+	defer m.r.started.Store(false)
+
+	interval := m.r.HealthCheckInterval
+	if interval <= 0 {
+		interval = 1 * time.Minute
+	}
+
+	for {
+		m.r.checkHealth(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checkHealth checks the health of each provider that implements
+// provider.HealthChecker. Providers that do not implement it are assumed to be
+// healthy.
+func (r *Reconciler) checkHealth(ctx context.Context) {
+	health := map[string]providerHealth{}
+	providers := r.providers()
+
+	if len(providers) == 0 {
+		r.Logger.Info("no providers are enabled")
+	}
+
+	for _, p := range providers {
+		h := providerHealth{
+			Description: p.Describe(),
+			Healthy:     true,
+			LastChecked: time.Now(),
+		}
+
+		if c, ok := p.(provider.HealthChecker); ok {
+			ctx, cancel := context.WithTimeout(ctx, provider.Timeout)
+			err := c.HealthCheck(ctx)
+			cancel()
+
+			if err != nil {
+				h.Healthy = false
+				h.Message = err.Error()
+
+				r.Logger.Error(
+					err,
+					"provider is unhealthy",
+					"provider", p.ID(),
+				)
+			}
+		}
+
+		health[p.ID()] = h
+	}
+
+	r.healthM.Lock()
+	r.health = health
+	r.healthM.Unlock()
+
+	if r.HealthStatusConfigMap.Name == "" {
+		return
+	}
+
+	// Only the leader writes the status, to avoid replicas contending over
+	// the same ConfigMap.
+	select {
+	case <-r.Manager.Elected():
+	default:
+		return
+	}
+
+	if err := r.writeHealthStatus(ctx, health); err != nil {
+		r.Logger.Error(
+			err,
+			"unable to write provider health status",
+			"configmap", r.HealthStatusConfigMap,
+		)
+	}
+}
+
+// writeHealthStatus writes the results of the provider health checks to the
+// ConfigMap identified by r.HealthStatusConfigMap, with one key per provider.
+func (r *Reconciler) writeHealthStatus(
+	ctx context.Context,
+	health map[string]providerHealth,
+) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: r.HealthStatusConfigMap.Namespace,
+			Name:      r.HealthStatusConfigMap.Name,
+		},
+		Data: map[string]string{},
+	}
+
+	for id, h := range health {
+		data, err := json.Marshal(h)
+		if err != nil {
+			return err
+		}
+		cm.Data[id] = string(data)
+	}
+
+	err := r.Client.Update(ctx, cm)
+	if apierrors.IsNotFound(err) {
+		err = r.Client.Create(ctx, cm)
+	}
+
+	return err
+}
@@ -0,0 +1,225 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// migrate moves the given service instance to the provider requested by the
+// crd.MigrateToAnnotation, if it is not already associated with it.
+//
+// The instance is first advertised by the new advertiser. Once the instance
+// is discoverable, it is unadvertised by the old advertiser and associated
+// with the new one.
+//
+// migrating is false if there is no migration in progress, in which case the
+// instance should be reconciled as usual.
+func (r *Reconciler) migrate(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+	spec crd.DNSSDServiceInstanceSpec,
+) (_ reconcile.Result, migrating bool, _ error) {
+	target, ok := res.Annotations[crd.MigrateToAnnotation]
+	if !ok || res.Status.Provider == "" {
+		return reconcile.Result{}, false, nil
+	}
+
+	p, a, err := r.migrationTarget(ctx, res, target)
+	if err != nil {
+		if ctx.Err() != nil {
+			return reconcile.Result{}, true, ctx.Err()
+		}
+
+		crd.MigrationFailed(r.Manager, res, err)
+		return reconcile.Result{}, true, r.update(
+			res,
+			crd.UpdateMigration(nil),
+			crd.MergeCondition(crd.MigrationFailedCondition(err)),
+		)
+	}
+
+	if p.ID() == res.Status.Provider && reflect.DeepEqual(a.ID(), res.Status.Advertiser) {
+		// The instance has already been migrated, or was already associated
+		// with the target advertiser.
+		return reconcile.Result{}, false, r.update(
+			res,
+			crd.UpdateMigration(nil),
+			crd.If(
+				res.Condition(crd.ConditionTypeMigrated).Status != metav1.ConditionTrue,
+				crd.MergeCondition(crd.MigratedCondition()),
+			),
+		)
+	}
+
+	m := &crd.Migration{
+		ProviderDescription: p.Describe(),
+		Provider:            p.ID(),
+		Advertiser:          a.ID(),
+	}
+
+	r.Logger.Info(
+		"migrating",
+		"namespace", res.Namespace,
+		"name", res.Name,
+		"from", res.Status.Provider,
+		"to", m.Provider,
+	)
+
+	changed, err := r.advertiseWith(ctx, a, res, spec)
+	if err != nil {
+		crd.ProviderError(r.Manager, res, m.Provider, m.ProviderDescription, err)
+		return reconcile.Result{}, true, r.update(
+			res,
+			crd.UpdateMigration(m),
+			crd.MergeCondition(crd.MigrationFailedCondition(err)),
+		)
+	}
+
+	if r.isDiscoveryEnabled(res) {
+		_, discoverable, _ := r.computeDiscoverable(ctx, res, spec)
+
+		if discoverable.Status != metav1.ConditionTrue {
+			r.Logger.Info(
+				"re-queuing",
+				"namespace", res.Namespace,
+				"name", res.Name,
+				"reason", "waiting for migrated instance to be discoverable",
+				"next", res.Spec.Instance.TTL.Duration,
+			)
+
+			result := reconcile.Result{
+				Requeue:      true,
+				RequeueAfter: res.Spec.Instance.TTL.Duration,
+			}
+
+			return result, true, r.update(
+				res,
+				crd.UpdateMigration(m),
+				crd.MergeCondition(crd.MigrationPendingCondition(m)),
+			)
+		}
+	}
+
+	// The old provider may no longer be configured, in which case its records
+	// can not be removed. If it is configured but failing, the migration is
+	// retried, so that its records are not left behind.
+	old, ok, err := r.getAdvertiser(ctx, res)
+	if err != nil {
+		return reconcile.Result{}, true, err
+	}
+
+	if !ok {
+		r.Logger.Info(
+			"not removing old records",
+			"namespace", res.Namespace,
+			"name", res.Name,
+			"reason", "provider is not configured",
+			"provider", res.Status.Provider,
+		)
+	}
+
+	// If the old and new advertisers manage the same zone, such as when the
+	// same credentials are configured as two different providers, the old
+	// records have already been replaced by the new ones, and must be left
+	// in place.
+	if ok && !reflect.DeepEqual(old.ID(), a.ID()) {
+		// The records to remove are those that were last advertised by the old
+		// advertiser, which may differ from those described by the spec.
+		inst, err := r.unadvertisedInstance(ctx, res)
+		if err == nil {
+			_, err = old.Unadvertise(ctx, inst)
+		}
+		if err == nil {
+			_, err = r.unadvertiseStaleAddresses(ctx, old, res, "")
+		}
+		if err == nil {
+			// The instance remains on the same domain, so only the service type
+			// enumeration record is removed; the browsing domain records are
+			// published by the parent domain's advertiser, not the old one.
+			_, err = r.unadvertiseServiceType(ctx, old, res, inst)
+		}
+		if err != nil {
+			crd.ProviderError(r.Manager, res, res.Status.Provider, res.Status.ProviderDescription, err)
+			return reconcile.Result{}, true, r.update(
+				res,
+				crd.UpdateMigration(m),
+				crd.MergeCondition(crd.MigrationFailedCondition(err)),
+			)
+		}
+	}
+
+	records, err := advertisedRecords(ctx, a, spec, changed)
+	if err != nil {
+		crd.ProviderError(r.Manager, res, m.Provider, m.ProviderDescription, err)
+		return reconcile.Result{}, true, r.update(
+			res,
+			crd.UpdateMigration(m),
+			crd.MergeCondition(crd.MigrationFailedCondition(err)),
+		)
+	}
+
+	from := res.Status.ProviderDescription
+
+	if err := r.update(
+		res,
+		crd.UpdateMigration(nil),
+		crd.UpdateProviderDescription(m.ProviderDescription),
+		crd.AssociateProvider(m.Provider, m.Advertiser),
+		crd.AssociateController(r.ControllerName),
+		crd.MergeCondition(crd.InstanceAdoptedCondition()),
+		crd.MergeCondition(crd.MigratedCondition()),
+		crd.RecordsAdvertised(records),
+	); err != nil {
+		return reconcile.Result{}, true, err
+	}
+
+	crd.Migrated(r.Manager, res, from)
+
+	return reconcile.Result{Requeue: true}, true, nil
+}
+
+// migrationTarget returns the provider with the given ID, and the advertiser
+// it uses for the given service instance's domain.
+func (r *Reconciler) migrationTarget(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+	id string,
+) (provider.Provider, provider.Advertiser, error) {
+	for _, p := range r.providers() {
+		if p.ID() != id {
+			continue
+		}
+
+		if rp, ok := p.(provider.Restricted); ok {
+			if err := rp.DomainPolicy().Check(res.Spec.Instance.Domain); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		a, ok, err := p.AdvertiserByDomain(ctx, res.Spec.Instance.Domain)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return nil, nil, fmt.Errorf("%s can not advertise on %q", p.Describe(), res.Spec.Instance.Domain)
+		}
+
+		a, ok, err = withRoutingPolicy(a, res)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return nil, nil, fmt.Errorf("%s does not support routing policies", p.Describe())
+		}
+
+		return p, a, nil
+	}
+
+	return nil, nil, fmt.Errorf("the %q provider is not configured", id)
+}
@@ -0,0 +1,124 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReconciler_migrate(t *testing.T) {
+	setup := func(t *testing.T, providers ...*testProvider) (*Reconciler, *crd.DNSSDServiceInstance) {
+		t.Helper()
+
+		cli, res := setupTestInstance(t)
+
+		r := &Reconciler{
+			Manager:          &testManager{recorder: record.NewFakeRecorder(100)},
+			Client:           cli,
+			Logger:           logr.Discard(),
+			ControllerName:   "a",
+			DisableDiscovery: true,
+		}
+
+		for _, p := range providers {
+			r.Providers = append(r.Providers, p)
+		}
+
+		if err := r.update(
+			res,
+			crd.AssociateProvider("provider-a", nil),
+			crd.AssociateController("a"),
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		res.Annotations = map[string]string{
+			crd.MigrateToAnnotation: "provider-b",
+		}
+
+		return r, res
+	}
+
+	t.Run("it removes the records from the old provider", func(t *testing.T) {
+		old := &zoneAdvertiser{zone: "old"}
+		r, res := setup(
+			t,
+			&testProvider{id: "provider-a", advertiser: old},
+			&testProvider{id: "provider-b", advertiser: &zoneAdvertiser{zone: "new"}},
+		)
+
+		if _, _, err := r.migrate(context.Background(), res, res.Spec); err != nil {
+			t.Fatal(err)
+		}
+
+		if !old.unadvertised {
+			t.Fatal("expected the old records to be removed")
+		}
+
+		if res.Status.Provider != "provider-b" {
+			t.Fatalf("unexpected provider: got %q, want %q", res.Status.Provider, "provider-b")
+		}
+	})
+
+	t.Run("it retries the migration if the old provider fails", func(t *testing.T) {
+		r, res := setup(
+			t,
+			&testProvider{id: "provider-a", err: errors.New("<error>")},
+			&testProvider{id: "provider-b", advertiser: &zoneAdvertiser{zone: "new"}},
+		)
+
+		_, migrating, err := r.migrate(context.Background(), res, res.Spec)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+
+		if !migrating {
+			t.Fatal("expected the migration to be in progress")
+		}
+
+		if res.Status.Provider != "provider-a" {
+			t.Fatalf("unexpected provider: got %q, want %q", res.Status.Provider, "provider-a")
+		}
+	})
+
+	t.Run("it completes the migration if the old provider is not configured", func(t *testing.T) {
+		r, res := setup(
+			t,
+			&testProvider{id: "provider-b", advertiser: &zoneAdvertiser{zone: "new"}},
+		)
+
+		if _, _, err := r.migrate(context.Background(), res, res.Spec); err != nil {
+			t.Fatal(err)
+		}
+
+		if res.Status.Provider != "provider-b" {
+			t.Fatalf("unexpected provider: got %q, want %q", res.Status.Provider, "provider-b")
+		}
+	})
+}
+
+// zoneAdvertiser is a provider.Advertiser for a specific zone that records
+// whether it has unadvertised an instance.
+type zoneAdvertiser struct {
+	testAdvertiser
+
+	zone         string
+	unadvertised bool
+}
+
+var _ provider.Advertiser = (*zoneAdvertiser)(nil)
+
+func (a *zoneAdvertiser) ID() map[string]any {
+	return map[string]any{"zone": a.zone}
+}
+
+func (a *zoneAdvertiser) Unadvertise(context.Context, dnssd.ServiceInstance) (bool, error) {
+	a.unadvertised = true
+	return true, nil
+}
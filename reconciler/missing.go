@@ -0,0 +1,86 @@
+package reconciler
+
+import (
+	"context"
+
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+)
+
+// MissingProviderPolicy determines how the reconciler handles a service
+// instance that is associated with a provider that is not configured.
+type MissingProviderPolicy string
+
+const (
+	// OrphanRecords is a MissingProviderPolicy that leaves any DNS records
+	// in place and allows the instance to be deleted.
+	OrphanRecords MissingProviderPolicy = "orphan"
+
+	// BlockDeletion is a MissingProviderPolicy that prevents the instance
+	// from being deleted until the provider is configured again.
+	BlockDeletion MissingProviderPolicy = "block"
+
+	// Readopt is a MissingProviderPolicy that associates the instance with
+	// any other provider that can advertise on its domain. If there is no such
+	// provider, deletion is blocked.
+	Readopt MissingProviderPolicy = "readopt"
+)
+
+// missingProvider handles a service instance that is associated with a
+// provider that is not configured, according to r.MissingProviderPolicy.
+//
+// It returns the advertiser of the provider that re-adopted the instance, if
+// any. block is true if the instance must not be deleted.
+//
+// If the instance was associated with its provider by some other controller,
+// the provider is assumed to be configured there, and the instance is ignored.
+func (r *Reconciler) missingProvider(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) (_ provider.Advertiser, ok, block bool, _ error) {
+	id := res.Status.Provider
+
+	if res.Status.Controller != r.ControllerName {
+		r.Logger.Info(
+			"ignoring",
+			"namespace", res.Namespace,
+			"name", res.Name,
+			"reason", "provider is managed by another controller",
+			"provider", id,
+			"controller", res.Status.Controller,
+		)
+		return nil, false, false, nil
+	}
+
+	r.Logger.Info(
+		"provider unavailable",
+		"namespace", res.Namespace,
+		"name", res.Name,
+		"provider", id,
+		"policy", r.MissingProviderPolicy,
+	)
+
+	action := "the DNS records are orphaned when the instance is deleted"
+
+	switch r.MissingProviderPolicy {
+	case BlockDeletion:
+		action = "deletion is blocked until it is configured again"
+		block = true
+
+	case Readopt:
+		a, ok, err := r.associateAdvertiser(ctx, res)
+		if ok || err != nil {
+			return a, ok, false, err
+		}
+
+		action = "no other provider can advertise on this domain, deletion is blocked"
+		block = true
+	}
+
+	crd.ProviderUnavailable(r.Manager, res, action)
+
+	return nil, false, block, r.update(
+		res,
+		crd.MergeCondition(crd.ProviderUnavailableCondition(id, action)),
+	)
+}
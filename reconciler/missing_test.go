@@ -0,0 +1,231 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+func TestReconciler_missingProvider(t *testing.T) {
+	reconciler := func(cli client.Client, name string, providers ...string) *Reconciler {
+		r := &Reconciler{
+			Manager:               &testManager{recorder: record.NewFakeRecorder(100)},
+			Client:                cli,
+			Logger:                logr.Discard(),
+			ControllerName:        name,
+			MissingProviderPolicy: Readopt,
+		}
+
+		for _, id := range providers {
+			r.Providers = append(r.Providers, &testProvider{id: id})
+		}
+
+		return r
+	}
+
+	t.Run("it ignores instances adopted by another controller", func(t *testing.T) {
+		cli, res := setupTestInstance(t)
+		a := reconciler(cli, "a", "provider-a")
+		b := reconciler(cli, "b", "provider-b")
+
+		if _, ok, err := a.getOrAssociateAdvertiser(context.Background(), res); err != nil {
+			t.Fatal(err)
+		} else if !ok {
+			t.Fatal("expected the instance to be adopted")
+		}
+
+		if _, ok, err := b.getOrAssociateAdvertiser(context.Background(), res); err != nil {
+			t.Fatal(err)
+		} else if ok {
+			t.Fatal("did not expect the instance to be re-adopted")
+		}
+
+		if res.Status.Provider != "provider-a" {
+			t.Fatalf("unexpected provider: got %q, want %q", res.Status.Provider, "provider-a")
+		}
+
+		if _, should, block, err := b.shouldUnadvertise(context.Background(), res); err != nil {
+			t.Fatal(err)
+		} else if should || block {
+			t.Fatal("did not expect the instance to be unadvertised or its deletion to be blocked")
+		}
+	})
+
+	t.Run("it does not apply the policy when the provider is configured but failing", func(t *testing.T) {
+		cli, res := setupTestInstance(t)
+		a := reconciler(cli, "a", "provider-a", "provider-b")
+
+		if _, _, err := a.getOrAssociateAdvertiser(context.Background(), res); err != nil {
+			t.Fatal(err)
+		}
+
+		a.Providers[0].(*testProvider).err = errors.New("<error>")
+
+		if _, ok, err := a.getOrAssociateAdvertiser(context.Background(), res); err == nil {
+			t.Fatal("expected an error")
+		} else if ok {
+			t.Fatal("did not expect an advertiser")
+		}
+
+		if res.Status.Provider != "provider-a" {
+			t.Fatalf("unexpected provider: got %q, want %q", res.Status.Provider, "provider-a")
+		}
+
+		a.MissingProviderPolicy = OrphanRecords
+
+		if _, should, block, err := a.shouldUnadvertise(context.Background(), res); err == nil {
+			t.Fatal("expected an error")
+		} else if should || block {
+			t.Fatal("did not expect the instance to be unadvertised or its deletion to be blocked")
+		}
+	})
+
+	t.Run("it applies the policy to instances adopted by this controller", func(t *testing.T) {
+		cli, res := setupTestInstance(t)
+		a := reconciler(cli, "a", "provider-a")
+
+		if _, _, err := a.getOrAssociateAdvertiser(context.Background(), res); err != nil {
+			t.Fatal(err)
+		}
+
+		// Reconfigure the controller without its original provider.
+		a = reconciler(cli, "a", "provider-c")
+
+		if _, ok, err := a.getOrAssociateAdvertiser(context.Background(), res); err != nil {
+			t.Fatal(err)
+		} else if !ok {
+			t.Fatal("expected the instance to be re-adopted")
+		}
+
+		if res.Status.Provider != "provider-c" {
+			t.Fatalf("unexpected provider: got %q, want %q", res.Status.Provider, "provider-c")
+		}
+	})
+}
+
+// setupTestInstance returns a fake client containing a single service
+// instance, along with that instance.
+func setupTestInstance(t *testing.T) (client.Client, *crd.DNSSDServiceInstance) {
+	t.Helper()
+
+	s := runtime.NewScheme()
+	b := &scheme.Builder{
+		GroupVersion: schema.GroupVersion{
+			Group:   crd.GroupName,
+			Version: crd.Version,
+		},
+	}
+	b.Register(
+		&crd.DNSSDServiceInstance{},
+		&crd.DNSSDServiceInstanceList{},
+		&crd.DNSSDPolicy{},
+		&crd.DNSSDPolicyList{},
+	)
+	if err := b.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	res := &crd.DNSSDServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "instance",
+		},
+		Spec: crd.DNSSDServiceInstanceSpec{
+			Instance: crd.Instance{
+				Name:        "Instance",
+				ServiceType: "_test._tcp",
+				Domain:      "example.org",
+			},
+		},
+	}
+
+	// The default object tracker can not handle the array within the
+	// instance's spec, so a tracker without field management is used.
+	cli := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjectTracker(
+			clienttesting.NewObjectTracker(
+				s,
+				serializer.NewCodecFactory(s).UniversalDecoder(),
+			),
+		).
+		WithIndex(res, instanceNameIndex, indexInstanceName).
+		WithObjects(res).
+		WithStatusSubresource(res).
+		Build()
+
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(res), res); err != nil {
+		t.Fatal(err)
+	}
+
+	return cli, res
+}
+
+// testManager is a manager.Manager that records events using a fake recorder.
+// Any other method panics.
+type testManager struct {
+	manager.Manager
+	recorder record.EventRecorder
+}
+
+func (m *testManager) GetEventRecorderFor(string) record.EventRecorder {
+	return m.recorder
+}
+
+// testProvider is a provider.Provider that manages every domain.
+//
+// It returns the given advertiser, or a testAdvertiser if it is nil. If err is
+// non-nil, it is returned by AdvertiserByID() instead.
+type testProvider struct {
+	id         string
+	advertiser provider.Advertiser
+	err        error
+}
+
+func (p *testProvider) ID() string       { return p.id }
+func (p *testProvider) Describe() string { return p.id }
+
+func (p *testProvider) AdvertiserByID(context.Context, map[string]any) (provider.Advertiser, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.advertiser != nil {
+		return p.advertiser, nil
+	}
+	return &testAdvertiser{}, nil
+}
+
+func (p *testProvider) AdvertiserByDomain(context.Context, string) (provider.Advertiser, bool, error) {
+	if p.advertiser != nil {
+		return p.advertiser, true, nil
+	}
+	return &testAdvertiser{}, true, nil
+}
+
+// testAdvertiser is a provider.Advertiser that does nothing.
+type testAdvertiser struct{}
+
+func (*testAdvertiser) ID() map[string]any { return nil }
+
+func (*testAdvertiser) Advertise(context.Context, dnssd.ServiceInstance, ...dnssd.AdvertiseOption) (bool, error) {
+	return true, nil
+}
+
+func (*testAdvertiser) Unadvertise(context.Context, dnssd.ServiceInstance) (bool, error) {
+	return true, nil
+}
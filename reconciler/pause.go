@@ -0,0 +1,44 @@
+package reconciler
+
+import (
+	"github.com/dogmatiq/proclaim/crd"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// pause reports the Paused condition of the given service instance.
+//
+// It returns true if reconciliation is paused, in which case the DNS records
+// and the remainder of the status must not be modified. This includes
+// unadvertising the instance when it is deleted.
+func (r *Reconciler) pause(res *crd.DNSSDServiceInstance) (bool, error) {
+	c := res.Condition(crd.ConditionTypePaused)
+
+	if res.IsPaused() {
+		r.Logger.Info(
+			"ignoring",
+			"namespace", res.Namespace,
+			"name", res.Name,
+			"reason", "reconciliation paused",
+		)
+
+		if c.Status == metav1.ConditionTrue {
+			return true, nil
+		}
+
+		crd.ReconciliationPaused(r.Manager, res)
+		return true, r.update(
+			res,
+			crd.MergeCondition(crd.ReconciliationPausedCondition()),
+		)
+	}
+
+	if c.Status != metav1.ConditionTrue {
+		return false, nil
+	}
+
+	crd.ReconciliationResumed(r.Manager, res)
+	return false, r.update(
+		res,
+		crd.MergeCondition(crd.ReconciliationResumedCondition()),
+	)
+}
@@ -0,0 +1,198 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconciler_pause(t *testing.T) {
+	setup := func(t *testing.T) (*Reconciler, client.Client, *crd.DNSSDServiceInstance, *countingAdvertiser) {
+		t.Helper()
+
+		cli, res := setupTestInstance(t)
+		a := &countingAdvertiser{}
+
+		r := &Reconciler{
+			Manager:        &testManager{recorder: record.NewFakeRecorder(100)},
+			Client:         cli,
+			Logger:         logr.Discard(),
+			ControllerName: "a",
+
+			// The instance is not discoverable without a resolver.
+			DisableDiscovery: true,
+
+			Providers: []provider.Provider{
+				&testProvider{id: "provider-a", advertiser: a},
+			},
+		}
+
+		return r, cli, res, a
+	}
+
+	// reconcileInstance reconciles the instance until it is no longer
+	// re-queued immediately, such as after its status is initialized.
+	reconcileInstance := func(t *testing.T, r *Reconciler, res *crd.DNSSDServiceInstance) {
+		t.Helper()
+
+		for i := 0; i < 5; i++ {
+			result, err := r.Reconcile(
+				context.Background(),
+				reconcile.Request{NamespacedName: client.ObjectKeyFromObject(res)},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Requeue {
+				break
+			}
+		}
+
+		if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(res), res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	setPaused := func(t *testing.T, cli client.Client, res *crd.DNSSDServiceInstance, paused bool) {
+		t.Helper()
+
+		if paused {
+			res.Annotations = map[string]string{crd.PausedAnnotation: "true"}
+		} else {
+			delete(res.Annotations, crd.PausedAnnotation)
+		}
+
+		if err := cli.Update(context.Background(), res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := func(r *Reconciler) int {
+		return len(r.Manager.(*testManager).recorder.(*record.FakeRecorder).Events)
+	}
+
+	t.Run("it reports that reconciliation is paused", func(t *testing.T) {
+		r, cli, res, _ := setup(t)
+		setPaused(t, cli, res, true)
+
+		reconcileInstance(t, r, res)
+
+		c := res.Condition(crd.ConditionTypePaused)
+		if c.Status != metav1.ConditionTrue || c.Reason != "ReconciliationPaused" {
+			t.Fatalf("unexpected condition: %s %s", c.Status, c.Reason)
+		}
+
+		if n := events(r); n != 1 {
+			t.Fatalf("unexpected number of events: got %d, want 1", n)
+		}
+
+		reconcileInstance(t, r, res)
+
+		if n := events(r); n != 1 {
+			t.Fatalf("did not expect the event to be recorded again, got %d events", n)
+		}
+	})
+
+	t.Run("it reports that reconciliation has resumed", func(t *testing.T) {
+		r, cli, res, _ := setup(t)
+		setPaused(t, cli, res, true)
+		reconcileInstance(t, r, res)
+		setPaused(t, cli, res, false)
+
+		paused, err := r.pause(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if paused {
+			t.Fatal("did not expect reconciliation to be paused")
+		}
+
+		c := res.Condition(crd.ConditionTypePaused)
+		if c.Status != metav1.ConditionFalse || c.Reason != "ReconciliationResumed" {
+			t.Fatalf("unexpected condition: %s %s", c.Status, c.Reason)
+		}
+
+		if n := events(r); n != 2 {
+			t.Fatalf("unexpected number of events: got %d, want 2", n)
+		}
+
+		if _, err := r.pause(res); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := events(r); n != 2 {
+			t.Fatalf("did not expect the event to be recorded again, got %d events", n)
+		}
+	})
+
+	t.Run("it does not advertise a paused instance", func(t *testing.T) {
+		r, cli, res, a := setup(t)
+		setPaused(t, cli, res, true)
+
+		reconcileInstance(t, r, res)
+
+		if a.advertised != 0 {
+			t.Fatalf("unexpected number of advertisements: got %d, want 0", a.advertised)
+		}
+
+		if res.Status.Provider != "" {
+			t.Fatalf("did not expect the instance to be adopted by %q", res.Status.Provider)
+		}
+	})
+
+	t.Run("it does not unadvertise a paused instance when it is deleted", func(t *testing.T) {
+		r, cli, res, a := setup(t)
+
+		if _, _, err := r.getOrAssociateAdvertiser(context.Background(), res); err != nil {
+			t.Fatal(err)
+		}
+
+		res.Finalizers = []string{crd.FinalizerName}
+		setPaused(t, cli, res, true)
+
+		if err := cli.Delete(context.Background(), res); err != nil {
+			t.Fatal(err)
+		}
+
+		reconcileInstance(t, r, res)
+
+		if a.unadvertised != 0 {
+			t.Fatalf("unexpected number of unadvertisements: got %d, want 0", a.unadvertised)
+		}
+
+		if res.DeletionTimestamp.IsZero() {
+			t.Fatal("expected the instance to be marked for deletion")
+		}
+
+		if len(res.Finalizers) == 0 {
+			t.Fatal("did not expect the finalizer to be removed")
+		}
+	})
+}
+
+// countingAdvertiser is a provider.Advertiser that records the number of times
+// it advertises and unadvertises an instance.
+type countingAdvertiser struct {
+	testAdvertiser
+
+	advertised   int
+	unadvertised int
+}
+
+func (a *countingAdvertiser) Advertise(context.Context, dnssd.ServiceInstance, ...dnssd.AdvertiseOption) (bool, error) {
+	a.advertised++
+	return true, nil
+}
+
+func (a *countingAdvertiser) Unadvertise(context.Context, dnssd.ServiceInstance) (bool, error) {
+	a.unadvertised++
+	return true, nil
+}
@@ -0,0 +1,305 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/miekg/dns"
+	"golang.org/x/exp/slices"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// plan reports the changes to DNS records that would be made in order to
+// advertise the given service instance, without making them. The changes are
+// planned by the advertiser, based on the records that currently exist.
+//
+// It is used in place of advertise() when the reconciler is in dry-run mode.
+// The instance is re-planned at its drift detection interval, so that changes
+// made to the records outside of Proclaim are reflected in the plan.
+func (r *Reconciler) plan(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+	spec crd.DNSSDServiceInstanceSpec,
+) (reconcile.Result, error) {
+	a, ok, err := r.plannedAdvertiser(ctx, res)
+
+	var changes []crd.RecordChange
+	if ok && err == nil {
+		changes, err = planAdvertise(ctx, a, res, spec)
+	}
+
+	if err != nil {
+		return reconcile.Result{Requeue: true}, r.planFailed(ctx, res, err)
+	}
+
+	return reconcile.Result{
+		RequeueAfter: r.driftDetectionInterval(res),
+	}, r.reportPlan(res, changes)
+}
+
+// planUnadvertise reports the changes to DNS records that would be made in
+// order to unadvertise the given service instance, without making them.
+//
+// It is used in place of unadvertise() when the reconciler is in dry-run mode.
+// The finalizer is removed without removing the records, so that dry-run mode
+// never prevents an instance from being deleted.
+func (r *Reconciler) planUnadvertise(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) (reconcile.Result, error) {
+	winner, err := r.conflictWinner(ctx, res)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	var changes []crd.RecordChange
+
+	// The records of a conflicting instance belong to the instance that takes
+	// precedence, so they would not be removed.
+	if winner == nil {
+		changes, err = r.planRemoval(ctx, res)
+		if err != nil {
+			if err := r.planFailed(ctx, res, err); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+	}
+
+	if err := r.reportPlan(res, changes); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	controllerutil.RemoveFinalizer(res, crd.FinalizerName)
+	if err := r.Client.Update(ctx, res); err != nil {
+		return reconcile.Result{}, fmt.Errorf("unable to remove finalizer: %w", err)
+	}
+
+	r.Logger.Info(
+		"removed finalizer",
+		"namespace", res.Namespace,
+		"name", res.Name,
+		"finalizer", crd.FinalizerName,
+		"reason", "dry-run",
+	)
+
+	return reconcile.Result{}, nil
+}
+
+// planRemoval returns the changes to DNS records that would be made in order
+// to remove the records of the given service instance.
+func (r *Reconciler) planRemoval(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) ([]crd.RecordChange, error) {
+	if res.Status.Provider == "" ||
+		res.Condition(crd.ConditionTypeAdvertised).Status == metav1.ConditionFalse {
+		return nil, nil
+	}
+
+	a, ok, err := r.plannedAdvertiser(ctx, res)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	p, ok := provider.AsPlanner(a)
+	if !ok {
+		return planRecordChanges(res.Status.Records, nil), nil
+	}
+
+	inst, err := r.unadvertisedInstance(ctx, res)
+	if err != nil {
+		return nil, err
+	}
+
+	planned, err := p.PlanUnadvertise(ctx, inst)
+	if err != nil {
+		return nil, err
+	}
+
+	return recordChanges(planned), nil
+}
+
+// plannedAdvertiser returns the advertiser that would be used to advertise or
+// unadvertise the given service instance.
+//
+// Unlike getOrAssociateAdvertiser(), it never modifies the resource's status,
+// so an instance that is not associated with a provider is not adopted, and
+// the missing provider policy is not applied.
+func (r *Reconciler) plannedAdvertiser(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) (provider.Advertiser, bool, error) {
+	if res.Status.Provider == "" {
+		p, a, _, _, err := r.candidateAdvertiser(ctx, res)
+		return a, p != nil, err
+	}
+
+	for _, p := range r.providers() {
+		if p.ID() != res.Status.Provider {
+			continue
+		}
+
+		a, err := p.AdvertiserByID(ctx, res.Status.Advertiser)
+		if err != nil {
+			return nil, false, err
+		}
+
+		a, ok, err := withRoutingPolicy(a, res)
+		if err == nil && !ok {
+			err = errors.New("the provider does not support routing policies")
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		return a, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// planFailed reports an error that occurred while planning changes to the
+// given service instance's records.
+func (r *Reconciler) planFailed(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+	err error,
+) error {
+	crd.ProviderError(
+		r.Manager,
+		res,
+		res.Status.Provider,
+		res.Status.ProviderDescription,
+		err,
+	)
+
+	r.Logger.Info(
+		"unable to plan record changes",
+		"namespace", res.Namespace,
+		"name", res.Name,
+		"error", err.Error(),
+	)
+
+	return ctx.Err()
+}
+
+// planAdvertise returns the changes to DNS records that would be made in order
+// to advertise the given service instance using a specific advertiser.
+//
+// If the advertiser can not plan changes, they are computed from the records
+// in the instance's status instead.
+func planAdvertise(
+	ctx context.Context,
+	a provider.Advertiser,
+	res *crd.DNSSDServiceInstance,
+	spec crd.DNSSDServiceInstanceSpec,
+) ([]crd.RecordChange, error) {
+	inst := spec.ToDissolve()
+	options := spec.AdvertiseOptions()
+
+	p, ok := provider.AsPlanner(a)
+	if !ok {
+		return planRecordChanges(
+			res.Status.Records,
+			dnssd.NewRecords(inst, options...),
+		), nil
+	}
+
+	planned, err := p.Plan(ctx, inst, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return recordChanges(planned), nil
+}
+
+// recordChanges converts changes planned by a provider.Planner to their
+// representation within the resource's status.
+func recordChanges(planned []provider.Change) []crd.RecordChange {
+	var changes []crd.RecordChange
+	for _, c := range planned {
+		changes = append(changes, crd.RecordChange{
+			Action: crd.RecordChangeAction(c.Action),
+			Record: crd.NewRecord(c.Record),
+		})
+	}
+	return changes
+}
+
+// reportPlan records the planned changes in the resource's status, and emits
+// an event if they differ from the previously planned changes.
+func (r *Reconciler) reportPlan(
+	res *crd.DNSSDServiceInstance,
+	changes []crd.RecordChange,
+) error {
+	r.Logger.Info(
+		"planned record changes",
+		"namespace", res.Namespace,
+		"name", res.Name,
+		"changes", len(changes),
+	)
+
+	if len(changes) != 0 && !slices.Equal(changes, res.Status.PlannedChanges) {
+		crd.RecordChangesPlanned(r.Manager, res, changes)
+	}
+
+	return r.update(
+		res,
+		crd.UpdatePlannedChanges(changes),
+	)
+}
+
+// clearPlan removes any planned changes from the resource's status. It is
+// called when the reconciler is not in dry-run mode, so that changes planned
+// by a previous dry-run are not reported indefinitely.
+func (r *Reconciler) clearPlan(res *crd.DNSSDServiceInstance) error {
+	if len(res.Status.PlannedChanges) == 0 {
+		return nil
+	}
+
+	return r.update(
+		res,
+		crd.UpdatePlannedChanges(nil),
+	)
+}
+
+// planRecordChanges returns the changes required to replace the current
+// records with the desired records.
+func planRecordChanges(
+	current []crd.Record,
+	desired []dns.RR,
+) []crd.RecordChange {
+	var (
+		changes []crd.RecordChange
+		records []crd.Record
+	)
+
+	for _, rr := range desired {
+		rec := crd.NewRecord(rr)
+		records = append(records, rec)
+
+		if !slices.Contains(current, rec) {
+			changes = append(changes, crd.RecordChange{
+				Action: crd.RecordChangeCreate,
+				Record: rec,
+			})
+		}
+	}
+
+	for _, rec := range current {
+		if !slices.Contains(records, rec) {
+			changes = append(changes, crd.RecordChange{
+				Action: crd.RecordChangeDelete,
+				Record: rec,
+			})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,238 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// enforcePolicies checks that the crd.DNSSDPolicy resources permit the given
+// service instance to be advertised.
+//
+// It is called every time the instance is advertised, not only when it is
+// adopted, so that an instance is unadvertised if the policies change such
+// that it is no longer permitted. It returns true if the instance is
+// forbidden.
+func (r *Reconciler) enforcePolicies(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) (reconcile.Result, bool, error) {
+	reason, err := r.checkPolicies(ctx, res)
+	if err != nil || reason == "" {
+		return reconcile.Result{}, false, err
+	}
+
+	if a := res.Condition(crd.ConditionTypeAdopted); a.Reason != crd.ReasonForbidden || a.Message != reason {
+		crd.InstanceForbidden(r.Manager, res, reason)
+	}
+
+	if err := r.update(
+		res,
+		crd.MergeCondition(crd.InstanceForbiddenCondition(reason)),
+	); err != nil {
+		return reconcile.Result{}, true, err
+	}
+
+	// Beyond this point, there is no point retrying until the policies or the
+	// labels of the instance's namespace change, at which point the instance
+	// is re-queued by the watch.
+	if r.DryRun {
+		changes, err := r.planRemoval(ctx, res)
+		if err != nil {
+			return reconcile.Result{Requeue: true}, true, r.planFailed(ctx, res, err)
+		}
+		return reconcile.Result{}, true, r.reportPlan(res, changes)
+	}
+
+	a, ok, block, err := r.shouldUnadvertise(ctx, res)
+	if err != nil || !ok || block {
+		return reconcile.Result{}, true, err
+	}
+
+	removed, err := r.removeRecords(ctx, a, res)
+	if err != nil {
+		return reconcile.Result{}, true, err
+	}
+
+	if !removed {
+		return reconcile.Result{Requeue: true}, true, nil
+	}
+
+	return reconcile.Result{}, true, nil
+}
+
+// checkPolicies returns a non-empty reason if the crd.DNSSDPolicy resources
+// forbid the given service instance from being advertised.
+//
+// If there are no policies, all instances are permitted. Otherwise, an
+// instance is permitted only if at least one policy that applies to its
+// namespace permits both its domain and its service type.
+func (r *Reconciler) checkPolicies(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) (string, error) {
+	policies := &crd.DNSSDPolicyList{}
+	if err := r.Client.List(ctx, policies); err != nil {
+		if meta.IsNoMatchError(err) {
+			// The DNSSDPolicy CRD is not installed.
+			return "", nil
+		}
+		return "", fmt.Errorf("unable to list policies: %w", err)
+	}
+
+	if len(policies.Items) == 0 {
+		return "", nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: res.Namespace}, ns); err != nil {
+		return "", fmt.Errorf("unable to get namespace: %w", err)
+	}
+
+	var applicable []string
+
+	for _, p := range policies.Items {
+		ok, err := policyAppliesTo(p, ns)
+		if err != nil {
+			return "", fmt.Errorf("invalid namespace selector in %q policy: %w", p.Name, err)
+		}
+		if !ok {
+			continue
+		}
+
+		if policyPermits(p, res) {
+			return "", nil
+		}
+
+		applicable = append(applicable, p.Name)
+	}
+
+	if len(applicable) == 0 {
+		return fmt.Sprintf(
+			"no policies apply to the %q namespace",
+			res.Namespace,
+		), nil
+	}
+
+	return fmt.Sprintf(
+		"none of the policies that apply to the %q namespace (%s) permit %q instances on %q",
+		res.Namespace,
+		strings.Join(applicable, ", "),
+		res.Spec.Instance.ServiceType,
+		res.Spec.Instance.Domain,
+	), nil
+}
+
+// policyAppliesTo returns true if the given policy applies to the given
+// namespace.
+func policyAppliesTo(p crd.DNSSDPolicy, ns *corev1.Namespace) (bool, error) {
+	if slices.Contains(p.Spec.Namespaces, ns.Name) {
+		return true, nil
+	}
+
+	if p.Spec.NamespaceSelector == nil {
+		return false, nil
+	}
+
+	sel, err := metav1.LabelSelectorAsSelector(p.Spec.NamespaceSelector)
+	if err != nil {
+		return false, err
+	}
+
+	return sel.Matches(labels.Set(ns.Labels)), nil
+}
+
+// policyPermits returns true if the given policy permits the domain and
+// service type of the given service instance.
+func policyPermits(p crd.DNSSDPolicy, res *crd.DNSSDServiceInstance) bool {
+	if len(p.Spec.Domains) != 0 && !slices.ContainsFunc(
+		p.Spec.Domains,
+		func(d string) bool {
+			return provider.IsWithinDomain(res.Spec.Instance.Domain, d)
+		},
+	) {
+		return false
+	}
+
+	if len(p.Spec.ServiceTypes) != 0 && !slices.ContainsFunc(
+		p.Spec.ServiceTypes,
+		func(t string) bool {
+			return strings.EqualFold(t, res.Spec.Instance.ServiceType)
+		},
+	) {
+		return false
+	}
+
+	return true
+}
+
+// instancesAffectedByPolicy returns a request for each service instance
+// managed by the reconciler, as any of them may be affected by a change to a
+// crd.DNSSDPolicy.
+func (r *Reconciler) instancesAffectedByPolicy(
+	ctx context.Context,
+	_ client.Object,
+) []reconcile.Request {
+	list := &crd.DNSSDServiceInstanceList{}
+	if err := r.Client.List(ctx, list); err != nil {
+		r.Logger.Error(err, "unable to list service instances affected by policy")
+		return nil
+	}
+
+	return r.responsibleInstances(list.Items)
+}
+
+// instancesInNamespace returns a request for each service instance managed by
+// the reconciler within the given namespace, as the policies that apply to
+// them may be affected by a change to the namespace's labels.
+func (r *Reconciler) instancesInNamespace(
+	ctx context.Context,
+	obj client.Object,
+) []reconcile.Request {
+	list := &crd.DNSSDServiceInstanceList{}
+	if err := r.Client.List(
+		ctx,
+		list,
+		client.InNamespace(obj.GetName()),
+	); err != nil {
+		r.Logger.Error(
+			err,
+			"unable to list service instances in namespace",
+			"namespace", obj.GetName(),
+		)
+		return nil
+	}
+
+	return r.responsibleInstances(list.Items)
+}
+
+// responsibleInstances returns a request for each of the given instances that
+// is managed by the reconciler.
+func (r *Reconciler) responsibleInstances(
+	instances []crd.DNSSDServiceInstance,
+) []reconcile.Request {
+	var requests []reconcile.Request
+
+	for _, res := range instances {
+		if r.isResponsible(&res) {
+			requests = append(
+				requests,
+				reconcile.Request{
+					NamespacedName: client.ObjectKeyFromObject(&res),
+				},
+			)
+		}
+	}
+
+	return requests
+}
@@ -0,0 +1,80 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dogmatiq/proclaim/crd"
+	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconciler_policyWatches(t *testing.T) {
+	setup := func(t *testing.T) (*Reconciler, *crd.DNSSDServiceInstance) {
+		t.Helper()
+
+		cli, res := setupTestInstance(t)
+
+		// Add an instance in another namespace, and one with a different class
+		// that is never re-queued because the reconciler does not manage it.
+		for _, x := range []*crd.DNSSDServiceInstance{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "instance"},
+				Spec:       res.Spec,
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "classy"},
+				Spec: crd.DNSSDServiceInstanceSpec{
+					Instance:          res.Spec.Instance,
+					InstanceClassName: "<class>",
+				},
+			},
+		} {
+			if err := cli.Create(context.Background(), x); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return &Reconciler{Client: cli}, res
+	}
+
+	request := func(ns, name string) reconcile.Request {
+		return reconcile.Request{
+			NamespacedName: client.ObjectKey{Namespace: ns, Name: name},
+		}
+	}
+
+	t.Run("it re-queues the managed instances in a namespace when it changes", func(t *testing.T) {
+		r, _ := setup(t)
+
+		got := r.instancesInNamespace(
+			context.Background(),
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		)
+		want := []reconcile.Request{request("default", "instance")}
+
+		if !slices.Equal(got, want) {
+			t.Fatalf("unexpected requests: got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("it re-queues every managed instance when a policy changes", func(t *testing.T) {
+		r, _ := setup(t)
+
+		got := r.instancesAffectedByPolicy(
+			context.Background(),
+			&crd.DNSSDPolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy"}},
+		)
+		want := []reconcile.Request{
+			request("default", "instance"),
+			request("other", "instance"),
+		}
+
+		if !slices.Equal(got, want) {
+			t.Fatalf("unexpected requests: got %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// propagationPollInterval is the interval at which the propagation of changes
+// to DNS records is checked.
+const propagationPollInterval = 10 * time.Second
+
+// propagationTimeout is the amount of time after the DNS records are advertised
+// that the reconciler continues to check the propagation of the changes if the
+// provider is unable to report it. After this time the changes are assumed to
+// have propagated.
+const propagationTimeout = 5 * time.Minute
+
+// waitForPropagation checks whether the changes most recently made to the
+// DNS records of the given service instance have propagated to all of the
+// provider's name servers.
+//
+// It returns true if the changes are still propagating, in which case the
+// instance must not be advertised or discovered until the returned result is
+// re-queued.
+//
+// If the provider is not configured, or repeatedly fails to report whether the
+// changes have propagated, the changes are abandoned after propagationTimeout,
+// and the error is reported by the Advertised condition.
+func (r *Reconciler) waitForPropagation(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) (reconcile.Result, bool, error) {
+	a := res.Condition(crd.ConditionTypeAdvertised)
+
+	if a.Reason != crd.ReasonPropagating || a.ObservedGeneration < res.Generation {
+		// Either nothing is propagating, or the resource has been modified and
+		// the changes will be superseded by advertising it again.
+		return reconcile.Result{}, false, nil
+	}
+
+	adv, ok, err := r.getAdvertiser(ctx, res)
+	if err != nil {
+		return reconcile.Result{}, false, err
+	}
+
+	if !ok {
+		// Without the provider there is no way to tell whether the changes
+		// have propagated, which is not the same as knowing that they have.
+		return r.retryPropagation(ctx, res, errors.New("the provider is not configured"))
+	}
+
+	if c, ok := provider.AsPropagationChecker(adv); ok {
+		for _, id := range res.Status.PropagatingChanges {
+			done, err := c.IsPropagated(ctx, id)
+			if err != nil {
+				crd.ProviderError(
+					r.Manager,
+					res,
+					res.Status.Provider,
+					res.Status.ProviderDescription,
+					err,
+				)
+
+				return r.retryPropagation(ctx, res, err)
+			}
+
+			if !done {
+				return r.requeuePropagation(ctx, res)
+			}
+		}
+	}
+
+	crd.DNSRecordsPropagated(r.Manager, res)
+
+	return reconcile.Result{}, false, r.update(
+		res,
+		crd.MergeCondition(crd.DNSRecordsPropagatedCondition()),
+		crd.UpdatePropagatingChanges(nil),
+	)
+}
+
+// retryPropagation re-queues the given service instance after its provider
+// was unable to report whether the changes to its DNS records have propagated.
+//
+// Once propagationTimeout has elapsed, the changes are abandoned instead, so
+// that the instance is no longer prevented from being discovered.
+func (r *Reconciler) retryPropagation(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+	err error,
+) (reconcile.Result, bool, error) {
+	if propagationTimedOut(res) {
+		return reconcile.Result{}, false, r.abandonPropagation(ctx, res, err)
+	}
+	return r.requeuePropagation(ctx, res)
+}
+
+// requeuePropagation re-queues the given service instance so that the
+// propagation of the changes to its DNS records is checked again.
+func (r *Reconciler) requeuePropagation(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) (reconcile.Result, bool, error) {
+	r.Logger.Info(
+		"re-queuing",
+		"namespace", res.Namespace,
+		"name", res.Name,
+		"reason", "changes are propagating",
+		"next", propagationPollInterval,
+	)
+
+	return reconcile.Result{
+		Requeue:      true,
+		RequeueAfter: propagationPollInterval,
+	}, true, ctx.Err()
+}
+
+// propagationTimedOut returns true if the changes to the given service
+// instance's DNS records were made more than propagationTimeout ago.
+func propagationTimedOut(res *crd.DNSSDServiceInstance) bool {
+	if res.Status.LastAdvertised == nil {
+		return true
+	}
+
+	return time.Since(res.Status.LastAdvertised.Time) >= propagationTimeout
+}
+
+// abandonPropagation stops waiting for the changes to the given service
+// instance's DNS records to propagate after the provider has been unable to
+// report their status for too long.
+func (r *Reconciler) abandonPropagation(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+	err error,
+) error {
+	r.Logger.Info(
+		"abandoning propagation check",
+		"namespace", res.Namespace,
+		"name", res.Name,
+		"timeout", propagationTimeout,
+		"error", err.Error(),
+	)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return r.update(
+		res,
+		crd.MergeCondition(crd.DNSRecordsPropagationUnknownCondition(err)),
+		crd.UpdatePropagatingChanges(nil),
+	)
+}
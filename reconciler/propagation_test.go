@@ -0,0 +1,198 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReconciler_waitForPropagation(t *testing.T) {
+	setupWith := func(
+		t *testing.T,
+		p provider.Provider,
+		advertised time.Time,
+	) (*Reconciler, *crd.DNSSDServiceInstance) {
+		t.Helper()
+
+		cli, res := setupTestInstance(t)
+
+		r := &Reconciler{
+			Manager:        &testManager{recorder: record.NewFakeRecorder(100)},
+			Client:         cli,
+			Logger:         logr.Discard(),
+			ControllerName: "a",
+			Providers:      []provider.Provider{p},
+		}
+
+		if err := r.update(
+			res,
+			crd.AssociateProvider(p.ID(), nil),
+			crd.AssociateController("a"),
+			crd.MergeCondition(crd.DNSRecordsPropagatingCondition()),
+			crd.UpdatePropagatingChanges([]string{"C123"}),
+			func(res *crd.DNSSDServiceInstance) {
+				res.Status.LastAdvertised = &metav1.Time{Time: advertised}
+			},
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		return r, res
+	}
+
+	setup := func(t *testing.T, advertised time.Time) (*Reconciler, *crd.DNSSDServiceInstance) {
+		t.Helper()
+
+		return setupWith(
+			t,
+			&testProvider{
+				id:         "provider-a",
+				advertiser: &uncheckableAdvertiser{},
+			},
+			advertised,
+		)
+	}
+
+	t.Run("it keeps waiting if the provider can not report propagation", func(t *testing.T) {
+		r, res := setup(t, time.Now())
+
+		result, propagating, err := r.waitForPropagation(context.Background(), res)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !propagating {
+			t.Fatal("expected the changes to be propagating")
+		}
+
+		if result.RequeueAfter != propagationPollInterval {
+			t.Fatalf("unexpected requeue interval: got %s, want %s", result.RequeueAfter, propagationPollInterval)
+		}
+	})
+
+	t.Run("it stops waiting after the propagation timeout", func(t *testing.T) {
+		r, res := setup(t, time.Now().Add(-propagationTimeout))
+
+		_, propagating, err := r.waitForPropagation(context.Background(), res)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if propagating {
+			t.Fatal("did not expect the changes to be propagating")
+		}
+
+		if len(res.Status.PropagatingChanges) != 0 {
+			t.Fatalf("expected the change IDs to be removed, got %v", res.Status.PropagatingChanges)
+		}
+
+		c := res.Condition(crd.ConditionTypeAdvertised)
+		if c.Status != metav1.ConditionTrue || c.Reason != "PropagationUnknown" {
+			t.Fatalf("unexpected condition: %s %s", c.Status, c.Reason)
+		}
+	})
+
+	t.Run("it keeps waiting while the provider is not configured", func(t *testing.T) {
+		r, res := setup(t, time.Now())
+		r.Providers = nil
+
+		_, propagating, err := r.waitForPropagation(context.Background(), res)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !propagating {
+			t.Fatal("expected the changes to be propagating")
+		}
+
+		if c := res.Condition(crd.ConditionTypeAdvertised); c.Reason != crd.ReasonPropagating {
+			t.Fatalf("unexpected reason: got %q, want %q", c.Reason, crd.ReasonPropagating)
+		}
+	})
+
+	t.Run("it keeps waiting if the provider fails", func(t *testing.T) {
+		r, res := setup(t, time.Now())
+		r.Providers[0].(*testProvider).err = errors.New("<error>")
+
+		if _, _, err := r.waitForPropagation(context.Background(), res); err == nil {
+			t.Fatal("expected an error")
+		}
+
+		if c := res.Condition(crd.ConditionTypeAdvertised); c.Reason != crd.ReasonPropagating {
+			t.Fatalf("unexpected reason: got %q, want %q", c.Reason, crd.ReasonPropagating)
+		}
+	})
+
+	policy := provider.DomainPolicy{
+		Allowed: []string{"example.org"},
+	}
+
+	cases := []struct {
+		Name     string
+		Provider provider.Provider
+	}{
+		{
+			"restricted provider",
+			provider.Restrict(
+				&testProvider{
+					id:         "provider-a",
+					advertiser: &pendingAdvertiser{},
+				},
+				policy,
+			),
+		},
+		{
+			"DNSProvider resource",
+			&dynamicProvider{
+				Provider: &testProvider{
+					id:         "provider-a",
+					advertiser: &pendingAdvertiser{},
+				},
+				name:   "provider-a",
+				policy: policy,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run("it checks the propagation of changes made by a "+c.Name, func(t *testing.T) {
+			r, res := setupWith(t, c.Provider, time.Now())
+
+			_, propagating, err := r.waitForPropagation(context.Background(), res)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !propagating {
+				t.Fatal("expected the changes to be propagating")
+			}
+		})
+	}
+}
+
+// pendingAdvertiser is a provider.PropagationChecker that reports that no
+// change has propagated.
+type pendingAdvertiser struct {
+	testAdvertiser
+}
+
+func (*pendingAdvertiser) IsPropagated(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+// uncheckableAdvertiser is a provider.PropagationChecker that is unable to
+// determine whether any change has propagated.
+type uncheckableAdvertiser struct {
+	testAdvertiser
+}
+
+func (*uncheckableAdvertiser) IsPropagated(context.Context, string) (bool, error) {
+	return false, errors.New("<error>")
+}
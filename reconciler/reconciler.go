@@ -3,14 +3,18 @@ package reconciler
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/dogmatiq/dissolve/dnssd"
 	"github.com/dogmatiq/proclaim/crd"
 	"github.com/dogmatiq/proclaim/provider"
+	"github.com/dogmatiq/proclaim/resolver"
 	"github.com/go-logr/logr"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -21,9 +25,99 @@ import (
 type Reconciler struct {
 	Manager   manager.Manager
 	Client    client.Client
-	Resolver  *dnssd.UnicastResolver
+	Resolver  resolver.Resolver
 	Providers []provider.Provider
 	Logger    logr.Logger
+
+	// APIReader is used to read the ConfigMaps and Secrets referenced by
+	// attributes directly from the API server, bypassing the cache. If it is
+	// nil, Client is used instead.
+	APIReader client.Reader
+
+	// Scoped indicates that the cache only contains some of the service
+	// instances in the cluster, because it is restricted to specific
+	// namespaces or by a label selector. If it is true, a separate cache of
+	// every instance in the cluster is used to find the instances that may
+	// conflict with, or share enumeration records with, a managed instance.
+	Scoped bool
+
+	// DisableDiscovery disables DNS-SD discovery for instances that do not
+	// explicitly enable it in their spec.
+	DisableDiscovery bool
+
+	// DriftDetectionInterval is the interval at which advertised instances are
+	// checked for drift, unless overridden by the instance's spec. If it is
+	// zero, the interval is 10 times the instance's TTL.
+	DriftDetectionInterval time.Duration
+
+	// EnableServiceTypeEnumeration enables advertisement of each instance's
+	// service type within the service type enumeration domain of its domain.
+	EnableServiceTypeEnumeration bool
+
+	// EnableBrowsingDomainEnumeration enables advertisement of each
+	// instance's domain as a browsing domain of its parent domain.
+	EnableBrowsingDomainEnumeration bool
+
+	// EnableSecretAttributeSources enables the use of Secrets as attribute
+	// sources. If it is false, Secrets are neither read nor watched, so the
+	// reconciler does not require permission to access them, and attributes
+	// that refer to a Secret can not be resolved.
+	EnableSecretAttributeSources bool
+
+	// InstanceClass is the instance class managed by the reconciler. The
+	// reconciler ignores any instances with a different class. If it is empty,
+	// only instances without a class are managed.
+	InstanceClass string
+
+	// HealthCheckInterval is the interval at which the health of each provider
+	// is checked. If it is zero, providers are checked every minute.
+	HealthCheckInterval time.Duration
+
+	// HealthStatusConfigMap identifies a ConfigMap to which the results of the
+	// provider health checks are written. If the name is empty, the results
+	// are only logged and reported by CheckProviders.
+	HealthStatusConfigMap types.NamespacedName
+
+	// ControllerName identifies this controller among any other deployments of
+	// Proclaim in the same cluster. It is recorded in the status of each
+	// instance that the reconciler associates with a provider.
+	ControllerName string
+
+	// MissingProviderPolicy determines how instances that are associated with
+	// a provider that is not configured are handled. If it is empty,
+	// OrphanRecords is used.
+	//
+	// The policy is only applied to instances that were associated with their
+	// provider by this controller, as identified by ControllerName. Any other
+	// instance is assumed to be managed by another deployment of Proclaim.
+	MissingProviderPolicy MissingProviderPolicy
+
+	// DryRun prevents the reconciler from modifying DNS records. Instead, the
+	// changes that would be made are reported via events and the instance's
+	// status. Instances are not adopted by providers, and the deletion of an
+	// instance is never blocked.
+	DryRun bool
+
+	// BuildProvider builds a provider from the specification of a
+	// crd.DNSProvider resource and the content of its credentials Secret, if
+	// any. If it is nil, DNSProvider resources are not supported.
+	BuildProvider func(
+		ctx context.Context,
+		spec crd.DNSProviderSpec,
+		credentials map[string][]byte,
+	) (provider.Provider, error)
+
+	started atomic.Bool
+
+	// instances is a cache of every service instance in the cluster. It is
+	// only used if the reconciler is scoped.
+	instances cache.Cache
+
+	healthM sync.RWMutex
+	health  map[string]providerHealth
+
+	dynamicM sync.RWMutex
+	dynamic  map[string]*dynamicProvider
 }
 
 // Reconcile performs a full reconciliation for the object referred to by the
@@ -46,12 +140,34 @@ func (r *Reconciler) Reconcile(
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
+	ctx = withParentAdvertiserCache(ctx)
+
 	// Lookup the resource so we know whether to advertise or unadvertise.
 	res := &crd.DNSSDServiceInstance{}
 	if err := r.Client.Get(ctx, req.NamespacedName, res); err != nil {
 		return reconcile.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !r.isResponsible(res) {
+		r.Logger.Info(
+			"ignoring",
+			"resource", req.NamespacedName,
+			"reason", "instance class is managed by another controller",
+			"class", res.Spec.InstanceClassName,
+		)
+		return reconcile.Result{}, nil
+	}
+
+	if paused, err := r.pause(res); paused || err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if !r.DryRun {
+		if err := r.clearPlan(res); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
 	if requeue, err := r.initialize(ctx, res); err != nil {
 		return reconcile.Result{}, err
 	} else if requeue {
@@ -70,9 +186,18 @@ func (r *Reconciler) Reconcile(
 	if res.ObjectMeta.DeletionTimestamp.IsZero() {
 		return r.advertise(ctx, res)
 	}
+	if r.DryRun {
+		return r.planUnadvertise(ctx, res)
+	}
 	return r.unadvertise(ctx, res)
 }
 
+// isResponsible returns true if the reconciler manages instances of the given
+// resource's instance class.
+func (r *Reconciler) isResponsible(res *crd.DNSSDServiceInstance) bool {
+	return res.Spec.InstanceClassName == r.InstanceClass
+}
+
 func (r *Reconciler) initialize(
 	_ context.Context,
 	res *crd.DNSSDServiceInstance,
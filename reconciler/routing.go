@@ -0,0 +1,57 @@
+package reconciler
+
+import (
+	"github.com/dogmatiq/proclaim/crd"
+	"github.com/dogmatiq/proclaim/provider"
+)
+
+// withRoutingPolicy returns an advertiser that advertises the given service
+// instance using the routing policy in its spec, if any.
+//
+// ok is false if the instance has a routing policy, but the advertiser does
+// not support routing policies.
+func withRoutingPolicy(
+	a provider.Advertiser,
+	res *crd.DNSSDServiceInstance,
+) (_ provider.Advertiser, ok bool, _ error) {
+	r := res.Spec.Routing
+	if r == nil {
+		return a, true, nil
+	}
+
+	ra, ok := provider.AsRoutingPolicyAdvertiser(a)
+	if !ok {
+		return nil, false, nil
+	}
+
+	p := provider.RoutingPolicy{
+		SetIdentifier: r.SetIdentifier,
+		Weight:        r.Weight,
+		Region:        r.Region,
+		Failover:      r.Failover,
+	}
+
+	if g := r.Geolocation; g != nil {
+		p.Geolocation = &provider.Geolocation{
+			Continent:   g.Continent,
+			Country:     g.Country,
+			Subdivision: g.Subdivision,
+		}
+	}
+
+	if hc := r.HealthCheck; hc != nil {
+		p.HealthCheck = &provider.HealthCheck{
+			ID:               hc.ID,
+			Protocol:         hc.Protocol,
+			Path:             hc.Path,
+			FailureThreshold: hc.FailureThreshold,
+		}
+	}
+
+	a, err := ra.WithRoutingPolicy(p)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return a, true, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/dogmatiq/dissolve/dnssd"
 	"github.com/dogmatiq/proclaim/crd"
 	"github.com/dogmatiq/proclaim/provider"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -20,39 +21,28 @@ func (r *Reconciler) unadvertise(
 	ctx context.Context,
 	res *crd.DNSSDServiceInstance,
 ) (reconcile.Result, error) {
-	a, ok, err := r.shouldUnadvertise(ctx, res)
+	a, ok, block, err := r.shouldUnadvertise(ctx, res)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
-	if ok {
-		advertised := res.Condition(crd.ConditionTypeAdvertised)
+	if block {
+		r.Logger.Info(
+			"re-queueing",
+			"namespace", res.Namespace,
+			"name", res.Name,
+			"reason", "provider unavailable",
+		)
+		return reconcile.Result{Requeue: true}, nil
+	}
 
-		changed, err := a.Unadvertise(ctx, res.Spec.ToDissolve())
+	if ok {
+		removed, err := r.removeRecords(ctx, a, res)
 		if err != nil {
-			crd.ProviderError(
-				r.Manager,
-				res,
-				res.Status.Provider,
-				res.Status.ProviderDescription,
-				err,
-			)
-			advertised = crd.UnadvertiseErrorCondition(err)
-		} else if changed {
-			crd.DNSRecordsDeleted(r.Manager, res)
-			advertised = crd.DNSRecordsDeletedCondition()
-		} else {
-			advertised = crd.DNSRecordsDoNotExistCondition()
-		}
-
-		if err := r.update(
-			res,
-			crd.MergeCondition(advertised),
-		); err != nil {
 			return reconcile.Result{}, err
 		}
 
-		if advertised.Status != metav1.ConditionFalse {
+		if !removed {
 			r.Logger.Info(
 				"re-queueing",
 				"namespace", res.Namespace,
@@ -78,21 +68,121 @@ func (r *Reconciler) unadvertise(
 	return reconcile.Result{}, nil
 }
 
+// removeRecords removes the DNS records of the given service instance using a
+// specific advertiser, and updates its Advertised condition accordingly.
+//
+// It returns true if the records are known to have been removed.
+func (r *Reconciler) removeRecords(
+	ctx context.Context,
+	a provider.Advertiser,
+	res *crd.DNSSDServiceInstance,
+) (bool, error) {
+	inst, err := r.unadvertisedInstance(ctx, res)
+	if err != nil {
+		return false, err
+	}
+
+	advertised := res.Condition(crd.ConditionTypeAdvertised)
+
+	changed, err := a.Unadvertise(ctx, inst)
+	if err == nil {
+		var ok bool
+		ok, err = r.unadvertiseStaleAddresses(ctx, a, res, "")
+		changed = changed || ok
+	}
+	if err == nil {
+		var ok bool
+		ok, err = r.unadvertiseEnumeration(ctx, a, res, inst)
+		changed = changed || ok
+	}
+
+	if err != nil {
+		crd.ProviderError(
+			r.Manager,
+			res,
+			res.Status.Provider,
+			res.Status.ProviderDescription,
+			err,
+		)
+		advertised = crd.UnadvertiseErrorCondition(err)
+	} else if changed {
+		crd.DNSRecordsDeleted(r.Manager, res)
+		advertised = crd.DNSRecordsDeletedCondition()
+	} else {
+		advertised = crd.DNSRecordsDoNotExistCondition()
+	}
+
+	removed := advertised.Status == metav1.ConditionFalse
+
+	return removed, r.update(
+		res,
+		crd.MergeCondition(advertised),
+		crd.If(removed, crd.RecordsUnadvertised()),
+	)
+}
+
+// unadvertisedInstance returns the service instance whose records are removed
+// when the given instance is unadvertised.
+//
+// It is built from the records in the instance's status where possible, as
+// they describe what was actually advertised. Otherwise, the attribute values
+// are resolved from the spec. The records to remove are identified by the
+// instance's name, so if the attribute values can not be resolved, for
+// example because the referenced Secret has already been deleted, they are
+// omitted.
+func (r *Reconciler) unadvertisedInstance(
+	ctx context.Context,
+	res *crd.DNSSDServiceInstance,
+) (dnssd.ServiceInstance, error) {
+	if inst, _, ok := parseInstance(res, res.Status.Records); ok {
+		return inst, nil
+	}
+
+	spec, err := r.resolveSpec(ctx, res)
+	if err == nil {
+		return spec.ToDissolve(), nil
+	}
+
+	if !isAttributeSourceError(err) {
+		return dnssd.ServiceInstance{}, fmt.Errorf("unable to resolve attributes: %w", err)
+	}
+
+	return res.Spec.ToDissolve(), nil
+}
+
 func (r *Reconciler) shouldUnadvertise(
 	ctx context.Context,
 	res *crd.DNSSDServiceInstance,
-) (adv provider.Advertiser, should bool, err error) {
+) (adv provider.Advertiser, should, block bool, err error) {
 	a := res.Condition(crd.ConditionTypeAdvertised)
 
 	reason := ""
 
-	if a.Status == metav1.ConditionFalse {
+	winner, err := r.conflictWinner(ctx, res)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	if winner != nil {
+		// The records belong to the instance that takes precedence.
+		should = false
+		reason = "records belong to conflicting instance"
+	} else if a.Status == metav1.ConditionFalse {
 		should = false
 		reason = "not advertised"
+	} else if res.Status.Provider == "" {
+		should = false
+		reason = "not adopted"
 	} else {
 		adv, should, err = r.getAdvertiser(ctx, res)
 		if err != nil {
-			return nil, false, err
+			return nil, false, false, err
+		}
+		if !should {
+			adv, should, block, err = r.missingProvider(ctx, res)
+			if err != nil {
+				return nil, false, false, err
+			}
 		}
 		if !should {
 			reason = "unrecognized provider"
@@ -117,5 +207,5 @@ func (r *Reconciler) shouldUnadvertise(
 		"reason", reason,
 	)
 
-	return adv, should, nil
+	return adv, should, block, nil
 }
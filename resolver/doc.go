@@ -0,0 +1,3 @@
+// Package resolver contains abstractions for discovering DNS-SD service
+// instances via DNS queries.
+package resolver
@@ -0,0 +1,3 @@
+// Package dohresolver provides a resolver implementation that performs DNS-SD
+// queries using DNS-over-HTTPS (DoH), as described in RFC 8484.
+package dohresolver
@@ -0,0 +1,288 @@
+package dohresolver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+	"github.com/dogmatiq/proclaim/resolver"
+	"github.com/miekg/dns"
+	"golang.org/x/sync/errgroup"
+)
+
+// mediaType is the media type of DNS messages sent and received via
+// DNS-over-HTTPS.
+//
+// See https://www.rfc-editor.org/rfc/rfc8484#section-6.
+const mediaType = "application/dns-message"
+
+// Resolver is an implementation of resolver.Resolver that performs DNS-SD
+// queries using DNS-over-HTTPS.
+type Resolver struct {
+	// Client is the HTTP client used to make requests. If it is nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// URLs is the list of DoH endpoints to query, for example
+	// "https://dns.google/dns-query". They are tried in order until one of
+	// them responds.
+	URLs []string
+
+	// Timeout is the timeout for each request made to a DoH endpoint. If it
+	// is zero, no timeout is applied beyond that of the context.
+	Timeout time.Duration
+}
+
+var _ resolver.Resolver = (*Resolver)(nil)
+
+// EnumerateInstances finds all of the instances of a given service type that
+// are advertised within a single domain.
+func (r *Resolver) EnumerateInstances(
+	ctx context.Context,
+	serviceType, domain string,
+) ([]string, error) {
+	return r.enumerate(
+		ctx,
+		dnssd.AbsoluteInstanceEnumerationDomain(serviceType, domain),
+	)
+}
+
+// EnumerateInstancesBySubType finds all of the instances of a given service
+// type that are advertised within a single domain as providing a specific
+// service sub-type.
+func (r *Resolver) EnumerateInstancesBySubType(
+	ctx context.Context,
+	subType, serviceType, domain string,
+) ([]string, error) {
+	return r.enumerate(
+		ctx,
+		dnssd.AbsoluteSelectiveInstanceEnumerationDomain(subType, serviceType, domain),
+	)
+}
+
+// enumerate returns the names of the instances referred to by the PTR records
+// at the given name.
+func (r *Resolver) enumerate(
+	ctx context.Context,
+	name string,
+) ([]string, error) {
+	res, ok, err := r.query(ctx, name, dns.TypePTR)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	instances := make([]string, 0, len(res.Answer))
+
+	for _, rr := range res.Answer {
+		if ptr, ok := rr.(*dns.PTR); ok {
+			instance, _, err := dnssd.ParseInstance(ptr.Ptr)
+			if err == nil {
+				instances = append(instances, instance)
+			}
+		}
+	}
+
+	return instances, nil
+}
+
+// LookupInstance looks up the details about a specific service instance.
+//
+// ok is false if the instance can not be resolved.
+func (r *Resolver) LookupInstance(
+	ctx context.Context,
+	instance, serviceType, domain string,
+) (_ dnssd.ServiceInstance, ok bool, _ error) {
+	queryName := dnssd.AbsoluteServiceInstanceName(instance, serviceType, domain)
+	responses := make(chan *dns.Msg, 2)
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	for _, t := range []uint16{dns.TypeSRV, dns.TypeTXT} {
+		g.Go(func() error {
+			res, ok, err := r.query(ctx, queryName, t)
+			if ok {
+				responses <- res
+			}
+			return err
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return dnssd.ServiceInstance{}, false, err
+	}
+
+	close(responses)
+
+	i := dnssd.ServiceInstance{
+		ServiceInstanceName: dnssd.ServiceInstanceName{
+			Name:        instance,
+			ServiceType: serviceType,
+			Domain:      domain,
+		},
+		TTL: math.MaxInt64,
+	}
+
+	var hasSRV, hasTXT bool
+
+	for res := range responses {
+		for _, rr := range res.Answer {
+			ttl := time.Duration(rr.Header().Ttl) * time.Second
+			if ttl < i.TTL {
+				i.TTL = ttl
+			}
+
+			switch rr := rr.(type) {
+			case *dns.SRV:
+				hasSRV = true
+				i.TargetHost = strings.TrimSuffix(rr.Target, ".")
+				i.TargetPort = rr.Port
+				i.Priority = rr.Priority
+				i.Weight = rr.Weight
+			case *dns.TXT:
+				hasTXT = true
+				if err := unpackTXT(&i, rr); err != nil {
+					return dnssd.ServiceInstance{}, false, err
+				}
+			}
+		}
+	}
+
+	return i, hasSRV && hasTXT, nil
+}
+
+// unpackTXT unpacks information from a TXT record into i.
+func unpackTXT(i *dnssd.ServiceInstance, rr *dns.TXT) error {
+	var attrs dnssd.Attributes
+
+	for _, pair := range rr.Txt {
+		var err error
+		attrs, _, err = attrs.WithTXT(pair)
+		if err != nil {
+			return fmt.Errorf("unable to parse TXT record: %w", err)
+		}
+	}
+
+	if !attrs.IsEmpty() {
+		i.Attributes = append(i.Attributes, attrs)
+	}
+
+	return nil
+}
+
+// query performs a DNS query against each of the endpoints in r.URLs until
+// one of them produces an authoritative result.
+//
+// ok is false if an endpoint responded authoritatively that the name does not
+// exist. An error is returned if none of the endpoints produced an
+// authoritative result.
+func (r *Resolver) query(
+	ctx context.Context,
+	name string,
+	questionType uint16,
+) (_ *dns.Msg, ok bool, _ error) {
+	if len(r.URLs) == 0 {
+		return nil, false, errors.New("no DNS-over-HTTPS endpoints are configured")
+	}
+
+	req := &dns.Msg{}
+	req.SetQuestion(name, questionType)
+
+	// The DNS ID SHOULD be zero to maximize HTTP cache friendliness.
+	//
+	// See https://www.rfc-editor.org/rfc/rfc8484#section-4.1.
+	req.Id = 0
+
+	body, err := req.Pack()
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to pack DNS query: %w", err)
+	}
+
+	var errs []error
+
+	for _, u := range r.URLs {
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+
+		res, err := r.queryEndpoint(ctx, u, body)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", u, err))
+			continue
+		}
+
+		switch res.Rcode {
+		case dns.RcodeSuccess:
+			// The server had an answer to this query, even if it was only to
+			// indicate that there are no records of this type.
+			return res, true, nil
+		case dns.RcodeNameError:
+			// The server responded authoritatively that the name does not
+			// exist.
+			return nil, false, nil
+		default:
+			errs = append(errs, fmt.Errorf("%s: %s", u, dns.RcodeToString[res.Rcode]))
+		}
+	}
+
+	return nil, false, fmt.Errorf(
+		"unable to query %s %s records: %w",
+		name,
+		dns.TypeToString[questionType],
+		errors.Join(errs...),
+	)
+}
+
+// queryEndpoint sends a packed DNS query to a single DoH endpoint.
+func (r *Resolver) queryEndpoint(
+	ctx context.Context,
+	u string,
+	body []byte,
+) (*dns.Msg, error) {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", mediaType)
+	req.Header.Set("Accept", mediaType)
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response: %w", err)
+	}
+
+	m := &dns.Msg{}
+	if err := m.Unpack(data); err != nil {
+		return nil, fmt.Errorf("unable to unpack DNS response: %w", err)
+	}
+
+	return m, nil
+}
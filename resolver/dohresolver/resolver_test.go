@@ -0,0 +1,262 @@
+package dohresolver_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+	. "github.com/dogmatiq/proclaim/resolver/dohresolver"
+	"github.com/miekg/dns"
+)
+
+func TestResolver(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	inst := dnssd.ServiceInstance{
+		ServiceInstanceName: dnssd.ServiceInstanceName{
+			Name:        "Instance A",
+			ServiceType: "_test._tcp",
+			Domain:      "example.org",
+		},
+		TargetHost: "a.example.org",
+		TargetPort: 1234,
+		Priority:   10,
+		Weight:     20,
+		Attributes: dnssd.AttributeCollection{
+			dnssd.NewAttributes().
+				WithPair("key", []byte("value")).
+				WithFlag("flag"),
+		},
+		TTL: 60 * time.Second,
+	}
+
+	server := httptest.NewServer(
+		newHandler(dnssd.NewRecords(inst, dnssd.WithServiceSubType("_printer"))),
+	)
+	t.Cleanup(server.Close)
+
+	resolver := &Resolver{
+		URLs: []string{server.URL},
+	}
+
+	t.Run("EnumerateInstances()", func(t *testing.T) {
+		t.Run("it returns the advertised instances", func(t *testing.T) {
+			instances, err := resolver.EnumerateInstances(ctx, inst.ServiceType, inst.Domain)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(instances) != 1 || instances[0] != inst.Name {
+				t.Fatalf("unexpected instances: %q", instances)
+			}
+		})
+
+		t.Run("it returns an empty result when there are no instances", func(t *testing.T) {
+			instances, err := resolver.EnumerateInstances(ctx, "_other._tcp", inst.Domain)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(instances) != 0 {
+				t.Fatalf("unexpected instances: %q", instances)
+			}
+		})
+	})
+
+	t.Run("EnumerateInstancesBySubType()", func(t *testing.T) {
+		t.Run("it returns the instances that provide the sub-type", func(t *testing.T) {
+			instances, err := resolver.EnumerateInstancesBySubType(ctx, "_printer", inst.ServiceType, inst.Domain)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(instances) != 1 || instances[0] != inst.Name {
+				t.Fatalf("unexpected instances: %q", instances)
+			}
+		})
+
+		t.Run("it returns an empty result when no instances provide the sub-type", func(t *testing.T) {
+			instances, err := resolver.EnumerateInstancesBySubType(ctx, "_scanner", inst.ServiceType, inst.Domain)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(instances) != 0 {
+				t.Fatalf("unexpected instances: %q", instances)
+			}
+		})
+	})
+
+	t.Run("LookupInstance()", func(t *testing.T) {
+		t.Run("it returns the instance details", func(t *testing.T) {
+			actual, ok, err := resolver.LookupInstance(ctx, inst.Name, inst.ServiceType, inst.Domain)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !ok {
+				t.Fatal("expected ok to be true")
+			}
+
+			if !actual.Equal(inst) {
+				t.Fatalf("unexpected instance: got %+v, want %+v", actual, inst)
+			}
+		})
+
+		t.Run("it returns false when the instance does not exist", func(t *testing.T) {
+			_, ok, err := resolver.LookupInstance(ctx, "Instance B", inst.ServiceType, inst.Domain)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok {
+				t.Fatal("expected ok to be false")
+			}
+		})
+	})
+}
+
+func TestResolver_errors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	inst := dnssd.ServiceInstance{
+		ServiceInstanceName: dnssd.ServiceInstanceName{
+			Name:        "Instance A",
+			ServiceType: "_test._tcp",
+			Domain:      "example.org",
+		},
+		TargetHost: "a.example.org",
+		TargetPort: 1234,
+		TTL:        60 * time.Second,
+	}
+
+	healthy := httptest.NewServer(newHandler(dnssd.NewRecords(inst)))
+	t.Cleanup(healthy.Close)
+
+	failing := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}),
+	)
+	t.Cleanup(failing.Close)
+
+	stop := make(chan struct{})
+	unresponsive := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-stop:
+			}
+		}),
+	)
+	t.Cleanup(unresponsive.Close)
+	t.Cleanup(func() { close(stop) })
+
+	t.Run("it returns an error when the endpoint responds with a non-200 status", func(t *testing.T) {
+		resolver := &Resolver{
+			URLs: []string{failing.URL},
+		}
+
+		if _, err := resolver.EnumerateInstances(ctx, inst.ServiceType, inst.Domain); err == nil {
+			t.Fatal("expected an error")
+		}
+
+		if _, _, err := resolver.LookupInstance(ctx, inst.Name, inst.ServiceType, inst.Domain); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("it returns an error when the endpoint times out", func(t *testing.T) {
+		resolver := &Resolver{
+			URLs:    []string{unresponsive.URL},
+			Timeout: 10 * time.Millisecond,
+		}
+
+		if _, err := resolver.EnumerateInstances(ctx, inst.ServiceType, inst.Domain); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("it returns an error when the endpoint is not contactable", func(t *testing.T) {
+		resolver := &Resolver{
+			URLs: []string{"https://127.0.0.1:1/dns-query"},
+		}
+
+		if _, err := resolver.EnumerateInstances(ctx, inst.ServiceType, inst.Domain); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("it fails over to the next endpoint", func(t *testing.T) {
+		resolver := &Resolver{
+			URLs:    []string{failing.URL, unresponsive.URL, healthy.URL},
+			Timeout: 10 * time.Millisecond,
+		}
+
+		actual, ok, err := resolver.LookupInstance(ctx, inst.Name, inst.ServiceType, inst.Domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !ok {
+			t.Fatal("expected ok to be true")
+		}
+
+		if !actual.Equal(inst) {
+			t.Fatalf("unexpected instance: got %+v, want %+v", actual, inst)
+		}
+	})
+}
+
+// newHandler returns a DoH handler that answers queries using the given
+// records.
+func newHandler(records []dns.RR) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req := &dns.Msg{}
+		if err := req.Unpack(data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res := &dns.Msg{}
+		res.SetReply(req)
+
+		for _, rr := range records {
+			h := rr.Header()
+			q := req.Question[0]
+			if h.Rrtype == q.Qtype && dns.CanonicalName(h.Name) == dns.CanonicalName(q.Name) {
+				res.Answer = append(res.Answer, rr)
+			}
+		}
+
+		if len(res.Answer) == 0 {
+			res.Rcode = dns.RcodeNameError
+		}
+
+		out, err := res.Pack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(out)
+	})
+}
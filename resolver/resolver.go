@@ -0,0 +1,38 @@
+package resolver
+
+import (
+	"context"
+
+	"github.com/dogmatiq/dissolve/dnssd"
+)
+
+// Resolver is an interface for performing DNS-SD queries.
+//
+// It is implemented by [dnssd.UnicastResolver], which queries DNS servers
+// using plain DNS or DNS-over-TLS.
+type Resolver interface {
+	// EnumerateInstances finds all of the instances of a given service type
+	// that are advertised within a single domain.
+	EnumerateInstances(
+		ctx context.Context,
+		serviceType, domain string,
+	) ([]string, error)
+
+	// EnumerateInstancesBySubType finds all of the instances of a given
+	// service type that are advertised within a single domain as providing
+	// a specific service sub-type.
+	EnumerateInstancesBySubType(
+		ctx context.Context,
+		subType, serviceType, domain string,
+	) ([]string, error)
+
+	// LookupInstance looks up the details about a specific service instance.
+	//
+	// ok is false if the instance can not be resolved.
+	LookupInstance(
+		ctx context.Context,
+		instance, serviceType, domain string,
+	) (_ dnssd.ServiceInstance, ok bool, _ error)
+}
+
+var _ Resolver = (*dnssd.UnicastResolver)(nil)